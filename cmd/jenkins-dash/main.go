@@ -1,13 +1,21 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joho/godotenv"
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/history"
 	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/notify"
 	"github.com/mpetters/jenkins-dash/internal/ui"
 )
 
@@ -15,6 +23,11 @@ func main() {
 	// Load environment variables from .env file
 	_ = godotenv.Load() // Ignore error if .env doesn't exist
 
+	// Subcommands run without the dashboard
+	if len(os.Args) > 1 {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Get Jenkins credentials (uses Basic Auth with username:token)
 	username := os.Getenv("JENKINS_USER")
 	jenkinsToken := os.Getenv("JENKINS_TOKEN")
@@ -33,7 +46,7 @@ func main() {
 	}
 
 	// Create Jenkins client
-	jenkinsClient := jenkins.NewClient(username, jenkinsToken)
+	jenkinsClient := newJenkinsClient()
 
 	// Get config file path
 	configPath := getConfigPath()
@@ -41,6 +54,57 @@ func main() {
 	// Create the model with Jenkins client and config path
 	m := ui.NewModelWithClient(jenkinsClient, configPath)
 
+	// Load failure classification rules (defaults if no rules file)
+	rules, err := classifier.LoadRules(classifier.GetRulesPath())
+	if err != nil {
+		fmt.Printf("Warning: Could not load failure rules, using defaults: %v\n", err)
+	} else {
+		m.SetFailureRules(rules)
+	}
+
+	// Load the list of known flaky tests
+	flakyTests, err := flaky.Load(flaky.GetStorePath())
+	if err != nil {
+		fmt.Printf("Warning: Could not load flaky test list: %v\n", err)
+	} else {
+		m.SetFlakyStore(flakyTests)
+	}
+
+	// Load stage duration history (baselines for slow stage detection)
+	stageHistory, err := analytics.LoadStageHistory(analytics.GetStageHistoryPath())
+	if err != nil {
+		fmt.Printf("Warning: Could not load stage history: %v\n", err)
+	} else {
+		m.SetStageHistory(stageHistory)
+	}
+
+	// Open the build history log (compacted on startup)
+	buildHistory, err := history.Open(history.GetHistoryPath(), history.LoadConfig())
+	if err != nil {
+		fmt.Printf("Warning: Could not open build history: %v\n", err)
+	} else {
+		m.SetBuildHistory(buildHistory)
+	}
+
+	// Desktop/terminal notifications when builds finish, written to the program's output from Update
+	notifyConfig, err := notify.LoadConfig()
+	if err != nil {
+		fmt.Printf("Warning: Notifications disabled: %v\n", err)
+	} else {
+		m.SetNotifier(notify.New(notifyConfig, os.Stdout))
+	}
+
+	// Chat webhooks for build status changes
+	webhooks, err := notify.LoadWebhooks(notify.GetWebhooksPath())
+	if err != nil {
+		fmt.Printf("Warning: Could not load webhooks: %v\n", err)
+	} else if len(webhooks) > 0 {
+		m.SetWebhooks(notify.NewWebhooks(webhooks))
+	}
+
+	// User-defined hook scripts on build events
+	m.SetHooks(notify.LoadHooks())
+
 	// Load persisted builds
 	if err := m.LoadPersistedBuilds(); err != nil {
 		fmt.Printf("Warning: Could not load saved builds: %v\n", err)
@@ -54,6 +118,18 @@ func main() {
 	}
 }
 
+// newJenkinsClient creates a Jenkins client from the environment
+func newJenkinsClient() *jenkins.Client {
+	client := jenkins.NewClient(os.Getenv("JENKINS_USER"), os.Getenv("JENKINS_TOKEN"))
+	if os.Getenv("JENKINS_STAGE_CACHE") == "off" {
+		client.SetStageCache(false) // Always re-fetch /wfapi/describe
+	}
+	if os.Getenv("JENKINS_STAGE_GRAPH") == "off" {
+		client.SetStageGraph(false) // Flat wfapi stages only (no Blue Ocean)
+	}
+	return client
+}
+
 func getConfigPath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -61,3 +137,68 @@ func getConfigPath() string {
 	}
 	return filepath.Join(homeDir, ".jenkins-dash-builds.json")
 }
+
+// runSubcommand runs a CLI subcommand and returns the process exit code
+// Output goes to stdout, errors, usage and wait progress to stderr
+func runSubcommand(name string, args []string, stdout, stderr io.Writer) int {
+	var err error
+	switch name {
+	case "report":
+		err = runReport(args, stdout)
+	case "status":
+		err = runStatus(args, stdout)
+	case "wait":
+		err = runWait(args, stdout, stderr)
+	case "serve":
+		err = runServe(args)
+	case "add":
+		err = runAdd(args, stdout)
+	case "remove", "rm":
+		err = runRemove(args, stdout)
+	case "list", "ls":
+		err = runList(args, stdout)
+	case "clear":
+		err = runClear(args, stdout)
+	case "help", "-h", "--help":
+		printUsage(stderr)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", name)
+		printUsage(stderr)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 0
+	}
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		if exitErr.err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", exitErr.err)
+		}
+		return exitErr.code
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, `Usage: jenkins-dash [command]
+
+Without a command, starts the dashboard.
+
+Commands:
+  add       Track PRs without starting the dashboard; a running one picks them up PR...
+  remove    Stop tracking PRs PR...
+  list      Print the tracked PRs as last fetched (--format table|json)
+  clear     Stop tracking every PR
+  status    Fetch saved (or given) PRs once and print their builds (--format table|json) [PR...]
+  wait      Poll a PR until its build finishes; exit 0 on success, 1 on failure, 2 on error or timeout
+            (--interval 15s, --timeout, --quiet) PR
+  serve     Poll the saved PRs headless and serve a JSON API and web dashboard (--addr 127.0.0.1:8080)
+  report    Pipeline health from the local build history (--days, --format text|markdown|json)
+  help      Show this help`)
+}
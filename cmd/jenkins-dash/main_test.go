@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+// useBuildsFile points the builds file at a temporary home with the given PRs saved
+func useBuildsFile(t *testing.T, prNumbers ...string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".jenkins-dash-builds.json")
+	builds := make([]models.Build, 0, len(prNumbers))
+	for _, prNumber := range prNumbers {
+		builds = append(builds, models.Build{PRNumber: prNumber, Status: models.StatusSuccess, BuildNumber: 7})
+	}
+	if err := persistence.SaveBuilds(path, builds); err != nil {
+		t.Fatalf("SaveBuilds() error = %v", err)
+	}
+	return path
+}
+
+func TestRunSubcommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		wantCode   int
+		wantStdout string // Substring of stdout ("" = empty)
+		wantStderr string // Substring of stderr ("" = empty)
+	}{
+		{"unknown command", "frobnicate", nil, 2, "", `Unknown command "frobnicate"`},
+		{"help", "help", nil, 0, "", "Usage: jenkins-dash"},
+		{"help flag", "--help", nil, 0, "", "Usage: jenkins-dash"},
+		{"add without PRs", "add", nil, 1, "", "add needs at least one PR number"},
+		{"add invalid PR", "add", []string{"abc"}, 1, "", "Error:"},
+		{"add", "add", []string{"PR-3"}, 0, "Added PR-3", ""},
+		{"remove without PRs", "remove", nil, 1, "", "remove needs at least one PR number"},
+		{"rm routes to remove", "rm", []string{"2"}, 0, "Removed PR-2", ""},
+		{"ls routes to list", "ls", []string{"--format", "json"}, 0, `"pr": "1"`, ""},
+		{"list unknown format", "list", []string{"--format", "xml"}, 1, "", "Error:"},
+		{"clear", "clear", nil, 0, "Removed 2 PR(s)", ""},
+		{"wait without PR", "wait", nil, 2, "", "wait takes exactly one PR number, got 0"},
+		{"wait invalid PR", "wait", []string{"abc"}, 2, "", "Error:"},
+		{"report with bad days", "report", []string{"--days", "0"}, 1, "", "--days must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBuildsFile(t, "1", "2")
+			var stdout, stderr strings.Builder
+			code := runSubcommand(tt.command, tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if !contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+// contains reports whether output has want in it, or is empty if want is
+func contains(output, want string) bool {
+	if want == "" {
+		return output == ""
+	}
+	return strings.Contains(output, want)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/report"
+)
+
+// runReport implements `jenkins-dash report`: pipeline health from the local build history
+func runReport(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	days := flags.Int("days", 7, "report on builds that finished in the last N days")
+	format := flags.String("format", report.FormatText, "output format: text, markdown or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *days <= 0 {
+		return fmt.Errorf("--days must be positive, got %d", *days)
+	}
+
+	until := time.Now()
+	since := until.AddDate(0, 0, -*days)
+
+	store := history.NewStore(history.GetHistoryPath(), history.LoadConfig())
+	records, err := store.Records(history.Filter{Since: since})
+	if err != nil {
+		return fmt.Errorf("reading build history: %w", err)
+	}
+
+	output, err := report.Render(report.Generate(records, since, until), *format)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, output)
+	return err
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/github"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/metrics"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+	"github.com/mpetters/jenkins-dash/internal/server"
+)
+
+// defaultServeAddr keeps the server on this machine unless --addr or SERVE_ADDR says otherwise
+const defaultServeAddr = "127.0.0.1:8080"
+
+// runServe implements `jenkins-dash serve`: poll the saved tiles headless and
+// serve them as a JSON API, web dashboard and Prometheus metrics until interrupted
+func runServe(args []string) error {
+	addr := os.Getenv("SERVE_ADDR")
+	if addr == "" {
+		addr = defaultServeAddr
+	}
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.StringVar(&addr, "addr", addr, "address to listen on, e.g. :8080 for all interfaces")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	// Record request latency, errors and the GitHub rate limit for /metrics
+	registry := metrics.New()
+	client := newJenkinsClient()
+	client.SetTransport(registry.Transport(metrics.BackendJenkins, nil))
+	github.SetTransport(registry.Transport(metrics.BackendGitHub, nil))
+
+	engine := server.NewEngine(client, fetch.GitHubFromEnv(), getConfigPath())
+	engine.SetLogger(logger)
+	engine.SetMetrics(registry)
+	if err := engine.Load(); err != nil {
+		return fmt.Errorf("loading saved builds: %w", err)
+	}
+
+	// Same failure analysis, history and notifications as the dashboard (no terminal notifications)
+	if rules, err := classifier.LoadRules(classifier.GetRulesPath()); err != nil {
+		logger.Printf("Warning: Could not load failure rules, using defaults: %v", err)
+	} else {
+		engine.SetFailureRules(rules)
+	}
+	if flakyTests, err := flaky.Load(flaky.GetStorePath()); err != nil {
+		logger.Printf("Warning: Could not load flaky test list: %v", err)
+	} else {
+		engine.SetFlakyStore(flakyTests)
+	}
+	if stageHistory, err := analytics.LoadStageHistory(analytics.GetStageHistoryPath()); err != nil {
+		logger.Printf("Warning: Could not load stage history: %v", err)
+	} else {
+		engine.SetStageHistory(stageHistory)
+	}
+	if buildHistory, err := history.Open(history.GetHistoryPath(), history.LoadConfig()); err != nil {
+		logger.Printf("Warning: Could not open build history: %v", err)
+	} else {
+		engine.SetBuildHistory(buildHistory)
+	}
+	if notifyConfig, err := notify.LoadConfig(); err != nil {
+		logger.Printf("Warning: Notifications disabled: %v", err)
+	} else {
+		engine.SetNotifier(notify.New(notifyConfig, nil))
+	}
+	if webhooks, err := notify.LoadWebhooks(notify.GetWebhooksPath()); err != nil {
+		logger.Printf("Warning: Could not load webhooks: %v", err)
+	} else if len(webhooks) > 0 {
+		engine.SetWebhooks(notify.NewWebhooks(webhooks))
+	}
+	engine.SetHooks(notify.LoadHooks())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	polling := make(chan struct{})
+	go func() {
+		defer close(polling)
+		engine.Run(ctx)
+	}()
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           server.NewHandler(engine, os.Getenv("SERVE_TOKEN")),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("Warning: Shutting down the server: %v", err)
+		}
+	}()
+
+	logger.Printf("Serving %d PR(s) on http://%s", len(engine.Builds()), addr)
+	err := srv.ListenAndServe()
+
+	// ListenAndServe returns as soon as shutdown begins: wait for in-flight
+	// requests and the poll loop to stop, so no fetch starts after this, then
+	// save the results of fetches still running
+	stop()
+	<-shutdown
+	<-polling
+	engine.Wait()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+	"github.com/mpetters/jenkins-dash/internal/status"
+)
+
+// runStatus implements `jenkins-dash status`: fetch PR builds once and print them
+// Without PR arguments, the tiles saved by the dashboard are fetched
+func runStatus(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	format := flags.String("format", status.FormatTable, "output format: table or json")
+	prArgs, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	builds, err := statusTargets(prArgs)
+	if err != nil {
+		return err
+	}
+
+	results := fetch.All(newJenkinsClient(), fetch.GitHubFromEnv(), builds)
+	entries := make([]status.Entry, 0, len(results))
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+		entries = append(entries, status.NewEntry(result))
+	}
+
+	if err := status.Render(out, entries, *format); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not fetch %d of %d PR(s)", failed, len(results))
+	}
+	return nil
+}
+
+// statusTargets returns the PRs to fetch: the arguments, or else the saved tiles
+func statusTargets(prArgs []string) ([]models.Build, error) {
+	if len(prArgs) == 0 {
+		builds, err := persistence.LoadBuilds(getConfigPath())
+		if err != nil {
+			return nil, fmt.Errorf("loading saved builds: %w", err)
+		}
+		return builds, nil
+	}
+
+	builds := make([]models.Build, 0, len(prArgs))
+	for _, arg := range prArgs {
+		prNumber, err := jenkins.ParsePRNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, models.Build{PRNumber: prNumber})
+	}
+	return builds, nil
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments (e.g., `status 3934 --format json`) and returns the positionals
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
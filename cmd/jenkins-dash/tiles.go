@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+	"github.com/mpetters/jenkins-dash/internal/status"
+)
+
+// runAdd implements `jenkins-dash add PR...`: save tiles for PRs without starting the dashboard
+// A running dashboard picks the new tiles up on its next poll
+func runAdd(args []string, out io.Writer) error {
+	prNumbers, err := parsePRArgs("add", args)
+	if err != nil {
+		return err
+	}
+	added, err := persistence.AddPRs(getConfigPath(), prNumbers)
+	if err != nil {
+		return err
+	}
+	reportChange(out, "Added", "already tracked", prNumbers, added)
+	return nil
+}
+
+// runRemove implements `jenkins-dash remove PR...`
+func runRemove(args []string, out io.Writer) error {
+	prNumbers, err := parsePRArgs("remove", args)
+	if err != nil {
+		return err
+	}
+	removed, err := persistence.RemovePRs(getConfigPath(), prNumbers)
+	if err != nil {
+		return err
+	}
+	reportChange(out, "Removed", "not tracked", prNumbers, removed)
+	return nil
+}
+
+// runList implements `jenkins-dash list`: print the saved tiles as last fetched, without fetching
+func runList(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	format := flags.String("format", status.FormatTable, "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	builds, err := persistence.LoadBuilds(getConfigPath())
+	if err != nil {
+		return err
+	}
+	entries := make([]status.Entry, 0, len(builds))
+	for _, build := range builds {
+		entries = append(entries, status.NewEntry(fetch.Result{PRNumber: build.PRNumber, Build: &build}))
+	}
+	return status.Render(out, entries, *format)
+}
+
+// runClear implements `jenkins-dash clear`: remove every saved tile
+func runClear(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("clear", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	removed, err := persistence.ClearPRs(getConfigPath())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Removed %d PR(s)\n", removed)
+	return nil
+}
+
+// parsePRArgs validates PR number arguments ("3934" or "PR-3934")
+func parsePRArgs(command string, args []string) ([]string, error) {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() == 0 {
+		return nil, fmt.Errorf("%s needs at least one PR number", command)
+	}
+
+	prNumbers := make([]string, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		prNumber, err := jenkins.ParsePRNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		prNumbers = append(prNumbers, prNumber)
+	}
+	return prNumbers, nil
+}
+
+// reportChange prints which PRs were changed and which were skipped
+func reportChange(out io.Writer, verb, skippedReason string, requested, changed []string) {
+	if len(changed) > 0 {
+		fmt.Fprintf(out, "%s %s\n", verb, joinPRs(changed))
+	}
+
+	var skipped []string
+	for _, prNumber := range requested {
+		if !slices.Contains(changed, prNumber) && !slices.Contains(skipped, prNumber) {
+			skipped = append(skipped, prNumber)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(out, "Skipped %s (%s)\n", joinPRs(skipped), skippedReason)
+	}
+}
+
+// joinPRs formats PR numbers as "PR-1, PR-2"
+func joinPRs(prNumbers []string) string {
+	labels := make([]string, len(prNumbers))
+	for i, prNumber := range prNumbers {
+		labels[i] = "PR-" + prNumber
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+func TestTilesCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(args []string, out io.Writer) error
+		saved   []string // PRs in the builds file before the command
+		args    []string
+		wantOut string   // Substring of the output ("" = none)
+		wantErr string   // Substring of the error ("" = no error)
+		wantPRs []string // PRs in the builds file after the command
+	}{
+		{"add", runAdd, []string{"1"}, []string{"2", "PR-3"}, "Added PR-2, PR-3\n", "", []string{"1", "2", "3"}},
+		{"add tracked", runAdd, []string{"1"}, []string{"1", "2"}, "Added PR-2\nSkipped PR-1 (already tracked)\n", "", []string{"1", "2"}},
+		{"add invalid", runAdd, []string{"1"}, []string{"2", "abc"}, "", "PR", []string{"1"}},
+		{"add nothing", runAdd, []string{"1"}, nil, "", "add needs at least one PR number", []string{"1"}},
+		{"remove", runRemove, []string{"1", "2"}, []string{"PR-1"}, "Removed PR-1\n", "", []string{"2"}},
+		{"remove untracked", runRemove, []string{"1"}, []string{"1", "9"}, "Removed PR-1\nSkipped PR-9 (not tracked)\n", "", []string{}},
+		{"remove nothing", runRemove, []string{"1"}, nil, "", "remove needs at least one PR number", []string{"1"}},
+		{"list", runList, []string{"1", "2"}, nil, "PR-1", "", []string{"1", "2"}},
+		{"list json", runList, []string{"1"}, []string{"--format", "json"}, `"status": "success"`, "", []string{"1"}},
+		{"list empty", runList, nil, nil, "PR  BUILD", "", []string{}},
+		{"clear", runClear, []string{"1", "2"}, nil, "Removed 2 PR(s)\n", "", []string{}},
+		{"clear empty", runClear, nil, nil, "Removed 0 PR(s)\n", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useBuildsFile(t, tt.saved...)
+			var out strings.Builder
+			err := tt.run(tt.args, &out)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if !contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+
+			builds, err := persistence.LoadBuilds(path)
+			if err != nil {
+				t.Fatalf("LoadBuilds() error = %v", err)
+			}
+			var prNumbers []string
+			for _, build := range builds {
+				prNumbers = append(prNumbers, build.PRNumber)
+			}
+			if strings.Join(prNumbers, ",") != strings.Join(tt.wantPRs, ",") {
+				t.Errorf("saved PRs = %v, want %v", prNumbers, tt.wantPRs)
+			}
+		})
+	}
+}
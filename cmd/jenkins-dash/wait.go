@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/status"
+)
+
+// Exit codes of `jenkins-dash wait`
+const (
+	waitExitSuccess = 0
+	waitExitFailure = 1 // The build failed
+	waitExitError   = 2 // The build errored or was aborted, Jenkins couldn't be polled, or timeout
+)
+
+// exitCodeError makes runSubcommand exit with a specific code
+// err is printed if set; a nil err exits quietly
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+// runWait implements `jenkins-dash wait PR`: poll until the PR's latest build
+// finishes and exit 0 on success, 1 on failure and 2 on error or timeout
+// Progress goes to progressOut and the result to out, both unless --quiet
+func runWait(args []string, out, progressOut io.Writer) error {
+	flags := flag.NewFlagSet("wait", flag.ContinueOnError)
+	interval := flags.Duration("interval", status.DefaultWaitInterval, "how often to poll Jenkins")
+	timeout := flags.Duration("timeout", 0, "give up after this long (0 = wait forever)")
+	quiet := flags.Bool("quiet", false, "print nothing, only set the exit code")
+	prArgs, err := parseInterspersed(flags, args)
+	if err == flag.ErrHelp {
+		return err
+	}
+	if err != nil {
+		return &exitCodeError{code: waitExitError}
+	}
+	if len(prArgs) != 1 {
+		return &exitCodeError{code: waitExitError, err: fmt.Errorf("wait takes exactly one PR number, got %d", len(prArgs))}
+	}
+	prNumber, err := jenkins.ParsePRNumber(prArgs[0])
+	if err != nil {
+		return &exitCodeError{code: waitExitError, err: err}
+	}
+
+	opts := status.WaitOptions{Interval: *interval, Timeout: *timeout}
+	if !*quiet {
+		opts.Progress = progressOut
+	}
+	build, err := status.Wait(newJenkinsClient(), fetch.GitHubFromEnv(), prNumber, opts)
+	if err != nil {
+		return &exitCodeError{code: waitExitError, err: fmt.Errorf("PR-%s: %w", prNumber, err)}
+	}
+
+	if !*quiet {
+		fmt.Fprintf(out, "PR-%s #%d %s in %s\n", prNumber, build.BuildNumber, build.Status, build.FormatDuration())
+	}
+	return waitResult(build.Status)
+}
+
+// waitResult maps a finished build's status to the wait exit code
+func waitResult(buildStatus models.BuildStatus) error {
+	switch buildStatus {
+	case models.StatusSuccess:
+		return nil
+	case models.StatusFailure:
+		return &exitCodeError{code: waitExitFailure}
+	default:
+		return &exitCodeError{code: waitExitError}
+	}
+}
@@ -0,0 +1,268 @@
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+const stageHistoryFileName = ".jenkins-dash-stages.json"
+
+// Default regression settings (can be overridden with environment variables)
+const (
+	defaultSlowFactor  = 1.5              // Stage is slow when it takes this many times its median
+	defaultMinSamples  = 5                // Baselines need at least this many finished builds
+	defaultMinSlowdown = 30 * time.Second // Ignore slowdowns smaller than this (short stages are noisy)
+	maxSamplesPerStage = 200              // Oldest samples are dropped beyond this
+)
+
+// Sample is the duration of one stage in one finished build
+type Sample struct {
+	PRNumber       string `json:"pr"`
+	BuildNumber    int    `json:"build"`
+	DurationMillis int64  `json:"duration_ms"`
+	Timestamp      int64  `json:"timestamp"` // Stage start, Unix seconds
+}
+
+// Stats summarizes the durations of a stage
+type Stats struct {
+	Samples int
+	Median  time.Duration
+	P90     time.Duration
+}
+
+// Regression is a stage of a build that took much longer than its baseline
+type Regression struct {
+	Stage    string
+	Duration time.Duration
+	Baseline Stats
+	Ratio    float64 // Duration / Baseline.Median
+}
+
+// Config controls when a stage counts as a regression
+type Config struct {
+	SlowFactor  float64
+	MinSamples  int
+	MinSlowdown time.Duration
+}
+
+// LoadConfig returns the regression settings from environment variables
+// STAGE_SLOW_FACTOR sets how many times its median a stage may take (default 1.5)
+func LoadConfig() Config {
+	cfg := Config{SlowFactor: defaultSlowFactor, MinSamples: defaultMinSamples, MinSlowdown: defaultMinSlowdown}
+	if value := os.Getenv("STAGE_SLOW_FACTOR"); value != "" {
+		if factor, err := strconv.ParseFloat(value, 64); err == nil && factor > 1 {
+			cfg.SlowFactor = factor
+		}
+	}
+	return cfg
+}
+
+// StageHistory keeps the durations of successful stages of finished builds
+// Baselines are per job, since different pipelines often share stage names
+// It is not safe for concurrent use, except for the writes returned by
+// Snapshot; the UI only touches it from Update
+type StageHistory struct {
+	path    string
+	samples map[string]map[string][]Sample // Job path -> stage name -> samples, oldest first
+
+	snapshots int        // Snapshots taken, numbering their writes
+	mu        sync.Mutex // Guards written, and orders snapshot writes
+	written   int        // Number of the newest snapshot written to disk
+}
+
+// GetStageHistoryPath returns the path to the stage history file
+// Reads from STAGE_HISTORY_FILE or defaults to ~/.jenkins-dash-stages.json
+func GetStageHistoryPath() string {
+	if path := os.Getenv("STAGE_HISTORY_FILE"); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return stageHistoryFileName
+	}
+	return filepath.Join(homeDir, stageHistoryFileName)
+}
+
+// NewStageHistory creates an empty history that saves to path ("" = in memory only)
+func NewStageHistory(path string) *StageHistory {
+	return &StageHistory{path: path, samples: make(map[string]map[string][]Sample)}
+}
+
+// LoadStageHistory reads the stage history from path
+// Returns an empty history if the file doesn't exist (not an error)
+func LoadStageHistory(path string) (*StageHistory, error) {
+	history := NewStageHistory(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history.samples); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// job returns the samples of a job's stages, creating them if needed
+func (h *StageHistory) job(jobPath string) map[string][]Sample {
+	stages, ok := h.samples[jobPath]
+	if !ok {
+		stages = make(map[string][]Sample)
+		h.samples[jobPath] = stages
+	}
+	return stages
+}
+
+// Save writes the stage history to disk
+func (h *StageHistory) Save() error {
+	return h.Snapshot()()
+}
+
+// Snapshot returns a function writing the history as it is now to disk, which
+// may run in the background while the history changes
+// A snapshot older than one already written is dropped, so writes finishing
+// out of order don't lose samples
+func (h *StageHistory) Snapshot() func() error {
+	if h.path == "" {
+		return func() error { return nil } // In-memory history, skip saving
+	}
+
+	data, err := json.Marshal(h.samples)
+	h.snapshots++
+	number := h.snapshots
+	return func() error {
+		if err != nil {
+			return err
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if number < h.written {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+			return err
+		}
+		if err := persistence.WriteAtomic(h.path, data); err != nil { // Shared with other instances
+			return err
+		}
+		h.written = number
+		return nil
+	}
+}
+
+// Record adds the successful stages of a finished build to the history
+// Returns false if the build is still running or was already recorded
+func (h *StageHistory) Record(build models.Build) bool {
+	if build.IsRunning() || build.Status == models.StatusPending || build.BuildNumber == 0 {
+		return false
+	}
+
+	recorded := false
+	stages := h.job(jenkins.JobPathOf(build))
+	for _, stage := range build.Stages {
+		if !Measurable(stage) || stage.Status != "SUCCESS" {
+			continue // Failed and skipped stages would skew the baseline
+		}
+		if contains(stages[stage.Name], build.PRNumber, build.BuildNumber) {
+			continue
+		}
+
+		samples := append(stages[stage.Name], Sample{
+			PRNumber:       build.PRNumber,
+			BuildNumber:    build.BuildNumber,
+			DurationMillis: stage.DurationMillis,
+			Timestamp:      stage.StartMillis / 1000,
+		})
+		if len(samples) > maxSamplesPerStage {
+			samples = samples[len(samples)-maxSamplesPerStage:]
+		}
+		stages[stage.Name] = samples
+		recorded = true
+	}
+	return recorded
+}
+
+// contains reports whether a build is among a stage's samples
+func contains(samples []Sample, prNumber string, buildNumber int) bool {
+	for _, sample := range samples {
+		if sample.PRNumber == prNumber && sample.BuildNumber == buildNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// Baseline returns the median and p90 duration of a stage of a job
+// Returns false if the stage has never been recorded
+func (h *StageHistory) Baseline(jobPath, stage string) (Stats, bool) {
+	samples := h.samples[jobPath][stage]
+	if len(samples) == 0 {
+		return Stats{}, false
+	}
+
+	durations := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		durations[i] = time.Duration(sample.DurationMillis) * time.Millisecond
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	return Stats{
+		Samples: len(durations),
+		Median:  percentile(durations, 50),
+		P90:     percentile(durations, 90),
+	}, true
+}
+
+// Regressions returns the stages of a build (finished or still running) that
+// take much longer than their baseline, slowest first
+func (h *StageHistory) Regressions(build models.Build, cfg Config) []Regression {
+	var regressions []Regression
+	jobPath := jenkins.JobPathOf(build)
+	for _, stage := range build.Stages {
+		if !Measurable(stage) {
+			continue
+		}
+		baseline, ok := h.Baseline(jobPath, stage.Name)
+		if !ok || baseline.Samples < cfg.MinSamples || baseline.Median <= 0 {
+			continue
+		}
+
+		duration := time.Duration(stage.DurationMillis) * time.Millisecond
+		ratio := float64(duration) / float64(baseline.Median)
+		if ratio >= cfg.SlowFactor && duration-baseline.Median >= cfg.MinSlowdown {
+			regressions = append(regressions, Regression{Stage: stage.Name, Duration: duration, Baseline: baseline, Ratio: ratio})
+		}
+	}
+
+	sort.SliceStable(regressions, func(i, j int) bool { return regressions[i].Ratio > regressions[j].Ratio })
+	return regressions
+}
+
+// Measurable reports whether a stage's duration means anything
+// Phase labels (e.g., "BUILD:") only group the stages after them and skipped
+// stages never ran
+func Measurable(stage models.Stage) bool {
+	return !strings.HasSuffix(stage.Name, ":") && stage.Status != "NOT_EXECUTED"
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
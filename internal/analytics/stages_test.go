@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// testJob is the job the builds of the tests were fetched from
+const testJob = "qal/job/qal-tests"
+
+// finishedBuild returns a successful build with a phase label and one timed stage
+func finishedBuild(buildNumber int, testsDuration time.Duration) models.Build {
+	return models.Build{
+		PRNumber:    "3934",
+		JobPath:     testJob,
+		BuildNumber: buildNumber,
+		Status:      models.StatusSuccess,
+		Stages: []models.Stage{
+			{Name: "BUILD:", Status: "SUCCESS", DurationMillis: 10},
+			{Name: "Run Unit Tests", Status: "SUCCESS", DurationMillis: testsDuration.Milliseconds()},
+		},
+	}
+}
+
+func TestStageHistory_Baseline(t *testing.T) {
+	history := NewStageHistory("")
+	for i, minutes := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10} {
+		history.Record(finishedBuild(i+1, time.Duration(minutes)*time.Minute))
+	}
+
+	stats, ok := history.Baseline(testJob, "Run Unit Tests")
+	if !ok {
+		t.Fatal("Expected a baseline for recorded stage")
+	}
+	if stats.Samples != 10 || stats.Median != 5*time.Minute || stats.P90 != 9*time.Minute {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	if _, ok := history.Baseline(testJob, "BUILD:"); ok {
+		t.Error("Phase labels should not be recorded")
+	}
+}
+
+func TestStageHistory_RecordSkipsRunningAndDuplicates(t *testing.T) {
+	history := NewStageHistory("")
+
+	running := finishedBuild(1, time.Minute)
+	running.Status = models.StatusRunning
+	if history.Record(running) {
+		t.Error("Running builds should not be recorded")
+	}
+
+	if !history.Record(finishedBuild(1, time.Minute)) {
+		t.Error("Finished build should be recorded")
+	}
+	if history.Record(finishedBuild(1, time.Minute)) {
+		t.Error("Refreshing the same build should not record it twice")
+	}
+
+	failed := finishedBuild(2, time.Hour)
+	failed.Stages[1].Status = "FAILED"
+	history.Record(failed)
+	if stats, _ := history.Baseline(testJob, "Run Unit Tests"); stats.Samples != 1 {
+		t.Errorf("Failed stages should not join the baseline, got %d samples", stats.Samples)
+	}
+}
+
+func TestStageHistory_Regressions(t *testing.T) {
+	history := NewStageHistory("")
+	for i := 1; i <= 5; i++ {
+		history.Record(finishedBuild(i, 4*time.Minute))
+	}
+	cfg := Config{SlowFactor: 1.5, MinSamples: 5, MinSlowdown: 30 * time.Second}
+
+	slow := finishedBuild(6, 10*time.Minute)
+	slow.Status = models.StatusRunning
+	regressions := history.Regressions(slow, cfg)
+	if len(regressions) != 1 || regressions[0].Stage != "Run Unit Tests" {
+		t.Fatalf("Expected Run Unit Tests to be slow, got %+v", regressions)
+	}
+	if regressions[0].Ratio != 2.5 {
+		t.Errorf("Ratio = %v, want 2.5", regressions[0].Ratio)
+	}
+
+	if regressions := history.Regressions(finishedBuild(7, 5*time.Minute), cfg); len(regressions) != 0 {
+		t.Errorf("Stage within the slow factor should not be flagged, got %+v", regressions)
+	}
+
+	cfg.MinSamples = 10
+	if regressions := history.Regressions(slow, cfg); len(regressions) != 0 {
+		t.Error("Stages without enough samples should not be flagged")
+	}
+}
+
+func TestStageHistory_KeepsJobsApart(t *testing.T) {
+	history := NewStageHistory("")
+	for i := 1; i <= 5; i++ {
+		history.Record(finishedBuild(i, 4*time.Minute))
+	}
+	cfg := Config{SlowFactor: 1.5, MinSamples: 5, MinSlowdown: 30 * time.Second}
+
+	other := finishedBuild(1, 20*time.Minute)
+	other.JobPath = "qal/job/qal-e2e"
+	if regressions := history.Regressions(other, cfg); len(regressions) != 0 {
+		t.Errorf("A stage of another job should not be compared to this job's baseline, got %+v", regressions)
+	}
+	history.Record(other)
+	if stats, _ := history.Baseline(testJob, "Run Unit Tests"); stats.Samples != 5 {
+		t.Errorf("Another job's build joined the baseline, got %d samples", stats.Samples)
+	}
+}
+
+func TestStageHistory_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stages.json")
+	history := NewStageHistory(path)
+	history.Record(finishedBuild(1, 2*time.Minute))
+	if err := history.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadStageHistory(path)
+	if err != nil {
+		t.Fatalf("LoadStageHistory() error = %v", err)
+	}
+	if stats, ok := loaded.Baseline(testJob, "Run Unit Tests"); !ok || stats.Median != 2*time.Minute {
+		t.Errorf("Expected saved baseline to load, got %+v", stats)
+	}
+
+	if _, err := LoadStageHistory(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Missing file should not error, got %v", err)
+	}
+}
+
+func TestStageHistory_SnapshotsWrittenOutOfOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stages.json")
+	history := NewStageHistory(path)
+	history.Record(finishedBuild(1, 2*time.Minute))
+	older := history.Snapshot()
+	history.Record(finishedBuild(2, 2*time.Minute))
+	newer := history.Snapshot()
+
+	// The newer snapshot lands first; the older one must not overwrite it
+	if err := newer(); err != nil {
+		t.Fatalf("newer snapshot error = %v", err)
+	}
+	if err := older(); err != nil {
+		t.Fatalf("older snapshot error = %v", err)
+	}
+
+	loaded, err := LoadStageHistory(path)
+	if err != nil {
+		t.Fatalf("LoadStageHistory() error = %v", err)
+	}
+	if stats, _ := loaded.Baseline(testJob, "Run Unit Tests"); stats.Samples != 2 {
+		t.Errorf("Expected both samples saved, got %d", stats.Samples)
+	}
+}
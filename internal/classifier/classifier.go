@@ -0,0 +1,146 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const rulesFileName = ".jenkins-dash-rules.json"
+
+// Rule maps a console log pattern to a failure category
+type Rule struct {
+	Category string `json:"category"` // Short label shown on the tile (e.g., "Out of memory")
+	Pattern  string `json:"pattern"`  // Go regular expression matched against the console log
+
+	re *regexp.Regexp
+}
+
+// RulesFile is the on-disk format of a rules config file
+// Custom rules are checked before the defaults unless ReplaceDefaults is set
+type RulesFile struct {
+	Rules           []Rule `json:"rules"`
+	ReplaceDefaults bool   `json:"replace_defaults"`
+}
+
+// Unclassified is the category of a failed build whose console log matches no
+// rule; it is stored like any other category, so the log isn't fetched again
+const Unclassified = "Unclassified"
+
+// Match is the result of classifying a console log
+type Match struct {
+	Category string
+	Line     string // The log line that matched, trimmed
+}
+
+// defaultRules covers common Maven, Gradle, npm and Podman failures
+// Order matters: the first rule that matches wins, so specific causes (rate limits,
+// OOM, lost agents) come before generic ones (test or npm failures)
+var defaultRules = []Rule{
+	{Category: "Docker rate limit", Pattern: `(?i)toomanyrequests|reached your pull rate limit`},
+	{Category: "Out of memory", Pattern: `OutOfMemoryError|JavaScript heap out of memory|OOMKilled|Cannot allocate memory|exit code 137|exited with code 137`},
+	{Category: "Agent disconnect", Pattern: `ChannelClosedException|RequestAbortedException|Agent .* was removed|went offline during the build|channel is already closed|RemovedNodeCause`},
+	{Category: "Timeout", Pattern: `Timeout has been exceeded|Cancelling nested steps due to timeout|Build timed out|context deadline exceeded`},
+	{Category: "Image pull", Pattern: `(?i)error: (initializing source|reading manifest|unable to pull|pulling image)|manifest unknown`},
+	{Category: "Dependency fetch", Pattern: `Could not resolve dependencies|Could not transfer artifact|Failed to read artifact descriptor|Could not resolve all (files|dependencies)|Could not GET 'https?://|npm ERR! code (E404|ETIMEDOUT|ECONNRESET|ENOTFOUND|EAI_AGAIN)|npm ERR! network`},
+	{Category: "Compile error", Pattern: `COMPILATION ERROR|maven-compiler-plugin.*(failed|FAILURE)|Compilation failed|Execution failed for task ':?[\w:-]*compile\w*'|error TS\d+:`},
+	{Category: "Test failure", Pattern: `There are test failures|There were failing tests|Tests run: \d+, Failures: [1-9]|maven-(surefire|failsafe)-plugin.*(failed|FAILURE)|> Task :[\w:-]*[tT]est FAILED|Tests:\s+\d+ failed|npm ERR! Test failed|^\s*\d+ failing$`},
+	{Category: "Podman build", Pattern: `(?i)error: building at step|error building at step|podman build.*exit status|Error: (creating build container|committing container)`},
+	{Category: "npm error", Pattern: `npm ERR!`},
+}
+
+// DefaultRules returns the built-in rule set, compiled
+func DefaultRules() []Rule {
+	rules, err := Compile(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default failure rule: %v", err)) // Programming error
+	}
+	return rules
+}
+
+// Compile validates and compiles rule patterns, returning compiled copies
+func Compile(rules []Rule) ([]Rule, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return nil, fmt.Errorf("rule with pattern %q has no category", rule.Pattern)
+		}
+		re, err := regexp.Compile("(?m)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Category, err)
+		}
+		rule.re = re
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// GetRulesPath returns the path to the rules file
+// Reads from FAILURE_RULES_FILE or defaults to ~/.jenkins-dash-rules.json
+func GetRulesPath() string {
+	if path := os.Getenv("FAILURE_RULES_FILE"); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return rulesFileName
+	}
+	return filepath.Join(homeDir, rulesFileName)
+}
+
+// LoadRules loads rules from a JSON file and merges them with the defaults
+// Returns the defaults if the file doesn't exist (not an error)
+func LoadRules(filePath string) ([]Rule, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return DefaultRules(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file RulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filePath, err)
+	}
+
+	custom, err := Compile(file.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filePath, err)
+	}
+	if file.ReplaceDefaults {
+		return custom, nil
+	}
+	return append(custom, DefaultRules()...), nil
+}
+
+// Classify returns the category of the first rule that matches the console log
+// Returns false if no rule matches
+func Classify(log string, rules []Rule) (Match, bool) {
+	for _, rule := range rules {
+		if rule.re == nil {
+			continue // Not compiled
+		}
+		loc := rule.re.FindStringIndex(log)
+		if loc == nil {
+			continue
+		}
+		return Match{Category: rule.Category, Line: lineAt(log, loc[0])}, true
+	}
+	return Match{}, false
+}
+
+// lineAt returns the trimmed line containing the given byte offset
+func lineAt(log string, offset int) string {
+	start := strings.LastIndexByte(log[:offset], '\n') + 1
+	end := strings.IndexByte(log[offset:], '\n')
+	if end < 0 {
+		end = len(log)
+	} else {
+		end += offset
+	}
+	return strings.TrimSpace(log[start:end])
+}
@@ -0,0 +1,181 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassify_DefaultRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		category string
+		line     string
+	}{
+		{
+			name:     "Maven test failure",
+			log:      "[INFO] Building account\n[ERROR] Failed to execute goal org.apache.maven.plugins:maven-surefire-plugin:3.0.0:test (default-test) on project account: There are test failures.\n[ERROR] -> [Help 1]",
+			category: "Test failure",
+			line:     "[ERROR] Failed to execute goal org.apache.maven.plugins:maven-surefire-plugin:3.0.0:test (default-test) on project account: There are test failures.",
+		},
+		{
+			name:     "Maven compile error",
+			log:      "[ERROR] COMPILATION ERROR : \n[ERROR] /src/Main.java:[12,5] cannot find symbol",
+			category: "Compile error",
+			line:     "[ERROR] COMPILATION ERROR :",
+		},
+		{
+			name:     "Gradle test failure",
+			log:      "> Task :compileJava\n> Task :test FAILED\n\nFAILURE: Build failed with an exception.",
+			category: "Test failure",
+			line:     "> Task :test FAILED",
+		},
+		{
+			name:     "Gradle dependency fetch",
+			log:      "> Could not resolve all files for configuration ':compileClasspath'.",
+			category: "Dependency fetch",
+			line:     "> Could not resolve all files for configuration ':compileClasspath'.",
+		},
+		{
+			name:     "Java out of memory beats test failure",
+			log:      "Tests run: 12, Failures: 1\njava.lang.OutOfMemoryError: Java heap space",
+			category: "Out of memory",
+			line:     "java.lang.OutOfMemoryError: Java heap space",
+		},
+		{
+			name:     "npm network error",
+			log:      "npm ERR! code ETIMEDOUT\nnpm ERR! network request failed",
+			category: "Dependency fetch",
+			line:     "npm ERR! code ETIMEDOUT",
+		},
+		{
+			name:     "Generic npm error",
+			log:      "npm ERR! Missing script: \"lint\"",
+			category: "npm error",
+			line:     "npm ERR! Missing script: \"lint\"",
+		},
+		{
+			name:     "Podman build step",
+			log:      "STEP 4/9: RUN make\nError: building at STEP \"RUN make\": exit status 2",
+			category: "Podman build",
+			line:     "Error: building at STEP \"RUN make\": exit status 2",
+		},
+		{
+			name:     "Docker Hub rate limit",
+			log:      "Error: initializing source docker://node:20: reading manifest 20 in docker.io/library/node: toomanyrequests: You have reached your pull rate limit.",
+			category: "Docker rate limit",
+		},
+		{
+			name:     "Agent disconnect",
+			log:      "hudson.remoting.ChannelClosedException: Channel \"hudson.remoting.Channel@1:agent-7\": Remote call failed",
+			category: "Agent disconnect",
+		},
+		{
+			name:     "Pipeline timeout",
+			log:      "Timeout has been exceeded\nCancelling nested steps due to timeout",
+			category: "Timeout",
+			line:     "Timeout has been exceeded",
+		},
+	}
+
+	rules := DefaultRules()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := Classify(tt.log, rules)
+			if !ok {
+				t.Fatalf("Expected a match for %q", tt.log)
+			}
+			if match.Category != tt.category {
+				t.Errorf("Category = %q, want %q", match.Category, tt.category)
+			}
+			if tt.line != "" && match.Line != tt.line {
+				t.Errorf("Line = %q, want %q", match.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestClassify_NoMatch(t *testing.T) {
+	if match, ok := Classify("[INFO] BUILD SUCCESS\nFinished: FAILURE", DefaultRules()); ok {
+		t.Errorf("Expected no match, got %+v", match)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Missing file returns defaults", func(t *testing.T) {
+		rules, err := LoadRules(filepath.Join(dir, "missing.json"))
+		if err != nil {
+			t.Fatalf("LoadRules() error = %v", err)
+		}
+		if len(rules) != len(defaultRules) {
+			t.Errorf("Expected %d default rules, got %d", len(defaultRules), len(rules))
+		}
+	})
+
+	t.Run("Custom rules checked before defaults", func(t *testing.T) {
+		path := filepath.Join(dir, "merge.json")
+		os.WriteFile(path, []byte(`{"rules": [{"category": "Flaky DB", "pattern": "Connection refused: db"}]}`), 0644)
+
+		rules, err := LoadRules(path)
+		if err != nil {
+			t.Fatalf("LoadRules() error = %v", err)
+		}
+		if len(rules) != len(defaultRules)+1 {
+			t.Errorf("Expected %d rules, got %d", len(defaultRules)+1, len(rules))
+		}
+
+		match, _ := Classify("Connection refused: db\nThere are test failures", rules)
+		if match.Category != "Flaky DB" {
+			t.Errorf("Expected custom rule to win, got %q", match.Category)
+		}
+	})
+
+	t.Run("Replace defaults", func(t *testing.T) {
+		path := filepath.Join(dir, "replace.json")
+		os.WriteFile(path, []byte(`{"replace_defaults": true, "rules": [{"category": "Lint", "pattern": "eslint.*problems?"}]}`), 0644)
+
+		rules, err := LoadRules(path)
+		if err != nil {
+			t.Fatalf("LoadRules() error = %v", err)
+		}
+		if len(rules) != 1 {
+			t.Errorf("Expected only the custom rule, got %d rules", len(rules))
+		}
+		if _, ok := Classify("There are test failures", rules); ok {
+			t.Error("Defaults should not apply when replace_defaults is set")
+		}
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		os.WriteFile(path, []byte(`{"rules": [{"category": "Broken", "pattern": "(unclosed"}]}`), 0644)
+
+		if _, err := LoadRules(path); err == nil {
+			t.Error("Expected error for invalid regular expression")
+		}
+	})
+
+	t.Run("Missing category", func(t *testing.T) {
+		path := filepath.Join(dir, "nocategory.json")
+		os.WriteFile(path, []byte(`{"rules": [{"pattern": "boom"}]}`), 0644)
+
+		if _, err := LoadRules(path); err == nil {
+			t.Error("Expected error for rule without a category")
+		}
+	})
+}
+
+func TestGetRulesPath(t *testing.T) {
+	t.Setenv("FAILURE_RULES_FILE", "/tmp/rules.json")
+	if got := GetRulesPath(); got != "/tmp/rules.json" {
+		t.Errorf("GetRulesPath() = %q, want /tmp/rules.json", got)
+	}
+
+	t.Setenv("FAILURE_RULES_FILE", "")
+	if got := filepath.Base(GetRulesPath()); got != rulesFileName {
+		t.Errorf("Expected default file name %s, got %s", rulesFileName, got)
+	}
+}
@@ -0,0 +1,217 @@
+package fetch
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/mpetters/jenkins-dash/internal/github"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// defaultGitHubRepo is used when GITHUB_REPO is not set
+const defaultGitHubRepo = "identity-manage/account"
+
+// maxConcurrentFetches bounds parallel fetches in All
+const maxConcurrentFetches = 4
+
+// sinceGreenLookback bounds how many builds are listed to find the commits
+// since the last green build
+const sinceGreenLookback = 20
+
+// Client is the part of the Jenkins client needed to fetch a PR's build
+type Client interface {
+	GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error)
+	IsQueued(jobPath, branch string) (bool, error)
+	ListBuilds(jobPath, branch string, limit int) ([]models.Build, error)
+}
+
+// GitHub holds the settings for enriching builds with PR info
+type GitHub struct {
+	Token string // Empty = skip GitHub
+	Repo  string // "owner/repo"
+}
+
+// GitHubFromEnv returns the GitHub settings from GITHUB_TOKEN and GITHUB_REPO
+func GitHubFromEnv() GitHub {
+	repo := os.Getenv("GITHUB_REPO")
+	if repo == "" {
+		repo = defaultGitHubRepo
+	}
+	return GitHub{Token: os.Getenv("GITHUB_TOKEN"), Repo: repo}
+}
+
+// Build fetches the latest build of a PR from Jenkins and adds the PR's
+// branch, author, checks and commits from GitHub
+// Branch, author and repository from an earlier fetch (existing) are kept, so
+// GitHub is only asked for them once; checks are always refreshed, and commits
+// when the PR head moved
+// For a red build, the commits of builds existing didn't observe are backfilled
+// (see backfillSinceGreen) for Merge to add to the carried history
+func Build(client Client, gh GitHub, prNumber string, existing models.Build) (*models.Build, error) {
+	jobPath := jenkins.InferJobPath(prNumber)
+	branch := "PR-" + prNumber
+	build, err := client.GetBuildStatus(jobPath, branch, 0)
+	if err != nil {
+		return nil, err
+	}
+	if build == nil {
+		return nil, fmt.Errorf("no build data returned")
+	}
+
+	if existing.GitBranch != "" {
+		build.GitBranch = existing.GitBranch
+	}
+	if existing.PRAuthor != "" {
+		build.PRAuthor = existing.PRAuthor
+	}
+	if existing.Repository != "" {
+		build.Repository = existing.Repository
+	}
+
+	if gh.Token != "" {
+		// Fetch PR information (branch, author, repository)
+		if build.GitBranch == "" || build.PRAuthor == "" || build.Repository == "" {
+			if prInfo, err := github.FetchPRBranch(gh.Token, gh.Repo, prNumber); err == nil {
+				if build.GitBranch == "" && prInfo.BranchName != "" {
+					build.GitBranch = prInfo.BranchName
+				}
+				if build.PRAuthor == "" && prInfo.Author != "" {
+					build.PRAuthor = prInfo.Author
+				}
+				if build.Repository == "" && prInfo.Repository != "" {
+					build.Repository = prInfo.Repository
+				}
+			}
+		}
+
+		// Re-fetch PR check status for real-time updates
+		checkStatus := github.FetchPRCheckStatus(gh.Token, gh.Repo, prNumber)
+		build.PRCheckStatus = checkStatus.Summary
+		build.PRHeadSHA = checkStatus.HeadSHA
+		if existing.PRCommits != nil && build.PRHeadSHA != "" && build.PRHeadSHA == existing.PRHeadSHA {
+			build.PRCommits = existing.PRCommits // Unchanged until the PR gets a new head
+		} else {
+			build.PRCommits = fetchPRCommits(gh.Token, gh.Repo, prNumber)
+		}
+	}
+
+	checkStaleness(client, build, jobPath, branch)
+	backfillSinceGreen(client, build, existing, jobPath, branch)
+	return build, nil
+}
+
+// backfillSinceGreen lists the builds of a red PR between existing and build
+// (back to sinceGreenLookback builds if existing was never fetched), and records
+// their commits in SinceGreen and the last green one among them in LastGreenBuild
+// Best effort: nothing is recorded if the builds can't be listed
+func backfillSinceGreen(client Client, build *models.Build, existing models.Build, jobPath, branch string) {
+	if build.IsSuccess() {
+		return
+	}
+	limit := sinceGreenLookback
+	if existing.BuildNumber > 0 {
+		limit = min(build.BuildNumber-existing.BuildNumber, sinceGreenLookback)
+	}
+	if limit <= 1 {
+		return // No builds in between
+	}
+
+	builds, err := client.ListBuilds(jobPath, branch, limit)
+	if err != nil {
+		return
+	}
+	var missed []models.Commit
+	for _, b := range builds { // Newest first
+		if b.BuildNumber >= build.BuildNumber {
+			continue
+		}
+		if b.BuildNumber <= existing.BuildNumber {
+			break
+		}
+		if b.IsSuccess() {
+			build.LastGreenBuild = b.BuildNumber
+			break
+		}
+		missed = slices.Concat(b.Changes, missed)
+	}
+	build.SinceGreen = missed
+}
+
+// fetchPRCommits fetches the PR's commits from GitHub (best effort, nil on error)
+func fetchPRCommits(token, repo, prNumber string) []models.Commit {
+	prCommits, err := github.FetchPRCommits(token, repo, prNumber)
+	if err != nil {
+		return nil
+	}
+
+	commits := make([]models.Commit, 0, len(prCommits))
+	for _, c := range prCommits {
+		commits = append(commits, models.Commit{
+			SHA:       c.SHA,
+			Author:    c.Author,
+			Message:   c.Message,
+			Timestamp: c.Timestamp,
+		})
+	}
+	return commits
+}
+
+// checkStaleness asks Jenkins whether a newer build is queued when the build is behind the PR head
+func checkStaleness(client Client, build *models.Build, jobPath, branch string) {
+	build.NewerQueued = false
+	if !build.IsStale() {
+		return
+	}
+	if queued, err := client.IsQueued(jobPath, branch); err == nil {
+		build.NewerQueued = queued
+	}
+}
+
+// Result is the outcome of fetching one PR
+type Result struct {
+	PRNumber string
+	Build    *models.Build // nil if the fetch failed
+	Err      error
+}
+
+// All fetches the given builds in parallel, keeping their order
+// Only PRNumber needs to be set; other known fields are kept as in Build
+func All(client Client, gh GitHub, builds []models.Build) []Result {
+	results := make([]Result, len(builds))
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	for i, existing := range builds {
+		wg.Add(1)
+		go func(i int, existing models.Build) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			build, err := Build(client, gh, existing.PRNumber, existing)
+			results[i] = Result{PRNumber: existing.PRNumber, Build: build, Err: err}
+		}(i, existing)
+	}
+	wg.Wait()
+	return results
+}
+
+// Merge carries over what a refresh can't know from the tile's previous build:
+// the green streak, the failure analysis of an unchanged failed build, and the
+// Git branch (from GitHub or user input) if the fetch found none
+func Merge(previous models.Build, fetched *models.Build) {
+	fetched.TrackSinceGreen(previous)
+	// A finished build's console log and test report don't change - keep the earlier analysis
+	if fetched.BuildNumber == previous.BuildNumber && fetched.IsFailure() {
+		fetched.FailureCategory = previous.FailureCategory
+		fetched.FailureLine = previous.FailureLine
+		fetched.TestsChecked = previous.TestsChecked
+		fetched.FailedTests = previous.FailedTests
+		fetched.FlakyTests = previous.FlakyTests
+	}
+	if fetched.GitBranch == "" {
+		fetched.GitBranch = previous.GitBranch
+	}
+}
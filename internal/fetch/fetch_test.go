@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// mockClient returns a build per branch, or an error for unknown branches
+type mockClient struct {
+	mu      sync.Mutex
+	builds  map[string]models.Build // Branch -> build
+	queued  bool
+	calls   int
+	history []models.Build // Returned by ListBuilds, newest first
+}
+
+func (m *mockClient) GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	build, ok := m.builds[branch]
+	if !ok {
+		return nil, fmt.Errorf("HTTP 404: not found")
+	}
+	return &build, nil
+}
+
+func (m *mockClient) IsQueued(jobPath, branch string) (bool, error) {
+	return m.queued, nil
+}
+
+func (m *mockClient) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	return m.history[:min(limit, len(m.history))], nil
+}
+
+func TestFetchBuild_KeepsKnownPRInfo(t *testing.T) {
+	client := &mockClient{builds: map[string]models.Build{
+		"PR-3934": {PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning},
+	}}
+	existing := models.Build{GitBranch: "feature/login", PRAuthor: "mpetters", Repository: "org/repo"}
+
+	build, err := Build(client, GitHub{}, "3934", existing)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if build.BuildNumber != 8 || build.GitBranch != "feature/login" || build.PRAuthor != "mpetters" || build.Repository != "org/repo" {
+		t.Errorf("Unexpected build %+v", build)
+	}
+
+	if _, err := Build(client, GitHub{}, "1", models.Build{}); err == nil {
+		t.Error("Expected the Jenkins error to be returned")
+	}
+}
+
+func TestFetchAll_KeepsOrderAndErrors(t *testing.T) {
+	client := &mockClient{builds: map[string]models.Build{}}
+	var builds []models.Build
+	for i := 1; i <= 10; i++ {
+		pr := fmt.Sprint(i)
+		if i != 5 {
+			client.builds["PR-"+pr] = models.Build{PRNumber: pr, BuildNumber: i}
+		}
+		builds = append(builds, models.Build{PRNumber: pr})
+	}
+
+	results := All(client, GitHub{}, builds)
+	if len(results) != 10 || client.calls != 10 {
+		t.Fatalf("Expected 10 results from 10 fetches, got %d from %d", len(results), client.calls)
+	}
+	for i, result := range results {
+		if result.PRNumber != fmt.Sprint(i+1) {
+			t.Errorf("Result %d is for PR %s", i, result.PRNumber)
+		}
+		if i == 4 {
+			if result.Err == nil || !strings.Contains(result.Err.Error(), "404") {
+				t.Errorf("Expected PR 5 to fail, got %v", result.Err)
+			}
+		} else if result.Err != nil || result.Build.BuildNumber != i+1 {
+			t.Errorf("Result %d = %+v", i, result)
+		}
+	}
+}
+
+func TestCheckStaleness(t *testing.T) {
+	mockClient := &mockClient{queued: true}
+
+	t.Run("stale build asks Jenkins about the queue", func(t *testing.T) {
+		build := &models.Build{BuiltSHA: "aaaaaaa111", PRHeadSHA: "bbbbbbb222"}
+		checkStaleness(mockClient, build, "job", "PR-1")
+		if !build.NewerQueued {
+			t.Error("Expected NewerQueued to be set for a stale build with a queued build")
+		}
+	})
+
+	t.Run("current build is never marked queued", func(t *testing.T) {
+		build := &models.Build{BuiltSHA: "aaaaaaa111", PRHeadSHA: "aaaaaaa111", NewerQueued: true}
+		checkStaleness(mockClient, build, "job", "PR-1")
+		if build.NewerQueued {
+			t.Error("Expected NewerQueued to be cleared for an up-to-date build")
+		}
+	})
+}
+
+func TestFetchBuild_BackfillsMissedBuilds(t *testing.T) {
+	red := models.Build{PRNumber: "3934", BuildNumber: 12, Status: models.StatusFailure,
+		Changes: []models.Commit{{SHA: "ccc", BuildNumber: 12}}}
+	client := &mockClient{
+		builds: map[string]models.Build{"PR-3934": red},
+		history: []models.Build{
+			red,
+			{BuildNumber: 11, Status: models.StatusFailure, Changes: []models.Commit{{SHA: "bbb", BuildNumber: 11}}},
+			{BuildNumber: 10, Status: models.StatusFailure, Changes: []models.Commit{{SHA: "aaa", BuildNumber: 10}}},
+			{BuildNumber: 9, Status: models.StatusSuccess},
+			{BuildNumber: 8, Status: models.StatusFailure, Changes: []models.Commit{{SHA: "old", BuildNumber: 8}}},
+		},
+	}
+
+	// A tile added while the PR is already red finds its last green build
+	build, err := Build(client, GitHub{}, "3934", models.Build{PRNumber: "3934"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	Merge(models.Build{PRNumber: "3934"}, build)
+	if build.LastGreenBuild != 9 {
+		t.Errorf("LastGreenBuild = %d, want 9", build.LastGreenBuild)
+	}
+	if got := commitSHAs(build.SinceGreen); got != "aaa,bbb,ccc" {
+		t.Errorf("SinceGreen = %s, want aaa,bbb,ccc", got)
+	}
+
+	// Builds missed between polls are added to the carried history
+	previous := models.Build{PRNumber: "3934", BuildNumber: 10, Status: models.StatusFailure, LastGreenBuild: 9,
+		SinceGreen: []models.Commit{{SHA: "aaa", BuildNumber: 10}}}
+	build, _ = Build(client, GitHub{}, "3934", previous)
+	Merge(previous, build)
+	if got := commitSHAs(build.SinceGreen); got != "aaa,bbb,ccc" || build.LastGreenBuild != 9 {
+		t.Errorf("SinceGreen = %s since #%d, want aaa,bbb,ccc since #9", got, build.LastGreenBuild)
+	}
+}
+
+// commitSHAs joins the SHAs of commits for comparison
+func commitSHAs(commits []models.Commit) string {
+	shas := make([]string, len(commits))
+	for i, commit := range commits {
+		shas[i] = commit.SHA
+	}
+	return strings.Join(shas, ",")
+}
@@ -0,0 +1,168 @@
+package flaky
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Default detection settings (can be overridden with environment variables)
+const (
+	defaultHistoryBuilds = 10
+	defaultMainBranch    = "main"
+)
+
+// Reasons a test is considered flaky
+const (
+	ReasonSameCommit = "passed and failed on the same commit"
+	ReasonNoChanges  = "changed result without code changes"
+)
+
+// Config controls how much build history is scanned for flaky tests
+type Config struct {
+	HistoryBuilds int    // Number of recent builds scanned per branch
+	MainBranch    string // Jenkins branch job of the main line ("" = don't scan)
+}
+
+// LoadConfig returns the detection settings from environment variables
+// FLAKY_HISTORY_BUILDS sets how many builds are scanned (default 10)
+// FLAKY_MAIN_BRANCH sets the main branch job (default "main", "off" to skip it)
+func LoadConfig() Config {
+	cfg := Config{HistoryBuilds: defaultHistoryBuilds, MainBranch: defaultMainBranch}
+
+	if value := os.Getenv("FLAKY_HISTORY_BUILDS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			cfg.HistoryBuilds = n
+		}
+	}
+	if value := os.Getenv("FLAKY_MAIN_BRANCH"); value != "" {
+		cfg.MainBranch = value
+		if strings.EqualFold(value, "off") {
+			cfg.MainBranch = ""
+		}
+	}
+	return cfg
+}
+
+// Run is the test outcome of one completed build
+type Run struct {
+	Branch      string // Jenkins branch job (e.g., "PR-3934" or "main")
+	BuildNumber int
+	SHA         string          // Commit that was built ("" if unknown)
+	BaseSHA     string          // Target branch commit a PR build was merged with ("" for branch builds or if unknown)
+	HasChanges  bool            // The build picked up new commits since the previous build
+	Results     map[string]bool // Test name -> passed
+}
+
+// NewRun creates a Run from a build and its test results
+func NewRun(build models.Build, branch string, results map[string]bool) Run {
+	return Run{
+		Branch:      branch,
+		BuildNumber: build.BuildNumber,
+		SHA:         build.BuiltSHA,
+		BaseSHA:     build.BaseSHA,
+		HasChanges:  len(build.Changes) > 0,
+		Results:     results,
+	}
+}
+
+// Detection is a test found to be flaky in a branch's history
+type Detection struct {
+	Test   string
+	Branch string
+	Builds [2]int // The two builds whose results disagree
+	Reason string
+}
+
+// Detect finds flaky tests in the runs of a single branch
+// A test is flaky if it both passed and failed on the same commit, or if its
+// result flipped between consecutive builds that picked up no new commits
+// For PR builds the same commit means the same PR head merged with the same
+// target branch commit: a rebuild after main moved tests different code
+// Each test is reported at most once
+func Detect(runs []Run) []Detection {
+	sorted := make([]Run, len(runs))
+	copy(sorted, runs)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].BuildNumber < sorted[j].BuildNumber })
+
+	var detections []Detection
+	seen := make(map[string]bool)
+	report := func(test string, first, second Run, reason string) {
+		if seen[test] {
+			return
+		}
+		seen[test] = true
+		detections = append(detections, Detection{
+			Test:   test,
+			Branch: second.Branch,
+			Builds: [2]int{first.BuildNumber, second.BuildNumber},
+			Reason: reason,
+		})
+	}
+
+	// Same commit, different result (re-runs and replays)
+	for i, later := range sorted {
+		if later.SHA == "" {
+			continue
+		}
+		for _, earlier := range sorted[:i] {
+			if earlier.SHA != later.SHA || earlier.BaseSHA != later.BaseSHA {
+				continue
+			}
+			for _, test := range disagreements(earlier, later) {
+				report(test, earlier, later, ReasonSameCommit)
+			}
+		}
+	}
+
+	// Consecutive builds without new commits, different result
+	for i := 1; i < len(sorted); i++ {
+		previous, current := sorted[i-1], sorted[i]
+		if current.HasChanges || differ(previous.SHA, current.SHA) || differ(previous.BaseSHA, current.BaseSHA) {
+			continue
+		}
+		for _, test := range disagreements(previous, current) {
+			report(test, previous, current, ReasonNoChanges)
+		}
+	}
+
+	return detections
+}
+
+// differ reports whether two commits are both known and different
+func differ(a, b string) bool {
+	return a != "" && b != "" && a != b
+}
+
+// disagreements returns the tests that ran in both builds with different results, sorted
+func disagreements(a, b Run) []string {
+	var tests []string
+	for test, passedA := range a.Results {
+		if passedB, ok := b.Results[test]; ok && passedA != passedB {
+			tests = append(tests, test)
+		}
+	}
+	sort.Strings(tests)
+	return tests
+}
+
+// FailedTests returns the names of the tests that failed in a run, sorted
+func FailedTests(results map[string]bool) []string {
+	var failed []string
+	for test, passed := range results {
+		if !passed {
+			failed = append(failed, test)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// String describes a detection for logs and the status bar
+func (d Detection) String() string {
+	return fmt.Sprintf("%s %s (%s #%d/#%d)", d.Test, d.Reason, d.Branch, d.Builds[0], d.Builds[1])
+}
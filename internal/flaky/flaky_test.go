@@ -0,0 +1,143 @@
+package flaky
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		runs []Run
+		want map[string]string // Test -> reason
+	}{
+		{
+			name: "Passed and failed on the same commit",
+			runs: []Run{
+				{Branch: "PR-1", BuildNumber: 3, SHA: "aaa", Results: map[string]bool{"LoginTest.testSession": true}},
+				{Branch: "PR-1", BuildNumber: 2, SHA: "bbb", HasChanges: true, Results: map[string]bool{"LoginTest.testSession": true}},
+				{Branch: "PR-1", BuildNumber: 1, SHA: "aaa", HasChanges: true, Results: map[string]bool{"LoginTest.testSession": false}},
+			},
+			want: map[string]string{"LoginTest.testSession": ReasonSameCommit},
+		},
+		{
+			name: "Flipped without code changes",
+			runs: []Run{
+				{Branch: "main", BuildNumber: 10, HasChanges: true, Results: map[string]bool{"QalTest.testCheckout": true, "MathTest.testAdd": true}},
+				{Branch: "main", BuildNumber: 11, Results: map[string]bool{"QalTest.testCheckout": false, "MathTest.testAdd": true}},
+			},
+			want: map[string]string{"QalTest.testCheckout": ReasonNoChanges},
+		},
+		{
+			name: "Failure after a code change is not flaky",
+			runs: []Run{
+				{Branch: "PR-1", BuildNumber: 1, SHA: "aaa", Results: map[string]bool{"LoginTest.testSession": true}},
+				{Branch: "PR-1", BuildNumber: 2, SHA: "bbb", HasChanges: true, Results: map[string]bool{"LoginTest.testSession": false}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "PR rebuilt after the target branch moved is not flaky",
+			runs: []Run{
+				{Branch: "PR-1", BuildNumber: 1, SHA: "aaa", BaseSHA: "main1", Results: map[string]bool{"LoginTest.testSession": true}},
+				{Branch: "PR-1", BuildNumber: 2, SHA: "aaa", BaseSHA: "main2", Results: map[string]bool{"LoginTest.testSession": false}},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "Same PR head and target branch commit",
+			runs: []Run{
+				{Branch: "PR-1", BuildNumber: 1, SHA: "aaa", BaseSHA: "main1", Results: map[string]bool{"LoginTest.testSession": true}},
+				{Branch: "PR-1", BuildNumber: 2, SHA: "aaa", BaseSHA: "main1", Results: map[string]bool{"LoginTest.testSession": false}},
+			},
+			want: map[string]string{"LoginTest.testSession": ReasonSameCommit},
+		},
+		{
+			name: "Tests missing from one run are ignored",
+			runs: []Run{
+				{Branch: "PR-1", BuildNumber: 1, SHA: "aaa", Results: map[string]bool{"OldTest.testGone": false}},
+				{Branch: "PR-1", BuildNumber: 2, SHA: "aaa", Results: map[string]bool{"NewTest.testAdded": true}},
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detections := Detect(tt.runs)
+			if len(detections) != len(tt.want) {
+				t.Fatalf("Expected %d detections, got %v", len(tt.want), detections)
+			}
+			for _, d := range detections {
+				if reason, ok := tt.want[d.Test]; !ok || reason != d.Reason {
+					t.Errorf("Unexpected detection %s", d)
+				}
+			}
+		})
+	}
+}
+
+func TestFailedTests(t *testing.T) {
+	failed := FailedTests(map[string]bool{"b": false, "a": false, "c": true})
+	if len(failed) != 2 || failed[0] != "a" || failed[1] != "b" {
+		t.Errorf("Expected [a b], got %v", failed)
+	}
+}
+
+func TestStore_RecordAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flaky.json")
+	store := NewStore(path)
+	now := time.Date(2025, 11, 7, 10, 0, 0, 0, time.UTC)
+
+	detection := Detection{Test: "QalTest.testCheckout", Branch: "PR-1", Builds: [2]int{1, 2}, Reason: ReasonSameCommit}
+	if added := store.Record([]Detection{detection}, now); added != 1 {
+		t.Errorf("Expected 1 new flaky test, got %d", added)
+	}
+	// Scanning the same history again doesn't count as a new detection
+	if added := store.Record([]Detection{detection}, now.Add(time.Hour)); added != 0 {
+		t.Errorf("Re-recording should add nothing, got %d", added)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	entries := loaded.List()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Detections != 1 || !entries[0].FirstSeen.Equal(now) {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+
+	if got := loaded.Filter([]string{"MathTest.testAdd", "QalTest.testCheckout"}); len(got) != 1 || got[0] != "QalTest.testCheckout" {
+		t.Errorf("Filter() = %v, want [QalTest.testCheckout]", got)
+	}
+}
+
+func TestLoad_FileNotExist(t *testing.T) {
+	store, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Loading a missing file should not error, got %v", err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("Expected empty store, got %d tests", store.Len())
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("FLAKY_HISTORY_BUILDS", "25")
+	t.Setenv("FLAKY_MAIN_BRANCH", "off")
+
+	cfg := LoadConfig()
+	if cfg.HistoryBuilds != 25 {
+		t.Errorf("HistoryBuilds = %d, want 25", cfg.HistoryBuilds)
+	}
+	if cfg.MainBranch != "" {
+		t.Errorf("MainBranch = %q, want empty when off", cfg.MainBranch)
+	}
+}
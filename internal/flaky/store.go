@@ -0,0 +1,147 @@
+package flaky
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+const storeFileName = ".jenkins-dash-flaky.json"
+
+// Entry is a test in the persistent flaky list
+type Entry struct {
+	Test       string    `json:"test"`
+	Reason     string    `json:"reason"` // Reason of the most recent detection
+	Branch     string    `json:"branch"` // Branch of the most recent detection
+	Builds     [2]int    `json:"builds"` // Builds of the most recent detection
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Detections int       `json:"detections"` // Number of distinct detections
+}
+
+// Store is the persistent list of known flaky tests
+// It is not safe for concurrent use; the UI only touches it from Update
+type Store struct {
+	path  string
+	tests map[string]*Entry
+}
+
+// GetStorePath returns the path to the flaky test list
+// Reads from FLAKY_TESTS_FILE or defaults to ~/.jenkins-dash-flaky.json
+func GetStorePath() string {
+	if path := os.Getenv("FLAKY_TESTS_FILE"); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return storeFileName
+	}
+	return filepath.Join(homeDir, storeFileName)
+}
+
+// NewStore creates an empty store that saves to path ("" = in memory only)
+func NewStore(path string) *Store {
+	return &Store{path: path, tests: make(map[string]*Entry)}
+}
+
+// Load reads the flaky test list from path
+// Returns an empty store if the file doesn't exist (not an error)
+func Load(path string) (*Store, error) {
+	store := NewStore(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		store.tests[entries[i].Test] = &entries[i]
+	}
+	return store, nil
+}
+
+// Save writes the flaky test list to disk
+func (s *Store) Save() error {
+	if s.path == "" {
+		return nil // In-memory store, skip saving
+	}
+
+	data, err := json.MarshalIndent(s.List(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return persistence.WriteAtomic(s.path, data) // Shared with other instances
+}
+
+// Record adds detections to the list and returns how many tests are new
+// Re-detecting the same builds (e.g., on a refresh) doesn't count twice
+func (s *Store) Record(detections []Detection, now time.Time) int {
+	added := 0
+	for _, d := range detections {
+		entry, ok := s.tests[d.Test]
+		if !ok {
+			entry = &Entry{Test: d.Test, FirstSeen: now}
+			s.tests[d.Test] = entry
+			added++
+		} else if entry.Branch == d.Branch && entry.Builds == d.Builds {
+			continue // Already recorded
+		}
+		entry.Reason = d.Reason
+		entry.Branch = d.Branch
+		entry.Builds = d.Builds
+		entry.LastSeen = now
+		entry.Detections++
+	}
+	return added
+}
+
+// IsFlaky reports whether a test is in the list
+func (s *Store) IsFlaky(test string) bool {
+	_, ok := s.tests[test]
+	return ok
+}
+
+// Filter returns the tests that are known to be flaky, in the given order
+func (s *Store) Filter(tests []string) []string {
+	var flaky []string
+	for _, test := range tests {
+		if s.IsFlaky(test) {
+			flaky = append(flaky, test)
+		}
+	}
+	return flaky
+}
+
+// List returns all flaky tests, most recently seen first
+func (s *Store) List() []Entry {
+	entries := make([]Entry, 0, len(s.tests))
+	for _, entry := range s.tests {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].LastSeen.Equal(entries[j].LastSeen) {
+			return entries[i].LastSeen.After(entries[j].LastSeen)
+		}
+		return entries[i].Test < entries[j].Test
+	})
+	return entries
+}
+
+// Len returns the number of known flaky tests
+func (s *Store) Len() int {
+	return len(s.tests)
+}
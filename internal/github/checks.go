@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 )
 
 // CheckStatus represents the status of PR checks
@@ -13,6 +12,7 @@ type CheckStatus struct {
 	PassedChecks int
 	FailedChecks int
 	Summary      string // e.g., "5/8 checks" or "all passed"
+	HeadSHA      string // Commit the checks ran against (the PR head)
 }
 
 // FetchPRCheckStatus fetches the check run status for a PR
@@ -30,9 +30,10 @@ func FetchPRCheckStatus(token, repo, prNumber string) CheckStatus {
 	// Then get check runs for that SHA
 	checks, err := fetchCheckRuns(token, repo, pr.HeadSHA)
 	if err != nil {
-		return CheckStatus{Summary: "unknown"}
+		return CheckStatus{Summary: "unknown", HeadSHA: pr.HeadSHA}
 	}
 
+	checks.HeadSHA = pr.HeadSHA
 	return checks
 }
 
@@ -41,7 +42,7 @@ type prInfo struct {
 }
 
 func fetchPR(token, repo, prNumber string) (*prInfo, error) {
-	client := &http.Client{Timeout: 3 * time.Second}
+	client := newHTTPClient()
 	
 	url := fmt.Sprintf("%s/repos/%s/pulls/%s", githubAPIBase, repo, prNumber)
 	
@@ -79,7 +80,7 @@ func fetchPR(token, repo, prNumber string) (*prInfo, error) {
 }
 
 func fetchCheckRuns(token, repo, sha string) (CheckStatus, error) {
-	client := &http.Client{Timeout: 3 * time.Second}
+	client := newHTTPClient()
 	
 	// Fetch Check Runs (GitHub Actions, GitHub Apps)
 	checkRunsURL := fmt.Sprintf("%s/repos/%s/commits/%s/check-runs", githubAPIBase, repo, sha)
@@ -166,6 +166,9 @@ func TestCheckStatusSummary(t *testing.T) {
 			if status.TotalChecks != tt.totalCount {
 				t.Errorf("Expected %d total checks, got %d", tt.totalCount, status.TotalChecks)
 			}
+			if status.HeadSHA != "abc123" {
+				t.Errorf("Expected head SHA 'abc123', got '%s'", status.HeadSHA)
+			}
 		})
 	}
 }
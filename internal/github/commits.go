@@ -0,0 +1,89 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PRCommit is a single commit on a PR
+type PRCommit struct {
+	SHA       string
+	Author    string // GitHub login, or the git author name if the commit isn't linked to a user
+	Message   string // First line of the commit message
+	Timestamp int64  // Author date in Unix seconds
+}
+
+// FetchPRCommits fetches the commits of a PR, oldest first
+// GitHub returns at most 250 commits for a PR; only the first 100 are fetched
+func FetchPRCommits(token, repo, prNumber string) ([]PRCommit, error) {
+	if repo == "" {
+		repo = defaultRepo
+	}
+
+	client := newHTTPClient()
+
+	url := fmt.Sprintf("%s/repos/%s/pulls/%s/commits?per_page=100", githubAPIBase, repo, prNumber)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var commits []struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+			Author  struct {
+				Name string    `json:"name"`
+				Date time.Time `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return nil, err
+	}
+
+	result := make([]PRCommit, 0, len(commits))
+	for _, c := range commits {
+		author := c.Commit.Author.Name
+		if c.Author != nil && c.Author.Login != "" {
+			author = c.Author.Login
+		}
+
+		message := c.Commit.Message
+		if i := strings.IndexByte(message, '\n'); i >= 0 {
+			message = message[:i]
+		}
+
+		result = append(result, PRCommit{
+			SHA:       c.SHA,
+			Author:    author,
+			Message:   strings.TrimSpace(message),
+			Timestamp: c.Commit.Author.Date.Unix(),
+		})
+	}
+
+	return result, nil
+}
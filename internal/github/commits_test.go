@@ -0,0 +1,64 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchPRCommits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/test-owner/test-repo/pulls/123/commits" {
+			w.Write([]byte(`[
+				{
+					"sha": "aaa111",
+					"commit": {"message": "Add login form\n\nLonger description", "author": {"name": "Jane Doe", "date": "2025-11-07T10:00:00Z"}},
+					"author": {"login": "jdoe"}
+				},
+				{
+					"sha": "bbb222",
+					"commit": {"message": "Fix typo", "author": {"name": "Unlinked Person", "date": "2025-11-07T11:00:00Z"}},
+					"author": null
+				}
+			]`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	oldBase := githubAPIBase
+	githubAPIBase = server.URL
+	defer func() { githubAPIBase = oldBase }()
+
+	commits, err := FetchPRCommits("test-token", "test-owner/test-repo", "123")
+	if err != nil {
+		t.Fatalf("FetchPRCommits() error = %v", err)
+	}
+
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].SHA != "aaa111" || commits[0].Author != "jdoe" || commits[0].Message != "Add login form" {
+		t.Errorf("Unexpected first commit: %+v", commits[0])
+	}
+	if commits[1].Author != "Unlinked Person" {
+		t.Errorf("Expected git author name for unlinked commit, got %q", commits[1].Author)
+	}
+	if commits[0].Timestamp == 0 {
+		t.Error("Expected commit timestamp to be parsed")
+	}
+}
+
+func TestFetchPRCommits_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	oldBase := githubAPIBase
+	githubAPIBase = server.URL
+	defer func() { githubAPIBase = oldBase }()
+
+	if _, err := FetchPRCommits("test-token", "test-owner/test-repo", "999"); err == nil {
+		t.Error("Expected error for missing PR")
+	}
+}
@@ -10,8 +10,21 @@ import (
 var (
 	githubAPIBase = "https://github.intuit.com/api/v3"
 	defaultRepo   = "identity-manage/account"
+
+	// transport carries every GitHub request (nil = http.DefaultTransport)
+	transport http.RoundTripper
 )
 
+// SetTransport replaces the HTTP transport of GitHub requests (e.g., to record metrics)
+func SetTransport(rt http.RoundTripper) {
+	transport = rt
+}
+
+// newHTTPClient returns a client with a short timeout - PR data is optional
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 3 * time.Second, Transport: transport}
+}
+
 // PRInfo contains PR information from GitHub
 type PRInfo struct {
 	BranchName string
@@ -26,7 +39,7 @@ func FetchPRBranch(token, repo, prNumber string) (PRInfo, error) {
 		repo = defaultRepo
 	}
 
-	client := &http.Client{Timeout: 3 * time.Second} // Short timeout
+	client := newHTTPClient()
 	
 	// GitHub API: GET /repos/{owner}/{repo}/pulls/{pull_number}
 	// For identity-manage/account, owner=identity-manage, repo=account
@@ -0,0 +1,379 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+const historyFileName = ".jenkins-dash-history.jsonl"
+
+// Default retention (can be overridden with environment variables)
+const defaultRetention = 90 * 24 * time.Hour
+
+// maxLineBytes bounds a single history line (records with many stages are a few KB)
+const maxLineBytes = 1 << 20
+
+// Record is one observation of a build, as written to the history file
+// A build is usually observed several times (queued, running, finished,
+// classified); the latest observation of a build is its current state
+type Record struct {
+	ObservedAt      time.Time           `json:"observed_at"`
+	JobPath         string              `json:"job,omitempty"` // Jenkins job the build ran in; "" in records written before it was kept
+	PRNumber        string              `json:"pr"`
+	BuildNumber     int                 `json:"build"`
+	Status          models.BuildStatus  `json:"status"`
+	Timestamp       int64               `json:"timestamp"` // Build start, Unix seconds
+	DurationSeconds int                 `json:"duration_s"`
+	BuiltSHA        string              `json:"sha,omitempty"`
+	Stages          []models.Stage      `json:"stages,omitempty"`
+	Causes          []models.BuildCause `json:"causes,omitempty"`
+	FailureCategory string              `json:"failure_category,omitempty"`
+	FailedTests     []string            `json:"failed_tests,omitempty"`
+	FlakyTests      []string            `json:"flaky_tests,omitempty"`
+}
+
+// NewRecord creates a history record from a build observed at the given time
+func NewRecord(build models.Build, observedAt time.Time) Record {
+	return Record{
+		ObservedAt:      observedAt,
+		JobPath:         jenkins.JobPathOf(build),
+		PRNumber:        build.PRNumber,
+		BuildNumber:     build.BuildNumber,
+		Status:          build.Status,
+		Timestamp:       build.Timestamp,
+		DurationSeconds: build.DurationSeconds,
+		BuiltSHA:        build.BuiltSHA,
+		Stages:          build.Stages,
+		Causes:          build.Causes,
+		FailureCategory: build.FailureCategory,
+		FailedTests:     build.FailedTests,
+		FlakyTests:      build.FlakyTests,
+	}
+}
+
+// Finished reports whether the record is a final result (not pending or running)
+func (r Record) Finished() bool {
+	return r.Status != models.StatusPending && r.Status != models.StatusRunning
+}
+
+// FinishedAt returns when the build finished (start + duration)
+func (r Record) FinishedAt() time.Time {
+	return time.Unix(r.Timestamp+int64(r.DurationSeconds), 0)
+}
+
+// Job returns the Jenkins job the build ran in, inferred for old records
+func (r Record) Job() string {
+	if r.JobPath != "" {
+		return r.JobPath
+	}
+	return jenkins.InferJobPath(r.PRNumber)
+}
+
+// key identifies a build across observations
+// PR numbers are only unique within a repository, so the job is part of it
+func (r Record) key() string {
+	return fmt.Sprintf("%s/PR-%s#%d", r.Job(), r.PRNumber, r.BuildNumber)
+}
+
+// Config holds retention and compaction settings
+type Config struct {
+	Retention time.Duration // Records observed longer ago are dropped on compaction (0 = keep forever)
+	Compact   bool          // Compact the file when the store is opened
+}
+
+// LoadConfig returns the history settings from environment variables
+// HISTORY_RETENTION is a Go duration or a number of days (e.g., "90d"), "off" keeps forever
+// HISTORY_COMPACT=off disables compaction on startup
+func LoadConfig() Config {
+	cfg := Config{Retention: defaultRetention, Compact: true}
+	if value := os.Getenv("HISTORY_RETENTION"); value != "" {
+		if retention, err := ParseRetention(value); err == nil {
+			cfg.Retention = retention
+		}
+	}
+	if strings.EqualFold(os.Getenv("HISTORY_COMPACT"), "off") {
+		cfg.Compact = false
+	}
+	return cfg
+}
+
+// ParseRetention parses a retention period such as "90d" or "720h"
+// "off", "never" and "0" mean records are kept forever
+func ParseRetention(value string) (time.Duration, error) {
+	cleaned := strings.TrimSpace(strings.ToLower(value))
+	switch cleaned {
+	case "off", "never", "0":
+		return 0, nil
+	}
+
+	if days, ok := strings.CutSuffix(cleaned, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(cleaned)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("retention cannot be negative, got: %s", value)
+	}
+	return d, nil
+}
+
+// GetHistoryPath returns the path to the history file
+// Reads from HISTORY_FILE or defaults to ~/.jenkins-dash-history.jsonl
+func GetHistoryPath() string {
+	if path := os.Getenv("HISTORY_FILE"); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return historyFileName
+	}
+	return filepath.Join(homeDir, historyFileName)
+}
+
+// Store is an append-only JSON Lines log of build observations
+type Store struct {
+	path string
+	cfg  Config
+
+	mu   sync.Mutex
+	last map[string]models.BuildStatus // Last appended status per build (see Record.key), to skip repeats
+}
+
+// NewStore creates a store that appends to path ("" = discard everything)
+func NewStore(path string, cfg Config) *Store {
+	return &Store{path: path, cfg: cfg, last: make(map[string]models.BuildStatus)}
+}
+
+// Open creates a store for an existing (or new) history file
+// Compacts the file first if enabled, and remembers the last status of each
+// build so restarting the dashboard doesn't record the same results again
+func Open(path string, cfg Config) (*Store, error) {
+	store := NewStore(path, cfg)
+
+	if cfg.Compact {
+		if err := store.Compact(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := store.Records(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		store.last[record.key()] = record.Status
+	}
+	return store, nil
+}
+
+// Observe records a build if its status changed since the last observation
+// Repeated polls of a running or finished build are not recorded
+func (s *Store) Observe(build models.Build, now time.Time) error {
+	if build.BuildNumber == 0 {
+		return nil // Nothing built yet
+	}
+
+	record := NewRecord(build, now)
+
+	s.mu.Lock()
+	status, seen := s.last[record.key()]
+	s.mu.Unlock()
+	if seen && status == record.Status {
+		return nil
+	}
+	return s.Append(record)
+}
+
+// Append writes a record to the end of the history file
+func (s *Store) Append(record Record) error {
+	if s.path == "" {
+		return nil // In-memory store, skip saving
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.last[record.key()] = record.Status
+	return nil
+}
+
+// Filter selects history records; zero fields match everything
+type Filter struct {
+	PRNumber string
+	Since    time.Time // Observed at or after
+	Until    time.Time // Observed before
+}
+
+// Match reports whether a record passes the filter
+func (f Filter) Match(r Record) bool {
+	if f.PRNumber != "" && r.PRNumber != f.PRNumber {
+		return false
+	}
+	if !f.Since.IsZero() && r.ObservedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.ObservedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Records reads all records matching the filter, oldest first
+// Returns nothing if the history file doesn't exist yet (not an error)
+// Lines that can't be parsed (e.g., a write cut short by a crash) are skipped
+func (s *Store) Records(filter Filter) ([]Record, error) {
+	if s.path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.Match(record) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Compact rewrites the history file without expired records and without
+// superseded observations of finished builds (only their latest is kept)
+func (s *Store) Compact(now time.Time) error {
+	records, err := s.Records(Filter{})
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	var kept []Record
+	latest := latestIndex(records)
+	for i, record := range records {
+		if s.cfg.Retention > 0 && now.Sub(record.ObservedAt) > s.cfg.Retention {
+			continue
+		}
+		if final := records[latest[record.key()]]; final.Finished() && latest[record.key()] != i {
+			continue // Intermediate observation of a build that has finished since
+		}
+		kept = append(kept, record)
+	}
+	if len(kept) == len(records) {
+		return nil // Nothing to drop
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range kept {
+		if err := encoder.Encode(record); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// latestIndex maps each build to the index of its last observation
+func latestIndex(records []Record) map[string]int {
+	latest := make(map[string]int)
+	for i, record := range records {
+		latest[record.key()] = i
+	}
+	return latest
+}
+
+// Latest returns the latest observation of each build, ordered by build start time
+func Latest(records []Record) []Record {
+	latest := latestIndex(records)
+	result := make([]Record, 0, len(latest))
+	for i, record := range records {
+		if latest[record.key()] == i {
+			result = append(result, record)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp < result[j].Timestamp })
+	return result
+}
+
+// Finished returns the records that are final results
+func Finished(records []Record) []Record {
+	var finished []Record
+	for _, record := range records {
+		if record.Finished() {
+			finished = append(finished, record)
+		}
+	}
+	return finished
+}
+
+// ByPR groups records by PR (job path and PR number), keeping their order
+func ByPR(records []Record) map[string][]Record {
+	groups := make(map[string][]Record)
+	for _, record := range records {
+		pr := record.Job() + "/PR-" + record.PRNumber
+		groups[pr] = append(groups[pr], record)
+	}
+	return groups
+}
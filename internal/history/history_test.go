@@ -0,0 +1,181 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestStore_ObserveSkipsRepeatedStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path, Config{})
+	now := time.Date(2025, 11, 7, 10, 0, 0, 0, time.UTC)
+
+	build := models.Build{PRNumber: "3934", BuildNumber: 7, Status: models.StatusRunning, Timestamp: now.Unix()}
+	store.Observe(build, now)
+	store.Observe(build, now.Add(10*time.Second)) // Same status - not recorded
+
+	build.Status = models.StatusSuccess
+	build.DurationSeconds = 300
+	build.Stages = []models.Stage{{Name: "Run Unit Tests", Status: "SUCCESS", DurationMillis: 120000}}
+	store.Observe(build, now.Add(5*time.Minute))
+
+	records, err := store.Records(Filter{})
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records (running, success), got %d", len(records))
+	}
+	if records[1].Status != models.StatusSuccess || len(records[1].Stages) != 1 {
+		t.Errorf("Unexpected final record %+v", records[1])
+	}
+
+	// Reopening remembers the last status, so a restart doesn't duplicate results
+	reopened, err := Open(path, Config{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	reopened.Observe(build, now.Add(time.Hour))
+	if records, _ := reopened.Records(Filter{}); len(records) != 2 {
+		t.Errorf("Expected no new record after reopening, got %d records", len(records))
+	}
+}
+
+func TestStore_RecordsFilter(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "history.jsonl"), Config{})
+	now := time.Date(2025, 11, 7, 10, 0, 0, 0, time.UTC)
+
+	store.Append(Record{ObservedAt: now.Add(-48 * time.Hour), PRNumber: "1", BuildNumber: 1, Status: models.StatusFailure})
+	store.Append(Record{ObservedAt: now.Add(-time.Hour), PRNumber: "1", BuildNumber: 2, Status: models.StatusSuccess})
+	store.Append(Record{ObservedAt: now.Add(-time.Hour), PRNumber: "2", BuildNumber: 1, Status: models.StatusSuccess})
+
+	records, _ := store.Records(Filter{PRNumber: "1"})
+	if len(records) != 2 {
+		t.Errorf("Expected 2 records for PR 1, got %d", len(records))
+	}
+
+	records, _ = store.Records(Filter{Since: now.Add(-24 * time.Hour)})
+	if len(records) != 2 {
+		t.Errorf("Expected 2 records in the last day, got %d", len(records))
+	}
+
+	records, _ = store.Records(Filter{Until: now.Add(-24 * time.Hour)})
+	if len(records) != 1 {
+		t.Errorf("Expected 1 record before yesterday, got %d", len(records))
+	}
+}
+
+func TestStore_RecordsSkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	os.WriteFile(path, []byte(`{"pr":"1","build":1,"status":2}`+"\n"+`{"pr":"1","bui`+"\n"), 0644)
+
+	records, err := NewStore(path, Config{}).Records(Filter{})
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("Expected the truncated line to be skipped, got %d records", len(records))
+	}
+}
+
+func TestStore_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2025, 11, 7, 10, 0, 0, 0, time.UTC)
+	store := NewStore(path, Config{Retention: 30 * 24 * time.Hour})
+
+	store.Append(Record{ObservedAt: now.Add(-60 * 24 * time.Hour), PRNumber: "1", BuildNumber: 1, Status: models.StatusSuccess}) // Expired
+	store.Append(Record{ObservedAt: now.Add(-time.Hour), PRNumber: "1", BuildNumber: 2, Status: models.StatusRunning})           // Superseded
+	store.Append(Record{ObservedAt: now.Add(-time.Minute), PRNumber: "1", BuildNumber: 2, Status: models.StatusFailure})
+	store.Append(Record{ObservedAt: now.Add(-time.Minute), PRNumber: "2", BuildNumber: 5, Status: models.StatusRunning}) // Still running - kept
+
+	if err := store.Compact(now); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	records, _ := store.Records(Filter{})
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records after compaction, got %d", len(records))
+	}
+	if records[0].BuildNumber != 2 || records[0].Status != models.StatusFailure {
+		t.Errorf("Expected the final result of build 2 to be kept, got %+v", records[0])
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("Temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestLatestAndFinished(t *testing.T) {
+	t.Setenv("JENKINS_JOB_PATH", "qal") // Records without a job path are filed under it
+	records := []Record{
+		{PRNumber: "1", BuildNumber: 2, Status: models.StatusRunning, Timestamp: 200},
+		{PRNumber: "1", BuildNumber: 1, Status: models.StatusFailure, Timestamp: 100},
+		{PRNumber: "1", BuildNumber: 2, Status: models.StatusSuccess, Timestamp: 200},
+		{PRNumber: "2", BuildNumber: 1, Status: models.StatusRunning, Timestamp: 300},
+	}
+
+	latest := Latest(records)
+	if len(latest) != 3 {
+		t.Fatalf("Expected 3 builds, got %d", len(latest))
+	}
+	if latest[0].BuildNumber != 1 || latest[1].Status != models.StatusSuccess {
+		t.Errorf("Expected builds ordered by start with latest status, got %+v", latest)
+	}
+
+	if finished := Finished(latest); len(finished) != 2 {
+		t.Errorf("Expected 2 finished builds, got %d", len(finished))
+	}
+	if groups := ByPR(latest); len(groups["qal/PR-1"]) != 2 || len(groups["qal/PR-2"]) != 1 {
+		t.Errorf("Unexpected grouping %+v", groups)
+	}
+}
+
+func TestStore_KeepsJobsApart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path, Config{})
+	now := time.Date(2025, 11, 7, 10, 0, 0, 0, time.UTC)
+
+	// The same PR and build number in two repositories are different builds
+	store.Observe(models.Build{JobPath: "qal/job/qal-tests", PRNumber: "12", BuildNumber: 3, Status: models.StatusFailure}, now)
+	store.Observe(models.Build{JobPath: "qal/job/qal-e2e", PRNumber: "12", BuildNumber: 3, Status: models.StatusFailure}, now)
+
+	records, _ := store.Records(Filter{})
+	if len(records) != 2 || len(Latest(records)) != 2 || len(ByPR(records)) != 2 {
+		t.Errorf("Expected the builds of both jobs to be kept apart, got %+v", records)
+	}
+	if records[0].JobPath != "qal/job/qal-tests" {
+		t.Errorf("Expected the job path to be recorded, got %q", records[0].JobPath)
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		err   bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"720h", 720 * time.Hour, false},
+		{"off", 0, false},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseRetention(%q) error = %v, want error %v", tt.input, err, tt.err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseRetention(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
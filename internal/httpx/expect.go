@@ -0,0 +1,24 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// expectedStatusKey is the context key of the status codes a request expects
+type expectedStatusKey struct{}
+
+// ExpectStatus returns a request context under which responses with the given
+// status codes are expected answers (e.g., 404 from a best-effort endpoint)
+// rather than errors
+func ExpectStatus(ctx context.Context, codes ...int) context.Context {
+	return context.WithValue(ctx, expectedStatusKey{}, codes)
+}
+
+// Expected reports whether a status code is an expected answer to a request
+// (see ExpectStatus)
+func Expected(req *http.Request, statusCode int) bool {
+	expected, _ := req.Context().Value(expectedStatusKey{}).([]int)
+	return slices.Contains(expected, statusCode)
+}
@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestExpected(t *testing.T) {
+	plain, _ := http.NewRequest("GET", "http://jenkins/api/json", nil)
+	bestEffort, _ := http.NewRequestWithContext(ExpectStatus(context.Background(), http.StatusNotFound), "GET", "http://jenkins/api/json", nil)
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		statusCode int
+		want       bool
+	}{
+		{"no expectation", plain, http.StatusNotFound, false},
+		{"expected status", bestEffort, http.StatusNotFound, true},
+		{"other status", bestEffort, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expected(tt.req, tt.statusCode); got != tt.want {
+				t.Errorf("Expected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
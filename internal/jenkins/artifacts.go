@@ -0,0 +1,128 @@
+package jenkins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// ListArtifacts returns the files archived by a build
+func (c *Client) ListArtifacts(jobPath, branch string, buildNum int) ([]models.Artifact, error) {
+	var data struct {
+		Artifacts []struct {
+			FileName     string `json:"fileName"`
+			RelativePath string `json:"relativePath"`
+		} `json:"artifacts"`
+	}
+
+	buildURL := BuildJenkinsURL(jobPath, branch, buildNum)
+	if err := c.fetchJSON(buildURL+"/api/json?tree="+url.QueryEscape("artifacts[fileName,relativePath]"), &data); err != nil {
+		return nil, fmt.Errorf("listing artifacts: %w", err)
+	}
+
+	artifacts := make([]models.Artifact, 0, len(data.Artifacts))
+	for _, a := range data.Artifacts {
+		artifacts = append(artifacts, models.Artifact{FileName: a.FileName, RelativePath: a.RelativePath})
+	}
+	return artifacts, nil
+}
+
+// DownloadArtifact downloads a build artifact into destDir and returns the saved path
+// Files are saved as destDir/<branch>-<build>/<relative path> so downloads from different builds and directories don't collide
+// progress (optional) is called as bytes arrive; total is -1 if Jenkins doesn't send a length
+func (c *Client) DownloadArtifact(jobPath, branch string, buildNum int, relativePath, destDir string, progress func(written, total int64)) (string, error) {
+	if relativePath == "" {
+		return "", fmt.Errorf("artifact path cannot be empty")
+	}
+
+	// Keep the artifact's directories, so same-named artifacts don't overwrite each other
+	buildDir := filepath.Join(destDir, fmt.Sprintf("%s-%d", branch, buildNum))
+	destPath := filepath.Join(buildDir, filepath.FromSlash(relativePath))
+	if rel, err := filepath.Rel(buildDir, destPath); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid artifact path %q", relativePath)
+	}
+
+	artifactURL := BuildJenkinsURL(jobPath, branch, buildNum) + "/artifact/" + escapePath(relativePath)
+
+	req, err := http.NewRequest("GET", artifactURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" && c.token != "" {
+		req.SetBasicAuth(c.username, c.token)
+	}
+
+	// Artifacts can be large - don't apply the API client's overall timeout
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", &APIError{Kind: classifyTransportError(err), URL: artifactURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &APIError{Kind: classifyStatus(resp.StatusCode), StatusCode: resp.StatusCode, URL: artifactURL}
+	}
+
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temp file first so a failed download never leaves a truncated artifact
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	var dst io.Writer = tmp
+	if progress != nil {
+		dst = &progressWriter{w: tmp, total: resp.ContentLength, progress: progress}
+	}
+
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("downloading %s: %w", relativePath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+// escapePath URL-escapes each segment of a slash-separated artifact path
+func escapePath(relativePath string) string {
+	segments := strings.Split(relativePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// progressWriter reports bytes written to a progress callback
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
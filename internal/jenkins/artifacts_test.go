@@ -0,0 +1,113 @@
+package jenkins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func newArtifactServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/263/api/json"):
+			w.Write([]byte(`{"artifacts": [
+				{"fileName": "report.html", "relativePath": "target/reports/report.html"},
+				{"fileName": "bundle.zip", "relativePath": "bundle.zip"}
+			]}`))
+		case strings.HasSuffix(r.URL.Path, "/263/artifact/target/reports/report.html"):
+			w.Header().Set("Content-Length", "11")
+			w.Write([]byte("hello world"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	t.Cleanup(func() {
+		jenkinsBaseURL = oldBaseURL
+		server.Close()
+	})
+	return server
+}
+
+func TestListArtifacts(t *testing.T) {
+	newArtifactServer(t)
+	c := NewClient("user", "token")
+
+	artifacts, err := c.ListArtifacts("job", "PR-1", 263)
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+
+	want := []models.Artifact{
+		{FileName: "report.html", RelativePath: "target/reports/report.html"},
+		{FileName: "bundle.zip", RelativePath: "bundle.zip"},
+	}
+	if len(artifacts) != len(want) {
+		t.Fatalf("Expected %d artifacts, got %d", len(want), len(artifacts))
+	}
+	for i := range want {
+		if artifacts[i] != want[i] {
+			t.Errorf("artifact[%d] = %+v, want %+v", i, artifacts[i], want[i])
+		}
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	newArtifactServer(t)
+	c := NewClient("user", "token")
+	destDir := t.TempDir()
+
+	var lastWritten, lastTotal int64
+	path, err := c.DownloadArtifact("job", "PR-1", 263, "target/reports/report.html", destDir, func(written, total int64) {
+		lastWritten, lastTotal = written, total
+	})
+	if err != nil {
+		t.Fatalf("DownloadArtifact() error = %v", err)
+	}
+
+	if want := filepath.Join(destDir, "PR-1-263", "target", "reports", "report.html"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "hello world" {
+		t.Errorf("Downloaded content = %q (err %v), want %q", data, err, "hello world")
+	}
+	if lastWritten != 11 || lastTotal != 11 {
+		t.Errorf("Progress = %d/%d, want 11/11", lastWritten, lastTotal)
+	}
+
+	// No temp files left behind
+	entries, _ := os.ReadDir(filepath.Join(destDir, "PR-1-263", "target", "reports"))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the artifact in the download dir, got %d entries", len(entries))
+	}
+}
+
+func TestDownloadArtifact_RejectsPathOutsideBuildDir(t *testing.T) {
+	c := NewClient("user", "token")
+	if _, err := c.DownloadArtifact("job", "PR-1", 263, "../../escape.txt", t.TempDir(), nil); err == nil {
+		t.Error("Expected an artifact path outside the download dir to be rejected")
+	}
+}
+
+func TestDownloadArtifact_NotFound(t *testing.T) {
+	newArtifactServer(t)
+	c := NewClient("user", "token")
+	destDir := t.TempDir()
+
+	_, err := c.DownloadArtifact("job", "PR-1", 263, "missing.txt", destDir, nil)
+	if ErrorKindOf(err) != models.ErrorNotFound {
+		t.Errorf("Expected not_found error, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(destDir, "PR-1-263", "missing.txt")); statErr == nil {
+		t.Error("Failed download should not create the artifact file")
+	}
+}
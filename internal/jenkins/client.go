@@ -1,64 +1,287 @@
 package jenkins
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/mpetters/jenkins-dash/internal/httpx"
 	"github.com/mpetters/jenkins-dash/internal/models"
 )
 
+// RetryPolicy controls how transient failures (5xx, timeouts, offline) are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first (1 = no retries)
+	BaseDelay   time.Duration // Delay before the first retry, doubled per attempt
+	MaxDelay    time.Duration // Upper bound for a single delay
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    4 * time.Second,
+	}
+}
+
+// delay returns the jittered backoff before the given retry (1-based)
+// Uses "equal jitter": half the exponential delay plus a random half
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			d = p.MaxDelay
+			break
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// buildTreeQuery limits /api/json to the fields of BuildResponse
+// Without it Jenkins returns the full actions and changeSets arrays, which are huge
+const buildTreeQuery = "number,building,result,duration,timestamp," +
+	"actions[_class,lastBuiltRevision[SHA1,branch[SHA1,name]],parameters[_class,name,value]," +
+	"causes[_class,shortDescription,userId,userName,upstreamProject,upstreamBuild]," +
+	"revision[hash,pullHash,baseHash]]," +
+	"changeSets[kind,items[commitId,msg,timestamp,author[fullName]]]"
+
+// maxCachedStages bounds the completed-build stage cache
+const maxCachedStages = 256
+
 // Client handles communication with Jenkins API
 type Client struct {
 	baseURL    string
 	username   string
 	token      string
 	httpClient *http.Client
+	retry      RetryPolicy
+	sleep      func(time.Duration) // Overridable for tests
+
+	// Stages of completed builds never change, so wfapi is skipped once they're cached
+	cacheStages bool
+	stagesMu    sync.Mutex
+	stages      map[string]stageData
+
+	// Fetch the Blue Ocean stage graph (parallel branches, nested stages)
+	// Jobs where Blue Ocean answered 404 (plugin not installed) aren't asked again
+	stageGraph   bool
+	noStageGraph map[string]bool // Job path -> Blue Ocean not available, guarded by stagesMu
+}
+
+// stageData holds the wfapi stages and Blue Ocean nodes of a completed build
+type stageData struct {
+	stages []Stage
+	nodes  []BlueOceanNode
 }
 
 // NewClient creates a new Jenkins API client
 // Jenkins uses Basic Auth with username:token
 func NewClient(username, token string) *Client {
 	return &Client{
-		baseURL:    jenkinsBaseURL,
-		username:   username,
-		token:      token,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:      jenkinsBaseURL,
+		username:     username,
+		token:        token,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		retry:        DefaultRetryPolicy(),
+		sleep:        time.Sleep,
+		cacheStages:  true,
+		stages:       make(map[string]stageData),
+		stageGraph:   true,
+		noStageGraph: make(map[string]bool),
 	}
 }
 
+// SetStageCache enables or disables skipping /wfapi/describe for completed builds
+// whose stages were already fetched (enabled by default)
+func (c *Client) SetStageCache(enabled bool) {
+	c.stagesMu.Lock()
+	defer c.stagesMu.Unlock()
+
+	c.cacheStages = enabled
+	if !enabled {
+		c.stages = make(map[string]stageData)
+	}
+}
+
+// SetStageGraph enables or disables fetching the Blue Ocean stage graph
+// Without it, stages come from wfapi only and parallel branches are flattened
+func (c *Client) SetStageGraph(enabled bool) {
+	c.stageGraph = enabled
+}
+
+// SetTransport replaces the HTTP transport of Jenkins requests (e.g., to record metrics)
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// SetRetryPolicy overrides the retry policy for transient failures
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
+
 // GetBuildStatus fetches build status from Jenkins API
-// Makes TWO calls: /api/json for basic info, /wfapi/describe for stages
+// Makes up to TWO calls: /api/json (tree-limited) for basic info, then the Blue
+// Ocean nodes API for the stage graph, or else /wfapi/describe for flat stages
+// The stage calls are skipped for completed builds whose stages are already cached
 func (c *Client) GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error) {
 	baseURL := BuildJenkinsURL(jobPath, branch, buildNum)
 
-	// Call 1: Get basic build info from standard API
-	basicData, err := c.fetchJSON(baseURL + "/api/json")
-	if err != nil {
+	// Call 1: Get basic build info from standard API (only the fields we parse)
+	var data BuildResponse
+	if err := c.fetchJSON(baseURL+"/api/json?tree="+url.QueryEscape(buildTreeQuery), &data); err != nil {
 		return nil, fmt.Errorf("fetching build info: %w", err)
 	}
 
-	// Call 2: Get stages from wfapi (best effort, don't fail if missing)
-	stagesData, _ := c.fetchJSON(baseURL + "/wfapi/describe")
+	// Stages are immutable once a build has a result
+	completed := !data.Building && data.Result != ""
+	cacheKey := stageCacheKey(jobPath, branch, data.Number)
+
+	if cached, ok := c.cachedStages(cacheKey); ok && completed {
+		data.Stages, data.Nodes = cached.stages, cached.nodes
+	} else {
+		// Call 2: Get the stage graph from Blue Ocean (best effort, the plugin may not be installed)
+		if c.stageGraph && data.Number > 0 && c.hasStageGraph(jobPath) {
+			var nodes []BlueOceanNode
+			err := c.fetchOptionalJSON(BuildBlueOceanNodesURL(jobPath, branch, data.Number), &nodes)
+			if err == nil {
+				data.Nodes = nodes
+			} else if ErrorKindOf(err) == models.ErrorNotFound {
+				c.markNoStageGraph(jobPath)
+			}
+		}
+
+		// Or else get flat stages from wfapi (best effort, don't fail if missing)
+		if len(data.Nodes) == 0 {
+			var describe WfapiDescribe
+			if err := c.fetchOptionalJSON(baseURL+"/wfapi/describe", &describe); err == nil {
+				data.Stages = describe.Stages
+			}
+		}
 
-	// Merge stages into basic data
-	if stagesData != nil {
-		if stages, ok := stagesData["stages"]; ok {
-			basicData["stages"] = stages
+		if completed && (data.Stages != nil || data.Nodes != nil) {
+			c.storeStages(cacheKey, stageData{stages: data.Stages, nodes: data.Nodes})
 		}
 	}
 
 	// Convert to Build struct
-	build := ParseBuildResponse(basicData, branch, jobPath)
+	build := ParseBuildResponse(data, branch, jobPath)
 	return &build, nil
 }
 
-// fetchJSON is a helper to fetch and parse JSON from Jenkins
-func (c *Client) fetchJSON(url string) (map[string]interface{}, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// IsQueued reports whether a new build of the branch is waiting in the Jenkins queue
+func (c *Client) IsQueued(jobPath, branch string) (bool, error) {
+	var job struct {
+		InQueue bool `json:"inQueue"`
+	}
+	if err := c.fetchJSON(BuildJobURL(jobPath, branch)+"/api/json?tree=inQueue", &job); err != nil {
+		return false, fmt.Errorf("fetching queue state: %w", err)
+	}
+	return job.InQueue, nil
+}
+
+// hasStageGraph reports whether Blue Ocean may serve the stage graph of a job
+func (c *Client) hasStageGraph(jobPath string) bool {
+	c.stagesMu.Lock()
+	defer c.stagesMu.Unlock()
+	return !c.noStageGraph[jobPath]
+}
+
+// markNoStageGraph remembers that Blue Ocean isn't available for a job
+func (c *Client) markNoStageGraph(jobPath string) {
+	c.stagesMu.Lock()
+	defer c.stagesMu.Unlock()
+	c.noStageGraph[jobPath] = true
+}
+
+// stageCacheKey identifies a specific build of a branch
+func stageCacheKey(jobPath, branch string, buildNum int) string {
+	return fmt.Sprintf("%s/%s/%d", jobPath, branch, buildNum)
+}
+
+// cachedStages returns the cached stages for a completed build
+func (c *Client) cachedStages(key string) (stageData, bool) {
+	c.stagesMu.Lock()
+	defer c.stagesMu.Unlock()
+
+	if !c.cacheStages {
+		return stageData{}, false
+	}
+	stages, ok := c.stages[key]
+	return stages, ok
+}
+
+// storeStages caches the stages for a completed build
+func (c *Client) storeStages(key string, stages stageData) {
+	c.stagesMu.Lock()
+	defer c.stagesMu.Unlock()
+
+	if !c.cacheStages {
+		return
+	}
+	// Simple bound: start over rather than tracking recency
+	if len(c.stages) >= maxCachedStages {
+		c.stages = make(map[string]stageData)
+	}
+	c.stages[key] = stages
+}
+
+// fetchJSON is a helper to fetch JSON from Jenkins and decode it into v
+// Transient failures are retried with jittered exponential backoff
+// All failures are returned as *APIError
+func (c *Client) fetchJSON(url string, v interface{}) error {
+	return c.fetchJSONContext(context.Background(), url, v)
+}
+
+// fetchOptionalJSON is fetchJSON for endpoints that may not exist (plugins not
+// installed, builds without a test report): a 404 is still returned as an
+// error, but isn't counted as a failed request in the metrics
+func (c *Client) fetchOptionalJSON(url string, v interface{}) error {
+	return c.fetchJSONContext(httpx.ExpectStatus(context.Background(), http.StatusNotFound), url, v)
+}
+
+// fetchJSONContext implements fetchJSON with a request context
+func (c *Client) fetchJSONContext(ctx context.Context, url string, v interface{}) error {
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr *APIError
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			c.sleep(c.retry.delay(attempt - 1))
+		}
+
+		err := c.fetchJSONOnce(ctx, url, v)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !err.Kind.Transient() {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// fetchJSONOnce performs a single request without retries
+func (c *Client) fetchJSONOnce(ctx context.Context, url string, v interface{}) *APIError {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return &APIError{Kind: models.ErrorUnknown, URL: url, Err: err}
 	}
 
 	if c.username != "" && c.token != "" {
@@ -68,18 +291,17 @@ func (c *Client) fetchJSON(url string) (map[string]interface{}, error) {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return &APIError{Kind: classifyTransportError(err), URL: url, Err: err}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status %d for URL: %s", resp.StatusCode, url)
+		return &APIError{Kind: classifyStatus(resp.StatusCode), StatusCode: resp.StatusCode, URL: url}
 	}
 
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &APIError{Kind: models.ErrorUnknown, URL: url, Err: err}
 	}
 
-	return data, nil
+	return nil
 }
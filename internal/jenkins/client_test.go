@@ -1,6 +1,10 @@
 package jenkins
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,20 +15,18 @@ import (
 func TestParseBuildResponse(t *testing.T) {
 	tests := []struct {
 		name     string
-		response map[string]interface{}
+		response BuildResponse
 		prBranch string
 		want     models.Build
 	}{
 		{
 			name: "Successful completed build",
-			response: map[string]interface{}{
-				"number":          float64(142),
-				"building":        false,
-				"result":          "SUCCESS",
-				"duration":        float64(323000),
-				"timestamp":       float64(1699564800000),
-				"url":             "https://build.intuit.com/job/PR-3859/142",
-				"fullDisplayName": "auth-service » PR-3859 » #142",
+			response: BuildResponse{
+				Number:    142,
+				Building:  false,
+				Result:    "SUCCESS",
+				Duration:  323000,
+				Timestamp: 1699564800000,
 			},
 			prBranch: "PR-3859",
 			want: models.Build{
@@ -38,14 +40,12 @@ func TestParseBuildResponse(t *testing.T) {
 		},
 		{
 			name: "Failed build",
-			response: map[string]interface{}{
-				"number":          float64(143),
-				"building":        false,
-				"result":          "FAILURE",
-				"duration":        float64(180000),
-				"timestamp":       float64(1699564900000),
-				"url":             "https://build.intuit.com/job/PR-3860/143",
-				"fullDisplayName": "auth-service » PR-3860 » #143",
+			response: BuildResponse{
+				Number:    143,
+				Building:  false,
+				Result:    "FAILURE",
+				Duration:  180000,
+				Timestamp: 1699564900000,
 			},
 			prBranch: "PR-3860",
 			want: models.Build{
@@ -59,14 +59,11 @@ func TestParseBuildResponse(t *testing.T) {
 		},
 		{
 			name: "Running build",
-			response: map[string]interface{}{
-				"number":          float64(144),
-				"building":        true,
-				"result":          nil,
-				"duration":        float64(0),
-				"timestamp":       float64(time.Now().Unix() * 1000),
-				"url":             "https://build.intuit.com/job/PR-3861/144",
-				"fullDisplayName": "auth-service » PR-3861 » #144",
+			response: BuildResponse{
+				Number:    144,
+				Building:  true,
+				Duration:  0,
+				Timestamp: time.Now().Unix() * 1000,
 			},
 			prBranch: "PR-3861",
 			want: models.Build{
@@ -135,3 +132,160 @@ func TestExtractJobName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBuildStatus_UsesTreeQueryAndCachesCompletedStages(t *testing.T) {
+	var apiCalls, wfapiCalls int
+	var treeParam string
+	building := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/api/json"):
+			apiCalls++
+			treeParam = r.URL.Query().Get("tree")
+			fmt.Fprintf(w, `{"number": 7, "building": %t, "result": %s, "duration": 1000, "timestamp": 1699564800000}`,
+				building, map[bool]string{true: "null", false: `"SUCCESS"`}[building])
+		case strings.HasSuffix(r.URL.Path, "/wfapi/describe"):
+			wfapiCalls++
+			w.Write([]byte(`{"stages": [{"name": "BUILD:", "status": "SUCCESS"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	c := NewClient("user", "token")
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetBuildStatus("job", "PR-1", 0); err != nil {
+			t.Fatalf("GetBuildStatus() error = %v", err)
+		}
+	}
+
+	if treeParam != buildTreeQuery {
+		t.Errorf("Expected tree query %q, got %q", buildTreeQuery, treeParam)
+	}
+	if apiCalls != 3 {
+		t.Errorf("Expected 3 /api/json calls, got %d", apiCalls)
+	}
+	if wfapiCalls != 1 {
+		t.Errorf("Completed build stages should be cached, got %d wfapi calls", wfapiCalls)
+	}
+
+	// Running builds always fetch stages
+	building = true
+	wfapiCalls = 0
+	c.GetBuildStatus("job", "PR-1", 0)
+	c.GetBuildStatus("job", "PR-1", 0)
+	if wfapiCalls != 2 {
+		t.Errorf("Running build should fetch wfapi every time, got %d calls", wfapiCalls)
+	}
+
+	// Disabling the cache always fetches stages
+	building = false
+	wfapiCalls = 0
+	c.SetStageCache(false)
+	c.GetBuildStatus("job", "PR-1", 0)
+	c.GetBuildStatus("job", "PR-1", 0)
+	if wfapiCalls != 2 {
+		t.Errorf("Disabled stage cache should fetch wfapi every time, got %d calls", wfapiCalls)
+	}
+}
+
+func TestGetBuildStatus_FetchesAndCachesStageGraph(t *testing.T) {
+	var nodesCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/api/json"):
+			w.Write([]byte(`{"number": 7, "building": false, "result": "SUCCESS"}`))
+		case strings.HasSuffix(r.URL.Path, "/wfapi/describe"):
+			w.Write([]byte(`{"stages": [{"name": "Tests", "status": "SUCCESS"}, {"name": "unit", "status": "SUCCESS"}]}`))
+		case r.URL.Path == "/team/blue/rest/organizations/jenkins/pipelines/svc/branches/PR-1/runs/7/nodes/":
+			nodesCalls++
+			w.Write([]byte(`[
+				{"id": "6", "displayName": "Tests", "type": "STAGE", "state": "FINISHED", "result": "SUCCESS"},
+				{"id": "9", "displayName": "unit", "type": "PARALLEL", "state": "FINISHED", "result": "SUCCESS", "firstParent": "6"}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	c := NewClient("user", "token")
+	for i := 0; i < 2; i++ {
+		build, err := c.GetBuildStatus("team/job/svc", "PR-1", 0)
+		if err != nil {
+			t.Fatalf("GetBuildStatus() error = %v", err)
+		}
+		if len(build.Stages) != 2 || build.Stages[1].ParentID != "6" || !build.Stages[1].Parallel {
+			t.Fatalf("Expected stages from the stage graph, got %+v", build.Stages)
+		}
+	}
+	if nodesCalls != 1 {
+		t.Errorf("Completed build stage graph should be cached, got %d calls", nodesCalls)
+	}
+
+	// Disabling the stage graph falls back to the flat wfapi stages
+	c.SetStageCache(false)
+	c.SetStageGraph(false)
+	build, _ := c.GetBuildStatus("team/job/svc", "PR-1", 0)
+	if nodesCalls != 1 || build.Stages[1].ParentID != "" {
+		t.Errorf("Disabled stage graph should use wfapi only, got %d calls, stages %+v", nodesCalls, build.Stages)
+	}
+}
+
+func TestGetBuildStatus_SkipsWfapiAndMissingBlueOcean(t *testing.T) {
+	var nodesCalls, wfapiCalls int
+	blueOcean := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/api/json"):
+			w.Write([]byte(`{"number": 7, "building": true, "result": null}`))
+		case strings.HasSuffix(r.URL.Path, "/wfapi/describe"):
+			wfapiCalls++
+			w.Write([]byte(`{"stages": [{"name": "Tests", "status": "IN_PROGRESS"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/nodes/") && blueOcean:
+			nodesCalls++
+			w.Write([]byte(`[{"id": "6", "displayName": "Tests", "type": "STAGE", "state": "RUNNING"}]`))
+		default:
+			nodesCalls++
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	// A running build with a stage graph takes two requests per poll
+	c := NewClient("user", "token")
+	c.GetBuildStatus("team/job/svc", "PR-1", 0)
+	c.GetBuildStatus("team/job/svc", "PR-1", 0)
+	if nodesCalls != 2 || wfapiCalls != 0 {
+		t.Errorf("Expected the stage graph only, got %d nodes and %d wfapi calls", nodesCalls, wfapiCalls)
+	}
+
+	// Without Blue Ocean, the 404 is remembered and wfapi is used
+	blueOcean = false
+	nodesCalls = 0
+	c = NewClient("user", "token")
+	for i := 0; i < 3; i++ {
+		build, err := c.GetBuildStatus("team/job/svc", "PR-1", 0)
+		if err != nil || len(build.Stages) != 1 {
+			t.Fatalf("Expected wfapi stages, got %+v, %v", build, err)
+		}
+	}
+	if nodesCalls != 1 || wfapiCalls != 3 {
+		t.Errorf("Expected Blue Ocean asked once and wfapi every poll, got %d nodes and %d wfapi calls", nodesCalls, wfapiCalls)
+	}
+}
@@ -0,0 +1,103 @@
+package jenkins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxConsoleBytes caps how much of a console log is fetched
+// Failures are almost always reported near the end, so only the tail is fetched
+const maxConsoleBytes = 1 << 20 // 1 MB
+
+// GetConsoleLog fetches the end of a build's plain-text console log (the last
+// 1 MB for huge logs)
+// The log's size comes from a HEAD request to logText/progressiveText, so the
+// tail is fetched from an offset instead of streaming the whole log
+func (c *Client) GetConsoleLog(jobPath, branch string, buildNum int) (string, error) {
+	textURL := BuildJenkinsURL(jobPath, branch, buildNum) + "/logText/progressiveText"
+
+	size, err := c.consoleSize(textURL)
+	if err != nil {
+		return "", err
+	}
+	var start int64
+	if size > maxConsoleBytes {
+		start = size - maxConsoleBytes
+	}
+
+	resp, err := c.consoleRequest("GET", textURL+"?start="+strconv.FormatInt(start, 10))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Without a size (e.g., a proxy dropped the header) the whole log streams in
+	tail, err := readTail(resp.Body, maxConsoleBytes)
+	if err != nil {
+		return "", fmt.Errorf("reading console log: %w", err)
+	}
+	return string(tail), nil
+}
+
+// consoleSize returns the length of a console log from its X-Text-Size header
+// Returns 0 if the header is missing, so the log is read from the start
+func (c *Client) consoleSize(textURL string) (int64, error) {
+	resp, err := c.consoleRequest("HEAD", textURL+"?start=0")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil || size < 0 {
+		return 0, nil
+	}
+	return size, nil
+}
+
+// consoleRequest sends a request for a console log, returning the response if it is 200 OK
+func (c *Client) consoleRequest(method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" && c.token != "" {
+		req.SetBasicAuth(c.username, c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &APIError{Kind: classifyTransportError(err), URL: url, Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &APIError{Kind: classifyStatus(resp.StatusCode), StatusCode: resp.StatusCode, URL: url}
+	}
+	return resp, nil
+}
+
+// readTail reads r to the end, keeping at most the last limit bytes
+func readTail(r io.Reader, limit int) ([]byte, error) {
+	buf := make([]byte, 0, 64*1024)
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if len(buf) > 2*limit {
+			// Compact occasionally rather than on every read
+			buf = append(buf[:0], buf[len(buf)-limit:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(buf) > limit {
+		buf = buf[len(buf)-limit:]
+	}
+	return buf, nil
+}
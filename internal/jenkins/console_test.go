@@ -0,0 +1,87 @@
+package jenkins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestGetConsoleLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/PR-1/7/logText/progressiveText") {
+			w.Write([]byte("Started by user\nnpm ERR! Test failed\nFinished: FAILURE\n"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	c := NewClient("user", "token")
+
+	log, err := c.GetConsoleLog("job", "PR-1", 7)
+	if err != nil {
+		t.Fatalf("GetConsoleLog() error = %v", err)
+	}
+	if !strings.Contains(log, "npm ERR! Test failed") {
+		t.Errorf("Unexpected console log %q", log)
+	}
+
+	if _, err := c.GetConsoleLog("job", "PR-1", 8); ErrorKindOf(err) != models.ErrorNotFound {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestGetConsoleLog_FetchesOnlyTheTail(t *testing.T) {
+	size := int64(maxConsoleBytes + 5000)
+	var starts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Text-Size", strconv.FormatInt(size, 10))
+		if r.Method == http.MethodHead {
+			return
+		}
+		starts = append(starts, r.URL.Query().Get("start"))
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		w.Write([]byte(strings.Repeat("x", int(size-start-7)) + "FAILURE"))
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	log, err := NewClient("user", "token").GetConsoleLog("job", "PR-1", 7)
+	if err != nil {
+		t.Fatalf("GetConsoleLog() error = %v", err)
+	}
+	if len(starts) != 1 || starts[0] != "5000" {
+		t.Errorf("Expected the log to be fetched from offset 5000, got %v", starts)
+	}
+	if len(log) != maxConsoleBytes || !strings.HasSuffix(log, "FAILURE") {
+		t.Errorf("Expected the last %d bytes, got %d", maxConsoleBytes, len(log))
+	}
+}
+
+func TestReadTail(t *testing.T) {
+	data := strings.Repeat("a", 100*1024) + strings.Repeat("b", 10)
+
+	got, err := readTail(strings.NewReader(data), 20)
+	if err != nil {
+		t.Fatalf("readTail() error = %v", err)
+	}
+	if string(got) != strings.Repeat("a", 10)+strings.Repeat("b", 10) {
+		t.Errorf("Expected last 20 bytes, got %q", got)
+	}
+
+	got, _ = readTail(strings.NewReader("short"), 20)
+	if string(got) != "short" {
+		t.Errorf("Expected whole input when under limit, got %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package jenkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// APIError is returned when a Jenkins request fails
+// Kind classifies the failure so callers can decide whether to retry and how to render it
+type APIError struct {
+	Kind       models.ErrorKind
+	StatusCode int // 0 if the request never got a response
+	URL        string
+	Err        error // Underlying transport error, if any
+}
+
+// Error returns a short, human-readable description (without the URL)
+func (e *APIError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s (HTTP %d)", DescribeErrorKind(e.Kind), e.StatusCode)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", DescribeErrorKind(e.Kind), e.Err)
+	}
+	return DescribeErrorKind(e.Kind)
+}
+
+// Unwrap returns the underlying transport error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// DescribeErrorKind returns a short description of an error class
+func DescribeErrorKind(kind models.ErrorKind) string {
+	switch kind {
+	case models.ErrorAuth:
+		return "authentication failed"
+	case models.ErrorForbidden:
+		return "access denied"
+	case models.ErrorNotFound:
+		return "not built yet"
+	case models.ErrorServer:
+		return "Jenkins server error"
+	case models.ErrorTimeout:
+		return "request timed out"
+	case models.ErrorOffline:
+		return "Jenkins unreachable"
+	default:
+		return "request failed"
+	}
+}
+
+// ErrorKindOf returns the classification of an error returned by the client
+// Errors that did not come from an APIError are classified by their transport cause
+func ErrorKindOf(err error) models.ErrorKind {
+	if err == nil {
+		return models.ErrorNone
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return classifyTransportError(err)
+}
+
+// classifyStatus maps an HTTP status code to an error class
+func classifyStatus(statusCode int) models.ErrorKind {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return models.ErrorAuth
+	case statusCode == http.StatusForbidden:
+		return models.ErrorForbidden
+	case statusCode == http.StatusNotFound:
+		return models.ErrorNotFound
+	case statusCode == http.StatusTooManyRequests, statusCode >= 500:
+		return models.ErrorServer
+	default:
+		return models.ErrorUnknown
+	}
+}
+
+// classifyTransportError maps a failed http.Client.Do error to an error class
+func classifyTransportError(err error) models.ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return models.ErrorTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return models.ErrorTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return models.ErrorOffline
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return models.ErrorOffline
+	}
+
+	return models.ErrorUnknown
+}
@@ -0,0 +1,158 @@
+package jenkins
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// newTestClient returns a client with retries that don't actually sleep
+func newTestClient(sleeps *[]time.Duration) *Client {
+	c := NewClient("user", "token")
+	c.sleep = func(d time.Duration) { *sleeps = append(*sleeps, d) }
+	return c
+}
+
+func TestFetchJSON_ClassifiesStatusCodes(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		wantKind   models.ErrorKind
+		wantTries  int
+	}{
+		{http.StatusUnauthorized, models.ErrorAuth, 1},
+		{http.StatusForbidden, models.ErrorForbidden, 1},
+		{http.StatusNotFound, models.ErrorNotFound, 1},
+		{http.StatusBadGateway, models.ErrorServer, 3},
+		{http.StatusTooManyRequests, models.ErrorServer, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("HTTP %d", tt.statusCode), func(t *testing.T) {
+			tries := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tries++
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			var sleeps []time.Duration
+			c := newTestClient(&sleeps)
+
+			var data BuildResponse
+			err := c.fetchJSON(server.URL+"/api/json", &data)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+
+			if got := ErrorKindOf(err); got != tt.wantKind {
+				t.Errorf("ErrorKindOf() = %v, want %v", got, tt.wantKind)
+			}
+			if tries != tt.wantTries {
+				t.Errorf("Expected %d attempts, got %d", tt.wantTries, tries)
+			}
+			if strings.Contains(err.Error(), server.URL) {
+				t.Errorf("Error message should not contain the URL, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestFetchJSON_RetriesTransientThenSucceeds(t *testing.T) {
+	tries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tries++
+		if tries < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"number": 42}`))
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	c := newTestClient(&sleeps)
+
+	var data BuildResponse
+	if err := c.fetchJSON(server.URL, &data); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if data.Number != 42 {
+		t.Errorf("Expected number 42, got %v", data.Number)
+	}
+	if len(sleeps) != 2 {
+		t.Fatalf("Expected 2 backoff sleeps, got %d", len(sleeps))
+	}
+}
+
+func TestFetchJSON_OfflineHost(t *testing.T) {
+	// Grab a free port and close it so the connection is refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var sleeps []time.Duration
+	c := newTestClient(&sleeps)
+
+	var data BuildResponse
+	err = c.fetchJSON("http://"+addr+"/api/json", &data)
+	if got := ErrorKindOf(err); got != models.ErrorOffline {
+		t.Errorf("ErrorKindOf() = %v, want offline (err: %v)", got, err)
+	}
+	if len(sleeps) != 2 {
+		t.Errorf("Offline errors should be retried, got %d sleeps", len(sleeps))
+	}
+}
+
+func TestFetchJSON_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	c := newTestClient(&sleeps)
+	c.httpClient.Timeout = 20 * time.Millisecond
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	var data BuildResponse
+	err := c.fetchJSON(server.URL, &data)
+	if got := ErrorKindOf(err); got != models.ErrorTimeout {
+		t.Errorf("ErrorKindOf() = %v, want timeout (err: %v)", got, err)
+	}
+}
+
+func TestRetryPolicy_DelayIsJitteredAndCapped(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for i := 0; i < 50; i++ {
+		if d := policy.delay(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("delay(1) = %v, want within [50ms, 100ms]", d)
+		}
+		if d := policy.delay(4); d < 150*time.Millisecond || d > 300*time.Millisecond {
+			t.Fatalf("delay(4) = %v, want within [150ms, 300ms] (capped)", d)
+		}
+	}
+}
+
+func TestErrorKindOf_WrappedErrors(t *testing.T) {
+	wrapped := fmt.Errorf("fetching build info: %w", &APIError{Kind: models.ErrorNotFound, StatusCode: 404})
+	if got := ErrorKindOf(wrapped); got != models.ErrorNotFound {
+		t.Errorf("ErrorKindOf(wrapped) = %v, want not_found", got)
+	}
+	if got := ErrorKindOf(errors.New("boom")); got != models.ErrorUnknown {
+		t.Errorf("ErrorKindOf(plain) = %v, want unknown", got)
+	}
+	if got := ErrorKindOf(nil); got != models.ErrorNone {
+		t.Errorf("ErrorKindOf(nil) = %v, want none", got)
+	}
+}
@@ -0,0 +1,58 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// testReportTreeQuery limits /testReport/api/json to test names and results
+const testReportTreeQuery = "suites[cases[className,name,status]]"
+
+// ListBuilds returns the most recent builds of a branch, newest first
+// Builds are parsed like GetBuildStatus but without stages (no wfapi calls)
+func (c *Client) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	var job struct {
+		Builds []BuildResponse `json:"builds"`
+	}
+
+	tree := fmt.Sprintf("builds[%s]{0,%d}", buildTreeQuery, limit)
+	if err := c.fetchJSON(BuildJobURL(jobPath, branch)+"/api/json?tree="+url.QueryEscape(tree), &job); err != nil {
+		return nil, fmt.Errorf("listing builds: %w", err)
+	}
+
+	builds := make([]models.Build, 0, len(job.Builds))
+	for _, data := range job.Builds {
+		builds = append(builds, ParseBuildResponse(data, branch, jobPath))
+	}
+	return builds, nil
+}
+
+// GetTestResults returns the result of every test case of a build, keyed by
+// "className.name" (true = passed). Skipped tests are left out.
+// Returns nil without error if the build has no test report
+func (c *Client) GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error) {
+	var report TestReport
+
+	reportURL := BuildJenkinsURL(jobPath, branch, buildNum) + "/testReport/api/json?tree=" + url.QueryEscape(testReportTreeQuery)
+	if err := c.fetchOptionalJSON(reportURL, &report); err != nil {
+		if ErrorKindOf(err) == models.ErrorNotFound {
+			return nil, nil // Build didn't publish test results
+		}
+		return nil, fmt.Errorf("fetching test report: %w", err)
+	}
+
+	results := make(map[string]bool)
+	for _, suite := range report.Suites {
+		for _, tc := range suite.Cases {
+			switch tc.Status {
+			case "PASSED", "FIXED":
+				results[tc.FullName()] = true
+			case "FAILED", "REGRESSION":
+				results[tc.FullName()] = false
+			}
+		}
+	}
+	return results, nil
+}
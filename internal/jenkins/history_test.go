@@ -0,0 +1,66 @@
+package jenkins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListBuildsAndTestResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/job/PR-1/api/json"):
+			if !strings.HasSuffix(r.URL.Query().Get("tree"), "{0,5}") {
+				t.Errorf("Expected build limit in tree query, got %q", r.URL.Query().Get("tree"))
+			}
+			w.Write([]byte(`{"builds": [
+				{"number": 8, "result": "FAILURE", "actions": [{"revision": {"pullHash": "abc123"}}]},
+				{"number": 7, "result": "SUCCESS", "changeSets": [{"items": [{"commitId": "abc123"}]}]}
+			]}`))
+		case strings.HasSuffix(r.URL.Path, "/PR-1/8/testReport/api/json"):
+			w.Write([]byte(`{"suites": [{"cases": [
+				{"className": "com.acme.LoginTest", "name": "testSession", "status": "REGRESSION"},
+				{"className": "com.acme.LoginTest", "name": "testLogout", "status": "PASSED"},
+				{"className": "com.acme.LoginTest", "name": "testIgnored", "status": "SKIPPED"}
+			]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldBaseURL := jenkinsBaseURL
+	jenkinsBaseURL = server.URL
+	defer func() { jenkinsBaseURL = oldBaseURL }()
+
+	c := NewClient("user", "token")
+
+	builds, err := c.ListBuilds("job", "PR-1", 5)
+	if err != nil {
+		t.Fatalf("ListBuilds() error = %v", err)
+	}
+	if len(builds) != 2 || builds[0].BuildNumber != 8 || builds[0].BuiltSHA != "abc123" {
+		t.Errorf("Unexpected builds %+v", builds)
+	}
+	if len(builds[1].Changes) != 1 {
+		t.Errorf("Expected changes to be parsed, got %+v", builds[1].Changes)
+	}
+
+	results, err := c.GetTestResults("job", "PR-1", 8)
+	if err != nil {
+		t.Fatalf("GetTestResults() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Skipped tests should be left out, got %v", results)
+	}
+	if passed, ok := results["com.acme.LoginTest.testSession"]; !ok || passed {
+		t.Error("REGRESSION should count as a failure")
+	}
+
+	// Builds without a test report are not an error
+	results, err = c.GetTestResults("job", "PR-1", 7)
+	if err != nil || results != nil {
+		t.Errorf("Expected no results and no error for a missing report, got %v, %v", results, err)
+	}
+}
@@ -1,56 +1,59 @@
 package jenkins
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/mpetters/jenkins-dash/internal/models"
 )
 
-// ParseBuildResponse parses Jenkins API JSON response into a Build object
-func ParseBuildResponse(data map[string]interface{}, prBranch, jobPath string) models.Build {
+// branchParameterNames are build parameters that may carry the source branch,
+// checked when the git BuildData action doesn't name a feature branch
+var branchParameterNames = []string{"CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"}
+
+// ParseBuildResponse parses a Jenkins build response into a Build object
+func ParseBuildResponse(data BuildResponse, prBranch, jobPath string) models.Build {
 	// Extract PR number from branch (e.g., "PR-3859" -> "3859")
 	prNumber := strings.TrimPrefix(prBranch, "PR-")
 
 	// Determine status
-	building, _ := data["building"].(bool)
-	result, _ := data["result"].(string)
-
 	var status models.BuildStatus
-	if building {
+	if data.Building {
 		status = models.StatusRunning
-	} else if result == "SUCCESS" {
+	} else if data.Result == "SUCCESS" {
 		status = models.StatusSuccess
-	} else if result == "FAILURE" {
+	} else if data.Result == "FAILURE" {
 		status = models.StatusFailure
-	} else if result == "" || result == "null" {
+	} else if data.Result == "ABORTED" {
+		status = models.StatusAborted
+	} else if data.Result == "" || data.Result == "null" {
 		status = models.StatusPending
 	} else {
 		status = models.StatusError
 	}
 
-	// Extract basic fields
-	buildNumber := int(getFloat(data, "number"))
-
 	// Calculate duration
-	durationMs := getFloat(data, "duration")
-	durationSeconds := int(durationMs / 1000)
+	durationSeconds := int(data.Duration / 1000)
 
 	// If still running, calculate from timestamp
 	if status == models.StatusRunning {
-		timestampMs := getFloat(data, "timestamp")
-		currentTimeMs := float64(time.Now().Unix() * 1000)
-		durationSeconds = int((currentTimeMs - timestampMs) / 1000)
+		currentTimeMs := time.Now().Unix() * 1000
+		durationSeconds = int((currentTimeMs - data.Timestamp) / 1000)
 	}
 
 	// Extract timestamp
-	timestampMs := getFloat(data, "timestamp")
-	timestamp := int64(timestampMs / 1000)
+	timestamp := data.Timestamp / 1000
 
-	// Extract stage and job info from stages array
+	// Extract stage and job info from the stage graph, or else the flat wfapi stages
 	var stage, jobName string
-	if stagesData, ok := data["stages"].([]interface{}); ok && len(stagesData) > 0 {
-		stage, jobName = ExtractStageInfo(stagesData, status)
+	stages := extractStages(data.Stages)
+	if len(data.Nodes) > 0 {
+		graph := BuildStageGraph(data.Nodes)
+		stages = FlattenStageGraph(graph)
+		stage, jobName = ExtractGraphStageInfo(graph, status)
+	} else if len(data.Stages) > 0 {
+		stage, jobName = ExtractStageInfo(data.Stages, status)
 	} else {
 		// No stages data - show status-based text
 		switch status {
@@ -58,6 +61,8 @@ func ParseBuildResponse(data map[string]interface{}, prBranch, jobPath string) m
 			stage, jobName = "Passed", "Passed"
 		case models.StatusFailure:
 			stage, jobName = "Failed", "Failed"
+		case models.StatusAborted:
+			stage, jobName = "Aborted", "Aborted"
 		case models.StatusRunning:
 			stage, jobName = "Running", "In Progress"
 		case models.StatusPending:
@@ -66,13 +71,13 @@ func ParseBuildResponse(data map[string]interface{}, prBranch, jobPath string) m
 			stage, jobName = "Unknown", "Unknown"
 		}
 	}
-	
+
 	// Extract Git branch from actions if available
 	gitBranch := extractGitBranch(data)
 
 	// Build Blue Ocean URL for better pipeline visualization
-	blueOceanURL := BuildBlueOceanBuildURL(jobPath, prBranch, buildNumber)
-	
+	blueOceanURL := BuildBlueOceanBuildURL(jobPath, prBranch, data.Number)
+
 	return models.Build{
 		PRNumber:        prNumber,
 		GitBranch:       gitBranch,
@@ -80,11 +85,17 @@ func ParseBuildResponse(data map[string]interface{}, prBranch, jobPath string) m
 		Stage:           stage,
 		JobName:         jobName,
 		JobPath:         jobPath,
-		BuildNumber:     buildNumber,
+		BuildNumber:     data.Number,
 		BuildURL:        blueOceanURL, // Use Blue Ocean URL instead of classic
 		PRURL:           BuildPRURL(prNumber),
 		DurationSeconds: durationSeconds,
 		Timestamp:       timestamp,
+		BuiltSHA:        extractBuiltSHA(data),
+		BaseSHA:         extractBaseSHA(data),
+		Stages:          stages,
+		Causes:          extractCauses(data),
+		Parameters:      extractParameters(data),
+		Changes:         extractChanges(data),
 	}
 }
 
@@ -103,19 +114,12 @@ func ExtractJobName(fullDisplayName string) string {
 	return "Unknown"
 }
 
-// Helper function to safely extract float from map
-func getFloat(data map[string]interface{}, key string) float64 {
-	if val, ok := data[key].(float64); ok {
-		return val
-	}
-	return 0
-}
-
-// ExtractStageInfo extracts phase and job information from stages
+// ExtractStageInfo extracts phase and job information from flat wfapi stages
+// wfapi loses the pipeline structure, so phases are recognized by their labels
 // Stage = outer phase label (e.g., "BUILD:", "QAL:")
 // Job = nested task name (e.g., "Podman Multi-Stage Build(NO Tests)")
-// For completed builds, returns simple "Passed"/"Failed"
-func ExtractStageInfo(stages []interface{}, buildStatus models.BuildStatus) (phase string, jobs string) {
+// For completed builds, returns simple "Passed"/"Failed"/"Aborted"
+func ExtractStageInfo(stages []Stage, buildStatus models.BuildStatus) (phase string, jobs string) {
 	if len(stages) == 0 {
 		return "Unknown", "Unknown"
 	}
@@ -127,30 +131,25 @@ func ExtractStageInfo(stages []interface{}, buildStatus models.BuildStatus) (pha
 	if buildStatus == models.StatusFailure || buildStatus == models.StatusError {
 		return "Failed", "Failed"
 	}
+	if buildStatus == models.StatusAborted {
+		return "Aborted", "Aborted"
+	}
 
 	// For running/pending builds, find the nested structure
-	var currentPhase string      // Last "LABEL:" seen
+	var currentPhase string        // Last "LABEL:" seen
 	var phaseForActiveTasks string // Phase label where active tasks are
-	var activeTasks []string      // Tasks that are IN_PROGRESS
-
-	for _, stageData := range stages {
-		stageMap, ok := stageData.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		stageName, _ := stageMap["name"].(string)
-		stageStatus, _ := stageMap["status"].(string)
+	var activeTasks []string       // Tasks that are IN_PROGRESS
 
+	for _, stage := range stages {
 		// Phase labels end with ":" (e.g., "BUILD:", "QAL:")
-		if len(stageName) > 0 && stageName[len(stageName)-1] == ':' {
-			currentPhase = stageName
+		if strings.HasSuffix(stage.Name, ":") {
+			currentPhase = stage.Name
 			continue
 		}
 
 		// Track IN_PROGRESS tasks and remember which phase they belong to
-		if stageStatus == "IN_PROGRESS" {
-			activeTasks = append(activeTasks, stageName)
+		if stage.Status == "IN_PROGRESS" {
+			activeTasks = append(activeTasks, stage.Name)
 			if phaseForActiveTasks == "" {
 				phaseForActiveTasks = currentPhase // Remember the phase label
 			}
@@ -177,56 +176,172 @@ func ExtractStageInfo(stages []interface{}, buildStatus models.BuildStatus) (pha
 }
 
 // extractGitBranch attempts to extract the Git branch name from Jenkins actions
+// Checks the git BuildData action first, then branch-like build parameters
 // Filters out master/main branches since those are base branches, not PR branches
-func extractGitBranch(data map[string]interface{}) string {
-	// Try to get from actions array
-	if actions, ok := data["actions"].([]interface{}); ok {
-		for _, action := range actions {
-			actionMap, ok := action.(map[string]interface{})
-			if !ok {
-				continue
-			}
+func extractGitBranch(data BuildResponse) string {
+	for _, action := range data.Actions {
+		if action.LastBuiltRevision == nil || len(action.LastBuiltRevision.Branch) == 0 {
+			continue
+		}
+		// Extract short branch name (e.g., "origin/feature/auth" -> "feature/auth")
+		if branch := featureBranch(shortBranchName(action.LastBuiltRevision.Branch[0].Name)); branch != "" {
+			return branch
+		}
+		break // Base branch reported - try parameters instead
+	}
 
-			// Look for branch name in various possible fields
-			if branchName, ok := actionMap["lastBuiltRevision"].(map[string]interface{}); ok {
-				if branch, ok := branchName["branch"].([]interface{}); ok && len(branch) > 0 {
-					if branchMap, ok := branch[0].(map[string]interface{}); ok {
-						if name, ok := branchMap["name"].(string); ok {
-							// Extract short branch name (e.g., "origin/feature/auth" -> "feature/auth")
-							parts := strings.Split(name, "/")
-							var shortName string
-							if len(parts) > 1 {
-								shortName = strings.Join(parts[1:], "/")
-							} else {
-								shortName = name
-							}
-							
-							// Filter out master/main branches - those are base branches, not PR branches
-							// We want the feature branch name from GitHub, not the base branch
-							if shortName == "master" || shortName == "main" || 
-							   shortName == "remotes/origin/master" || shortName == "remotes/origin/main" {
-								return "" // Return empty so GitHub branch is preferred
-							}
-							
-							return shortName
-						}
-					}
-				}
-			}
+	params := extractParameters(data)
+	for _, name := range branchParameterNames {
+		if value := params[name]; value != "" {
+			// Parameters usually hold the plain branch name, but GIT_BRANCH may include the remote
+			value = strings.TrimPrefix(strings.TrimPrefix(value, "refs/heads/"), "origin/")
+			return featureBranch(value)
 		}
 	}
 
 	return "" // Will fallback to GitHub branch or PR number in tile
 }
 
-// Helper function to extract current stage from stages array (legacy)
-func extractCurrentStage(data map[string]interface{}) string {
-	stages, ok := data["stages"].([]interface{})
-	if !ok || len(stages) == 0 {
-		return "Unknown"
+// shortBranchName strips the remote prefix from a branch name
+// Example: "origin/feature/auth" -> "feature/auth"
+func shortBranchName(name string) string {
+	parts := strings.Split(name, "/")
+	if len(parts) > 1 {
+		return strings.Join(parts[1:], "/")
+	}
+	return name
+}
+
+// featureBranch returns "" for base branches so the GitHub branch is preferred
+func featureBranch(shortName string) string {
+	if shortName == "master" || shortName == "main" ||
+		shortName == "remotes/origin/master" || shortName == "remotes/origin/main" {
+		return ""
+	}
+	return shortName
+}
+
+// extractBuiltSHA returns the source commit the build was for, if reported
+// The multibranch revision is preferred: for merge-strategy PR builds the git
+// plugin's lastBuiltRevision is the synthetic merge commit, which never equals
+// the PR head. lastBuiltRevision is used for builds that weren't merged with a
+// target branch (branch builds, PR builds without a base commit); a merge
+// build without a PR head returns "" (staleness unknown)
+func extractBuiltSHA(data BuildResponse) string {
+	var lastBuilt string
+	merged := false
+	for _, action := range data.Actions {
+		if action.LastBuiltRevision != nil && lastBuilt == "" {
+			lastBuilt = action.LastBuiltRevision.SHA1
+		}
+		if action.Revision == nil {
+			continue
+		}
+		if action.Revision.PullHash != "" {
+			return action.Revision.PullHash
+		}
+		if action.Revision.Hash != "" {
+			return action.Revision.Hash
+		}
+		merged = merged || action.Revision.BaseHash != ""
+	}
+	if merged {
+		return ""
+	}
+	return lastBuilt
+}
+
+// extractBaseSHA returns the target branch commit a PR build was merged with,
+// if reported
+func extractBaseSHA(data BuildResponse) string {
+	for _, action := range data.Actions {
+		if action.Revision != nil && action.Revision.BaseHash != "" {
+			return action.Revision.BaseHash
+		}
+	}
+	return ""
+}
+
+// extractStages converts wfapi stages to model stages
+func extractStages(stages []Stage) []models.Stage {
+	if len(stages) == 0 {
+		return nil
+	}
+	result := make([]models.Stage, 0, len(stages))
+	for _, stage := range stages {
+		result = append(result, models.Stage{
+			Name:           stage.Name,
+			Status:         stage.Status,
+			StartMillis:    stage.StartTimeMillis,
+			DurationMillis: stage.DurationMillis,
+		})
+	}
+	return result
+}
+
+// extractCauses returns why the build was started, in the order Jenkins reports them
+func extractCauses(data BuildResponse) []models.BuildCause {
+	var causes []models.BuildCause
+	for _, action := range data.Actions {
+		for _, cause := range action.Causes {
+			causes = append(causes, models.BuildCause{
+				Type:        causeType(cause.Class),
+				Description: cause.ShortDescription,
+				UserID:      cause.UserID,
+				UserName:    cause.UserName,
+			})
+		}
 	}
+	return causes
+}
 
-	// Use new extraction logic
-	phase, _ := ExtractStageInfo(stages, models.StatusRunning)
-	return phase
+// causeType shortens a Jenkins cause class to its simple name
+// Example: "hudson.model.Cause$UserIdCause" -> "UserIdCause"
+func causeType(class string) string {
+	if i := strings.LastIndexAny(class, ".$"); i >= 0 {
+		return class[i+1:]
+	}
+	return class
+}
+
+// extractParameters returns the build parameters as strings, keyed by name
+func extractParameters(data BuildResponse) map[string]string {
+	var params map[string]string
+	for _, action := range data.Actions {
+		for _, param := range action.Parameters {
+			if param.Name == "" || param.Value == nil {
+				continue
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[param.Name] = fmt.Sprint(param.Value)
+		}
+	}
+	return params
+}
+
+// extractChanges returns the commits new in this build, tagged with the build number
+func extractChanges(data BuildResponse) []models.Commit {
+	var commits []models.Commit
+	for _, changeSet := range data.ChangeSets {
+		for _, item := range changeSet.Items {
+			commits = append(commits, models.Commit{
+				SHA:         item.CommitID,
+				Author:      item.Author.FullName,
+				Message:     firstLine(item.Msg),
+				Timestamp:   item.Timestamp / 1000,
+				BuildNumber: data.Number,
+			})
+		}
+	}
+	return commits
+}
+
+// firstLine returns the first line of a commit message
+func firstLine(message string) string {
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		return strings.TrimSpace(message[:i])
+	}
+	return strings.TrimSpace(message)
 }
@@ -1,6 +1,9 @@
 package jenkins
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/mpetters/jenkins-dash/internal/models"
@@ -10,97 +13,57 @@ import (
 func TestExtractGitBranch_IgnoresMasterBranches(t *testing.T) {
 	tests := []struct {
 		name     string
-		data     map[string]interface{}
+		data     BuildResponse
 		expected string
 	}{
 		{
 			name: "returns empty for master branch",
-			data: map[string]interface{}{
-				"actions": []interface{}{
-					map[string]interface{}{
-						"lastBuiltRevision": map[string]interface{}{
-							"branch": []interface{}{
-								map[string]interface{}{
-									"name": "origin/master",
-								},
-							},
-						},
-					},
+			data: BuildResponse{
+				Actions: []Action{
+					{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/master"}}}}},
 				},
 			},
 			expected: "", // Should return empty, not "master"
 		},
 		{
 			name: "returns empty for main branch",
-			data: map[string]interface{}{
-				"actions": []interface{}{
-					map[string]interface{}{
-						"lastBuiltRevision": map[string]interface{}{
-							"branch": []interface{}{
-								map[string]interface{}{
-									"name": "origin/main",
-								},
-							},
-						},
-					},
+			data: BuildResponse{
+				Actions: []Action{
+					{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/main"}}}}},
 				},
 			},
 			expected: "", // Should return empty, not "main"
 		},
 		{
 			name: "returns empty for refs/remotes/origin/master",
-			data: map[string]interface{}{
-				"actions": []interface{}{
-					map[string]interface{}{
-						"lastBuiltRevision": map[string]interface{}{
-							"branch": []interface{}{
-								map[string]interface{}{
-									"name": "refs/remotes/origin/master",
-								},
-							},
-						},
-					},
+			data: BuildResponse{
+				Actions: []Action{
+					{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "refs/remotes/origin/master"}}}}},
 				},
 			},
 			expected: "", // Should return empty
 		},
 		{
 			name: "returns feature branch",
-			data: map[string]interface{}{
-				"actions": []interface{}{
-					map[string]interface{}{
-						"lastBuiltRevision": map[string]interface{}{
-							"branch": []interface{}{
-								map[string]interface{}{
-									"name": "origin/feature/add-auth",
-								},
-							},
-						},
-					},
+			data: BuildResponse{
+				Actions: []Action{
+					{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/feature/add-auth"}}}}},
 				},
 			},
 			expected: "feature/add-auth",
 		},
 		{
 			name: "returns bugfix branch",
-			data: map[string]interface{}{
-				"actions": []interface{}{
-					map[string]interface{}{
-						"lastBuiltRevision": map[string]interface{}{
-							"branch": []interface{}{
-								map[string]interface{}{
-									"name": "origin/bugfix/fix-login",
-								},
-							},
-						},
-					},
+			data: BuildResponse{
+				Actions: []Action{
+					{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/bugfix/fix-login"}}}}},
 				},
 			},
 			expected: "bugfix/fix-login",
 		},
 		{
 			name:     "returns empty for no branch data",
-			data:     map[string]interface{}{},
+			data:     BuildResponse{},
 			expected: "",
 		},
 	}
@@ -117,22 +80,14 @@ func TestExtractGitBranch_IgnoresMasterBranches(t *testing.T) {
 
 // Test that ParseBuildResponse doesn't overwrite GitBranch with master/main
 func TestParseBuildResponse_PreservesFeatureBranches(t *testing.T) {
-	data := map[string]interface{}{
-		"building": false,
-		"result":   "SUCCESS",
-		"number":   float64(142),
-		"duration": float64(120000),
-		"timestamp": float64(1234567890000),
-		"actions": []interface{}{
-			map[string]interface{}{
-				"lastBuiltRevision": map[string]interface{}{
-					"branch": []interface{}{
-						map[string]interface{}{
-							"name": "origin/master", // Jenkins reports master
-						},
-					},
-				},
-			},
+	data := BuildResponse{
+		Building:  false,
+		Result:    "SUCCESS",
+		Number:    142,
+		Duration:  120000,
+		Timestamp: 1234567890000,
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/master"}}}}}, // Jenkins reports master
 		},
 	}
 
@@ -146,22 +101,14 @@ func TestParseBuildResponse_PreservesFeatureBranches(t *testing.T) {
 
 // Test that ParseBuildResponse preserves feature branches from Jenkins
 func TestParseBuildResponse_UsesJenkinsFeatureBranch(t *testing.T) {
-	data := map[string]interface{}{
-		"building": false,
-		"result":   "SUCCESS",
-		"number":   float64(142),
-		"duration": float64(120000),
-		"timestamp": float64(1234567890000),
-		"actions": []interface{}{
-			map[string]interface{}{
-				"lastBuiltRevision": map[string]interface{}{
-					"branch": []interface{}{
-						map[string]interface{}{
-							"name": "origin/feature/new-feature",
-						},
-					},
-				},
-			},
+	data := BuildResponse{
+		Building:  false,
+		Result:    "SUCCESS",
+		Number:    142,
+		Duration:  120000,
+		Timestamp: 1234567890000,
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/feature/new-feature"}}}}},
 		},
 	}
 
@@ -174,12 +121,12 @@ func TestParseBuildResponse_UsesJenkinsFeatureBranch(t *testing.T) {
 }
 
 func TestParseBuildResponse_BasicFields(t *testing.T) {
-	data := map[string]interface{}{
-		"building": false,
-		"result":   "SUCCESS",
-		"number":   float64(142),
-		"duration": float64(120000),
-		"timestamp": float64(1234567890000),
+	data := BuildResponse{
+		Building:  false,
+		Result:    "SUCCESS",
+		Number:    142,
+		Duration:  120000,
+		Timestamp: 1234567890000,
 	}
 
 	build := ParseBuildResponse(data, "PR-3859", "test/job/path")
@@ -198,6 +145,20 @@ func TestParseBuildResponse_BasicFields(t *testing.T) {
 	}
 }
 
+// Test that aborted builds are neither failures nor fetch errors
+func TestParseBuildResponse_Aborted(t *testing.T) {
+	data := BuildResponse{Result: "ABORTED", Number: 7, Duration: 60000, Timestamp: 1234567890000}
+
+	build := ParseBuildResponse(data, "PR-3859", "test/job/path")
+
+	if build.Status != models.StatusAborted || build.IsFailure() {
+		t.Errorf("Status = %v, want %v", build.Status, models.StatusAborted)
+	}
+	if build.Stage != "Aborted" {
+		t.Errorf("Stage = %q, want %q", build.Stage, "Aborted")
+	}
+}
+
 func TestExtractJobName_ValidInput(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -218,9 +179,9 @@ func TestExtractJobName_ValidInput(t *testing.T) {
 }
 
 func TestExtractStageInfo_CompletedBuild(t *testing.T) {
-	stages := []interface{}{
-		map[string]interface{}{"name": "BUILD:", "status": "SUCCESS"},
-		map[string]interface{}{"name": "Test", "status": "SUCCESS"},
+	stages := []Stage{
+		{Name: "BUILD:", Status: "SUCCESS"},
+		{Name: "Test", Status: "SUCCESS"},
 	}
 
 	phase, job := ExtractStageInfo(stages, models.StatusSuccess)
@@ -235,11 +196,11 @@ func TestExtractStageInfo_CompletedBuild(t *testing.T) {
 }
 
 func TestExtractStageInfo_RunningBuild(t *testing.T) {
-	stages := []interface{}{
-		map[string]interface{}{"name": "BUILD:", "status": "SUCCESS"},
-		map[string]interface{}{"name": "Compile", "status": "SUCCESS"},
-		map[string]interface{}{"name": "QAL:", "status": "IN_PROGRESS"},
-		map[string]interface{}{"name": "Integration Tests", "status": "IN_PROGRESS"},
+	stages := []Stage{
+		{Name: "BUILD:", Status: "SUCCESS"},
+		{Name: "Compile", Status: "SUCCESS"},
+		{Name: "QAL:", Status: "IN_PROGRESS"},
+		{Name: "Integration Tests", Status: "IN_PROGRESS"},
 	}
 
 	phase, job := ExtractStageInfo(stages, models.StatusRunning)
@@ -251,3 +212,187 @@ func TestExtractStageInfo_RunningBuild(t *testing.T) {
 		t.Errorf("Expected job 'Integration Tests', got %q", job)
 	}
 }
+
+// Test that a raw Jenkins payload decodes into the typed structs and captures causes, parameters and SHA
+func TestParseBuildResponse_TypedActions(t *testing.T) {
+	payload := `{
+		"number": 263,
+		"building": false,
+		"result": "SUCCESS",
+		"duration": 60000,
+		"timestamp": 1699564800000,
+		"actions": [
+			{"_class": "hudson.model.CauseAction", "causes": [
+				{"_class": "hudson.model.Cause$UserIdCause", "shortDescription": "Started by user Jane Doe", "userId": "jdoe", "userName": "Jane Doe"}
+			]},
+			{"_class": "hudson.model.ParametersAction", "parameters": [
+				{"_class": "hudson.model.StringParameterValue", "name": "CHANGE_BRANCH", "value": "feature/typed"},
+				{"_class": "hudson.model.BooleanParameterValue", "name": "SKIP_E2E", "value": true}
+			]},
+			{"_class": "hudson.plugins.git.util.BuildData", "lastBuiltRevision": {
+				"SHA1": "abc123def456",
+				"branch": [{"SHA1": "abc123def456", "name": "PR-3934"}]
+			}},
+			{}
+		]
+	}`
+
+	var data BuildResponse
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	build := ParseBuildResponse(data, "PR-3934", "test/job/path")
+
+	if build.BuiltSHA != "abc123def456" {
+		t.Errorf("BuiltSHA = %q, want the git plugin's revision (no base commit reported)", build.BuiltSHA)
+	}
+	if len(build.Causes) != 1 {
+		t.Fatalf("Expected 1 cause, got %d", len(build.Causes))
+	}
+	cause := build.Causes[0]
+	if cause.Type != "UserIdCause" || cause.UserID != "jdoe" || cause.Description != "Started by user Jane Doe" {
+		t.Errorf("Unexpected cause: %+v", cause)
+	}
+	if build.Parameters["CHANGE_BRANCH"] != "feature/typed" {
+		t.Errorf("CHANGE_BRANCH = %q, want feature/typed", build.Parameters["CHANGE_BRANCH"])
+	}
+	if build.Parameters["SKIP_E2E"] != "true" {
+		t.Errorf("SKIP_E2E = %q, want true", build.Parameters["SKIP_E2E"])
+	}
+}
+
+// Test that a branch parameter is used when BuildData only reports the base branch
+func TestExtractGitBranch_FallsBackToParameters(t *testing.T) {
+	data := BuildResponse{
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{Branch: []BranchRef{{Name: "origin/main"}}}}},
+			{ParametersAction: ParametersAction{Parameters: []Parameter{{Name: "CHANGE_BRANCH", Value: "feature/from-param"}}}},
+		},
+	}
+
+	if got := extractGitBranch(data); got != "feature/from-param" {
+		t.Errorf("extractGitBranch() = %q, want feature/from-param", got)
+	}
+}
+
+// Test that the multibranch PR head is preferred over the git plugin's merge commit
+func TestExtractBuiltSHA_PrefersPullHash(t *testing.T) {
+	data := BuildResponse{
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{SHA1: "merge0000"}}},
+			{SCMRevisionAction: SCMRevisionAction{Revision: &SCMRevision{PullHash: "head1111", BaseHash: "base2222"}}},
+		},
+	}
+
+	if got := extractBuiltSHA(data); got != "head1111" {
+		t.Errorf("extractBuiltSHA() = %q, want head1111", got)
+	}
+	if got := extractBaseSHA(data); got != "base2222" {
+		t.Errorf("extractBaseSHA() = %q, want base2222", got)
+	}
+}
+
+// Test that a merge commit doesn't mark every merge-strategy PR build stale
+func TestExtractBuiltSHA_IgnoresMergeCommit(t *testing.T) {
+	data := BuildResponse{
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{SHA1: "merge0000"}}},
+			{SCMRevisionAction: SCMRevisionAction{Revision: &SCMRevision{BaseHash: "base2222"}}},
+		},
+	}
+
+	if got := extractBuiltSHA(data); got != "" {
+		t.Errorf("extractBuiltSHA() = %q, want empty", got)
+	}
+}
+
+// Test that lastBuiltRevision is used for builds that weren't merged
+func TestExtractBuiltSHA_FallsBackToLastBuiltRevision(t *testing.T) {
+	data := BuildResponse{
+		Actions: []Action{
+			{BuildData: BuildData{LastBuiltRevision: &Revision{SHA1: "head1111"}}},
+		},
+	}
+
+	if got := extractBuiltSHA(data); got != "head1111" {
+		t.Errorf("extractBuiltSHA() = %q, want head1111", got)
+	}
+}
+
+// Test the commits of /api/json responses as Jenkins returns them
+func TestParseBuildResponse_Fixtures(t *testing.T) {
+	tests := []struct {
+		file     string
+		branch   string
+		wantSHA  string
+		wantBase string
+	}{
+		// Multibranch PR build merged with main: the revision action has the PR head
+		{"pr_build_merge.json", "PR-3934", "9f1e2d3c4b5a69788796a5b4c3d2e1f0a9b8c7d6", "5d2c7b0c1f4e8a9d3b6e2f1a0c9b8d7e6f5a4b3c"},
+		// Branch build without a revision action: lastBuiltRevision is the commit
+		{"branch_build.json", "main", "4a7e1c9b2d8f3e6a5c0b7d1e9f2a8c3b6d4e7f10", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			payload, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data BuildResponse
+			if err := json.Unmarshal(payload, &data); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			build := ParseBuildResponse(data, tt.branch, "test/job/path")
+			if build.BuiltSHA != tt.wantSHA || build.BaseSHA != tt.wantBase {
+				t.Errorf("BuiltSHA, BaseSHA = %q, %q; want %q, %q", build.BuiltSHA, build.BaseSHA, tt.wantSHA, tt.wantBase)
+			}
+		})
+	}
+}
+
+func TestParseBuildResponse_ChangeSets(t *testing.T) {
+	payload := `{
+		"number": 12,
+		"result": "FAILURE",
+		"changeSets": [{"kind": "git", "items": [
+			{"commitId": "abc123", "msg": "Fix login\nmore details", "timestamp": 1699564800000, "author": {"fullName": "Jane Doe"}}
+		]}]
+	}`
+
+	var data BuildResponse
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	build := ParseBuildResponse(data, "PR-1", "test/job/path")
+	if len(build.Changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(build.Changes))
+	}
+	change := build.Changes[0]
+	if change.SHA != "abc123" || change.Author != "Jane Doe" || change.Message != "Fix login" || change.BuildNumber != 12 || change.Timestamp != 1699564800 {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+}
+
+func TestParseBuildResponse_Stages(t *testing.T) {
+	data := BuildResponse{
+		Number: 5,
+		Result: "SUCCESS",
+		Stages: []Stage{
+			{Name: "BUILD:", Status: "SUCCESS", StartTimeMillis: 1000, DurationMillis: 5},
+			{Name: "Run Unit Tests", Status: "SUCCESS", StartTimeMillis: 2000, DurationMillis: 90000},
+		},
+	}
+
+	build := ParseBuildResponse(data, "PR-1", "job")
+
+	if len(build.Stages) != 2 {
+		t.Fatalf("Expected 2 stages, got %d", len(build.Stages))
+	}
+	if build.Stages[1].Name != "Run Unit Tests" || build.Stages[1].DurationMillis != 90000 || build.Stages[1].StartMillis != 2000 {
+		t.Errorf("Unexpected stage %+v", build.Stages[1])
+	}
+}
@@ -0,0 +1,203 @@
+package jenkins
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// blueOceanTimeLayout is the format of Blue Ocean node start times
+const blueOceanTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// StageNode is a stage of the pipeline graph with the stages nested in it
+// Parallel blocks are stages whose children are the parallel branches
+type StageNode struct {
+	Stage    models.Stage
+	Children []*StageNode
+}
+
+// BuildStageGraph turns Blue Ocean nodes (in execution order) into a tree
+// Parallel branches hang off the stage that declares them, stages inside a
+// branch hang off the branch, and sequential stages are siblings
+func BuildStageGraph(nodes []BlueOceanNode) []*StageNode {
+	byID := make(map[string]*StageNode, len(nodes))
+	types := make(map[string]string, len(nodes))
+	parents := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		types[node.ID] = node.Type
+	}
+
+	var roots []*StageNode
+	for _, node := range nodes {
+		parentID := graphParent(node, types, parents)
+		parents[node.ID] = parentID
+
+		stageNode := &StageNode{Stage: nodeStage(node, parentID)}
+		byID[node.ID] = stageNode
+
+		if parent, ok := byID[parentID]; ok {
+			stageNode.Stage.Depth = parent.Stage.Depth + 1
+			parent.Children = append(parent.Children, stageNode)
+		} else {
+			stageNode.Stage.ParentID = ""
+			roots = append(roots, stageNode)
+		}
+	}
+	return roots
+}
+
+// graphParent returns the ID of the node a node is nested in ("" at the top level)
+// FirstParent points to the enclosing stage for parallel branches and for the
+// first stage of a branch, but to the previous stage for sequential stages
+func graphParent(node BlueOceanNode, types, parents map[string]string) string {
+	if node.FirstParent == "" {
+		return ""
+	}
+	if node.Type == "PARALLEL" || types[node.FirstParent] == "PARALLEL" {
+		return node.FirstParent
+	}
+	return parents[node.FirstParent] // Sibling of the previous stage
+}
+
+// nodeStage converts a Blue Ocean node to a model stage with a wfapi status
+func nodeStage(node BlueOceanNode, parentID string) models.Stage {
+	stage := models.Stage{
+		Name:           node.DisplayName,
+		Status:         nodeStatus(node),
+		DurationMillis: node.DurationInMillis,
+		ID:             node.ID,
+		ParentID:       parentID,
+		Parallel:       node.Type == "PARALLEL",
+	}
+	if start, err := time.Parse(blueOceanTimeLayout, node.StartTime); err == nil {
+		stage.StartMillis = start.UnixMilli()
+	}
+	return stage
+}
+
+// nodeStatus maps a Blue Ocean state and result to the wfapi status vocabulary
+// so graph and wfapi stages can be rendered and analyzed the same way
+func nodeStatus(node BlueOceanNode) string {
+	switch node.State {
+	case "RUNNING":
+		return "IN_PROGRESS"
+	case "SKIPPED", "NOT_BUILT":
+		return "NOT_EXECUTED"
+	case "PAUSED":
+		return "PAUSED_PENDING_INPUT"
+	case "QUEUED", "":
+		return "NOT_EXECUTED"
+	}
+
+	switch node.Result {
+	case "FAILURE":
+		return "FAILED"
+	case "NOT_BUILT":
+		return "NOT_EXECUTED"
+	default:
+		return node.Result // SUCCESS, UNSTABLE, ABORTED, UNKNOWN
+	}
+}
+
+// FlattenStageGraph lists the stages of a graph depth-first (parents before children)
+func FlattenStageGraph(roots []*StageNode) []models.Stage {
+	var stages []models.Stage
+	var walk func(nodes []*StageNode)
+	walk = func(nodes []*StageNode) {
+		for _, node := range nodes {
+			stages = append(stages, node.Stage)
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+	return stages
+}
+
+// nested reports whether any stage of the graph has children
+func nested(roots []*StageNode) bool {
+	for _, root := range roots {
+		if len(root.Children) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractGraphStageInfo extracts phase and job information from a stage graph
+// Stage = the running top-level stage (e.g., "Tests")
+// Job = the running stages nested in it (e.g., "unit, integration")
+// Flat graphs have no structure to go by, so they use the phase label heuristic
+func ExtractGraphStageInfo(roots []*StageNode, buildStatus models.BuildStatus) (phase string, jobs string) {
+	if !nested(roots) {
+		return ExtractStageInfo(wfapiStages(FlattenStageGraph(roots)), buildStatus)
+	}
+
+	if buildStatus == models.StatusSuccess {
+		return "Passed", "Passed"
+	}
+	if buildStatus == models.StatusFailure || buildStatus == models.StatusError {
+		return "Failed", "Failed"
+	}
+	if buildStatus == models.StatusAborted {
+		return "Aborted", "Aborted"
+	}
+
+	var label string // Last "LABEL:" seen, for top-level stages without children
+	for _, root := range roots {
+		if strings.HasSuffix(root.Stage.Name, ":") {
+			label = root.Stage.Name
+			continue
+		}
+		if root.Stage.Status != "IN_PROGRESS" {
+			continue
+		}
+
+		if len(root.Children) == 0 {
+			if label != "" {
+				return label, root.Stage.Name
+			}
+			return root.Stage.Name, root.Stage.Name
+		}
+
+		var active []string
+		for _, leaf := range activeLeaves(root.Children) {
+			active = append(active, leaf.Stage.Name)
+		}
+		if len(active) == 0 {
+			return root.Stage.Name, "Starting..."
+		}
+		return root.Stage.Name, strings.Join(active, ", ")
+	}
+	return "Starting", "Starting..."
+}
+
+// activeLeaves returns the innermost running stages under the given nodes
+func activeLeaves(nodes []*StageNode) []*StageNode {
+	var leaves []*StageNode
+	for _, node := range nodes {
+		if node.Stage.Status != "IN_PROGRESS" {
+			continue
+		}
+		if inner := activeLeaves(node.Children); len(inner) > 0 {
+			leaves = append(leaves, inner...)
+		} else {
+			leaves = append(leaves, node)
+		}
+	}
+	return leaves
+}
+
+// wfapiStages converts model stages back to wfapi stages
+func wfapiStages(stages []models.Stage) []Stage {
+	result := make([]Stage, 0, len(stages))
+	for _, stage := range stages {
+		result = append(result, Stage{
+			Name:            stage.Name,
+			Status:          stage.Status,
+			StartTimeMillis: stage.StartMillis,
+			DurationMillis:  stage.DurationMillis,
+		})
+	}
+	return result
+}
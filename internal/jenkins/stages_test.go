@@ -0,0 +1,98 @@
+package jenkins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// parallelPipeline is Build -> Tests (parallel: unit, integration with nested
+// "setup" and "run" stages) -> Deploy, as returned by the Blue Ocean nodes API
+func parallelPipeline(state string) []BlueOceanNode {
+	return []BlueOceanNode{
+		{ID: "3", DisplayName: "Build", Type: "STAGE", State: "FINISHED", Result: "SUCCESS", StartTime: "2025-11-07T10:45:00.000-0800", DurationInMillis: 60000},
+		{ID: "6", DisplayName: "Tests", Type: "STAGE", State: state, Result: "UNKNOWN", FirstParent: "3"},
+		{ID: "9", DisplayName: "unit", Type: "PARALLEL", State: "FINISHED", Result: "SUCCESS", FirstParent: "6"},
+		{ID: "10", DisplayName: "integration", Type: "PARALLEL", State: state, Result: "UNKNOWN", FirstParent: "6"},
+		{ID: "14", DisplayName: "setup", Type: "STAGE", State: "FINISHED", Result: "SUCCESS", FirstParent: "10"},
+		{ID: "20", DisplayName: "run", Type: "STAGE", State: state, Result: "UNKNOWN", FirstParent: "14"},
+		{ID: "30", DisplayName: "Deploy", Type: "STAGE", State: "NOT_BUILT", FirstParent: "6"},
+	}
+}
+
+func TestBuildStageGraph_NestsParallelBranches(t *testing.T) {
+	roots := BuildStageGraph(parallelPipeline("RUNNING"))
+
+	if len(roots) != 3 {
+		t.Fatalf("Expected 3 top-level stages, got %d", len(roots))
+	}
+	tests := roots[1]
+	if tests.Stage.Name != "Tests" || len(tests.Children) != 2 {
+		t.Fatalf("Expected Tests with 2 parallel branches, got %+v", tests)
+	}
+	integration := tests.Children[1]
+	if !integration.Stage.Parallel || integration.Stage.Depth != 1 || len(integration.Children) != 2 {
+		t.Errorf("Expected integration branch with 2 nested stages, got %+v", integration)
+	}
+	if run := integration.Children[1]; run.Stage.ParentID != "10" || run.Stage.Depth != 2 {
+		t.Errorf("Sequential stage in a branch should be nested in the branch, got %+v", run.Stage)
+	}
+	if deploy := roots[2]; deploy.Stage.ParentID != "" || deploy.Stage.Status != "NOT_EXECUTED" {
+		t.Errorf("Stage after a parallel block should be top-level and not executed, got %+v", deploy.Stage)
+	}
+
+	build := roots[0].Stage
+	if build.Status != "SUCCESS" || build.StartMillis != 1762541100000 || build.DurationMillis != 60000 {
+		t.Errorf("Unexpected Build stage %+v", build)
+	}
+
+	var names []string
+	for _, stage := range FlattenStageGraph(roots) {
+		names = append(names, stage.Name)
+	}
+	want := "Build Tests unit integration setup run Deploy"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("FlattenStageGraph() = %q, want %q", got, want)
+	}
+}
+
+func TestNodeStatus(t *testing.T) {
+	tests := []struct {
+		state, result, want string
+	}{
+		{"RUNNING", "UNKNOWN", "IN_PROGRESS"},
+		{"FINISHED", "FAILURE", "FAILED"},
+		{"FINISHED", "UNSTABLE", "UNSTABLE"},
+		{"SKIPPED", "NOT_BUILT", "NOT_EXECUTED"},
+		{"PAUSED", "UNKNOWN", "PAUSED_PENDING_INPUT"},
+	}
+	for _, tt := range tests {
+		if got := nodeStatus(BlueOceanNode{State: tt.state, Result: tt.result}); got != tt.want {
+			t.Errorf("nodeStatus(%s, %s) = %s, want %s", tt.state, tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestExtractGraphStageInfo(t *testing.T) {
+	phase, jobs := ExtractGraphStageInfo(BuildStageGraph(parallelPipeline("RUNNING")), models.StatusRunning)
+	if phase != "Tests" || jobs != "run" {
+		t.Errorf("Expected Tests / run, got %s / %s", phase, jobs)
+	}
+
+	phase, jobs = ExtractGraphStageInfo(BuildStageGraph(parallelPipeline("FINISHED")), models.StatusFailure)
+	if phase != "Failed" || jobs != "Failed" {
+		t.Errorf("Expected Failed / Failed, got %s / %s", phase, jobs)
+	}
+}
+
+func TestExtractGraphStageInfo_FlatGraphUsesPhaseLabels(t *testing.T) {
+	nodes := []BlueOceanNode{
+		{ID: "3", DisplayName: "BUILD:", Type: "STAGE", State: "FINISHED", Result: "SUCCESS"},
+		{ID: "5", DisplayName: "Compile", Type: "STAGE", State: "RUNNING", FirstParent: "3"},
+	}
+	phase, jobs := ExtractGraphStageInfo(BuildStageGraph(nodes), models.StatusRunning)
+	if phase != "BUILD:" || jobs != "Compile" {
+		t.Errorf("Expected BUILD: / Compile, got %s / %s", phase, jobs)
+	}
+}
@@ -0,0 +1,170 @@
+package jenkins
+
+// BuildResponse is the subset of a Jenkins build's /api/json that the dashboard reads
+type BuildResponse struct {
+	Number     int         `json:"number"`
+	Building   bool        `json:"building"`
+	Result     string      `json:"result"`    // Empty while running or queued (JSON null)
+	Duration   int64       `json:"duration"`  // Milliseconds, 0 while running
+	Timestamp  int64       `json:"timestamp"` // Start time in milliseconds since epoch
+	Actions    []Action    `json:"actions"`
+	ChangeSets []ChangeSet `json:"changeSets"`
+
+	// Stages is not part of /api/json - it is merged in from /wfapi/describe
+	Stages []Stage `json:"-"`
+
+	// Nodes is not part of /api/json either - it is merged in from the Blue Ocean
+	// nodes API and, when present, takes precedence over the flat wfapi stages
+	Nodes []BlueOceanNode `json:"-"`
+}
+
+// Action is a single entry of the build's actions array
+// Jenkins mixes many action types in one array, distinguished by _class
+// The embedded structs cover the action shapes the dashboard understands;
+// fields of other action types are simply left empty
+type Action struct {
+	Class string `json:"_class"`
+	BuildData
+	ParametersAction
+	CauseAction
+	SCMRevisionAction
+}
+
+// BuildData is the git plugin's hudson.plugins.git.util.BuildData action
+type BuildData struct {
+	LastBuiltRevision *Revision `json:"lastBuiltRevision,omitempty"`
+	RemoteURLs        []string  `json:"remoteUrls,omitempty"`
+}
+
+// Revision is the commit a build checked out
+type Revision struct {
+	SHA1   string      `json:"SHA1"`
+	Branch []BranchRef `json:"branch"`
+}
+
+// BranchRef is a branch that pointed at a built revision
+type BranchRef struct {
+	SHA1 string `json:"SHA1"`
+	Name string `json:"name"` // e.g., "origin/feature/auth" or "PR-3934"
+}
+
+// SCMRevisionAction is jenkins.scm.api.SCMRevisionAction (multibranch source revision)
+// For PR builds that merge with the target branch, BuildData holds the merge commit,
+// while PullHash here is the PR head commit
+type SCMRevisionAction struct {
+	Revision *SCMRevision `json:"revision,omitempty"`
+}
+
+// SCMRevision is the source revision a multibranch build was created for
+type SCMRevision struct {
+	Hash     string `json:"hash"`     // Branch builds
+	PullHash string `json:"pullHash"` // PR builds: head of the PR
+	BaseHash string `json:"baseHash"` // PR builds: target branch commit
+}
+
+// ParametersAction is hudson.model.ParametersAction (build parameters)
+type ParametersAction struct {
+	Parameters []Parameter `json:"parameters,omitempty"`
+}
+
+// Parameter is a single build parameter
+// Value is untyped because Jenkins parameters may be strings, booleans or numbers
+type Parameter struct {
+	Class string      `json:"_class"`
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// CauseAction is hudson.model.CauseAction (why the build was started)
+type CauseAction struct {
+	Causes []Cause `json:"causes,omitempty"`
+}
+
+// Cause is a single build cause
+// Example: {"_class": "hudson.model.Cause$UserIdCause", "shortDescription": "Started by user Jane", "userId": "jane"}
+type Cause struct {
+	Class            string `json:"_class"`
+	ShortDescription string `json:"shortDescription"`
+	UserID           string `json:"userId,omitempty"`
+	UserName         string `json:"userName,omitempty"`
+	UpstreamProject  string `json:"upstreamProject,omitempty"`
+	UpstreamBuild    int    `json:"upstreamBuild,omitempty"`
+}
+
+// ChangeSet is the list of SCM changes a build picked up since the previous build
+type ChangeSet struct {
+	Kind  string       `json:"kind"` // e.g., "git"
+	Items []ChangeItem `json:"items"`
+}
+
+// ChangeItem is a single commit in a changeset
+type ChangeItem struct {
+	CommitID  string `json:"commitId"`
+	Msg       string `json:"msg"`       // First line of the commit message
+	Timestamp int64  `json:"timestamp"` // Milliseconds since epoch
+	Author    struct {
+		FullName string `json:"fullName"`
+	} `json:"author"`
+}
+
+// WfapiDescribe is the response of a build's /wfapi/describe endpoint
+type WfapiDescribe struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	StartTimeMillis int64   `json:"startTimeMillis"`
+	DurationMillis  int64   `json:"durationMillis"`
+	Stages          []Stage `json:"stages"`
+}
+
+// Stage is a pipeline stage as reported by wfapi
+// Status is one of SUCCESS, FAILED, IN_PROGRESS, NOT_EXECUTED, ABORTED, UNSTABLE, PAUSED_PENDING_INPUT
+type Stage struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	ExecNode            string `json:"execNode"`
+	Status              string `json:"status"`
+	StartTimeMillis     int64  `json:"startTimeMillis"`
+	DurationMillis      int64  `json:"durationMillis"`
+	PauseDurationMillis int64  `json:"pauseDurationMillis"`
+}
+
+// TestReport is the response of a build's /testReport/api/json endpoint
+type TestReport struct {
+	Suites []TestSuite `json:"suites"`
+}
+
+// TestSuite is one suite (usually one test class or file) of a test report
+type TestSuite struct {
+	Cases []TestCase `json:"cases"`
+}
+
+// TestCase is a single test result
+// Status is one of PASSED, FIXED, FAILED, REGRESSION, SKIPPED
+type TestCase struct {
+	ClassName string `json:"className"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+}
+
+// FullName returns the test's fully qualified name (e.g., "com.acme.LoginTest.testExpiry")
+func (tc TestCase) FullName() string {
+	if tc.ClassName == "" {
+		return tc.Name
+	}
+	return tc.ClassName + "." + tc.Name
+}
+
+// BlueOceanNode is one entry of a Blue Ocean run's /nodes/ response
+// Unlike wfapi, nodes keep the pipeline structure: parallel branches have type
+// PARALLEL and point to their enclosing stage through FirstParent
+type BlueOceanNode struct {
+	ID               string `json:"id"`
+	DisplayName      string `json:"displayName"`
+	Type             string `json:"type"`      // STAGE or PARALLEL
+	State            string `json:"state"`     // FINISHED, RUNNING, QUEUED, PAUSED, SKIPPED, NOT_BUILT
+	Result           string `json:"result"`    // SUCCESS, FAILURE, UNSTABLE, ABORTED, NOT_BUILT, UNKNOWN
+	StartTime        string `json:"startTime"` // e.g., "2025-11-07T10:45:00.000-0800", empty if not started
+	DurationInMillis int64  `json:"durationInMillis"`
+	FirstParent      string `json:"firstParent"` // Enclosing stage (branches) or previous stage, "" for the first
+}
@@ -2,9 +2,12 @@ package jenkins
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
 )
 
 // Default configuration (can be overridden with environment variables)
@@ -44,6 +47,15 @@ func InferJobPath(prNumber string) string {
 	return defaultJobPath
 }
 
+// JobPathOf returns the job path a build was fetched from, falling back to the
+// inferred one for tiles not fetched yet
+func JobPathOf(build models.Build) string {
+	if build.JobPath != "" {
+		return build.JobPath
+	}
+	return InferJobPath(build.PRNumber)
+}
+
 // BuildPRURL constructs the GitHub PR URL for the given PR number
 func BuildPRURL(prNumber string) string {
 	// Intuit GitHub URL
@@ -60,6 +72,11 @@ func BuildJenkinsURL(jobPath, branch string, buildNumber int) string {
 	return fmt.Sprintf("%s/%s/job/%s/%s", jenkinsBaseURL, jobPath, branch, buildRef)
 }
 
+// BuildJobURL constructs the Jenkins URL of a branch job (without a build number)
+func BuildJobURL(jobPath, branch string) string {
+	return fmt.Sprintf("%s/%s/job/%s", jenkinsBaseURL, jobPath, branch)
+}
+
 // BuildBlueOceanBuildURL constructs the Blue Ocean pipeline view URL for a specific build
 func BuildBlueOceanBuildURL(jobPath, branch string, buildNumber int) string {
 	// Blue Ocean format: https://build.intuit.com/{first-segment}/blue/organizations/jenkins/{rest-of-path}/detail/{branch}/{build}/pipeline
@@ -85,6 +102,20 @@ func BuildBlueOceanBuildURL(jobPath, branch string, buildNumber int) string {
 		jenkinsBaseURL, firstSegment, restOfPath, branch, buildRef)
 }
 
+// BuildBlueOceanNodesURL constructs the Blue Ocean REST URL of a run's stage nodes
+// Example: identity/job/identity-manage/job/account/job/account-eks, PR-3934, 8
+// Becomes: https://build.intuit.com/identity/blue/rest/organizations/jenkins/pipelines/identity-manage/pipelines/account/pipelines/account-eks/branches/PR-3934/runs/8/nodes/
+func BuildBlueOceanNodesURL(jobPath, branch string, buildNumber int) string {
+	parts := strings.Split(jobPath, "/job/")
+	if len(parts) < 2 {
+		return fmt.Sprintf("%s/blue/rest/organizations/jenkins/pipelines/%s/branches/%s/runs/%d/nodes/",
+			jenkinsBaseURL, jobPath, url.PathEscape(branch), buildNumber)
+	}
+
+	return fmt.Sprintf("%s/%s/blue/rest/organizations/jenkins/pipelines/%s/branches/%s/runs/%d/nodes/",
+		jenkinsBaseURL, parts[0], strings.Join(parts[1:], "/pipelines/"), url.PathEscape(branch), buildNumber)
+}
+
 // ParsePRNumber parses and validates a PR number from user input
 // Removes "PR-" prefix if present, trims whitespace, and validates it's numeric
 func ParsePRNumber(input string) (string, error) {
@@ -95,6 +95,14 @@ func TestBuildJenkinsURL(t *testing.T) {
 	}
 }
 
+func TestBuildBlueOceanNodesURL(t *testing.T) {
+	got := BuildBlueOceanNodesURL("identity/job/identity-manage/job/account/job/account-eks", "PR-3934", 8)
+	want := "https://build.intuit.com/identity/blue/rest/organizations/jenkins/pipelines/identity-manage/pipelines/account/pipelines/account-eks/branches/PR-3934/runs/8/nodes/"
+	if got != want {
+		t.Errorf("BuildBlueOceanNodesURL() = %v, want %v", got, want)
+	}
+}
+
 func TestParsePRNumber(t *testing.T) {
 	tests := []struct {
 		name      string
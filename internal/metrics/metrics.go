@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Fetch backends
+const (
+	BackendJenkins = "jenkins"
+	BackendGitHub  = "github"
+)
+
+// Histogram buckets, in seconds
+var (
+	buildDurationBuckets = []float64{60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 5400, 7200}
+	fetchDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// statuses lists every build status, so the status gauge has a series per status
+var statuses = []models.BuildStatus{
+	models.StatusPending,
+	models.StatusRunning,
+	models.StatusSuccess,
+	models.StatusFailure,
+	models.StatusError,
+	models.StatusAborted,
+}
+
+// histogram is a cumulative histogram with fixed buckets
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // Per bucket, not cumulative
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// errorKey identifies a fetch error series
+type errorKey struct {
+	backend string
+	code    string // HTTP status code, or "network" if there was no response
+}
+
+// Registry collects fetch and build metrics and writes them in the Prometheus
+// text format. A nil *Registry records nothing
+type Registry struct {
+	mu             sync.Mutex
+	fetchDurations map[string]*histogram // Backend -> request latency
+	fetchErrors    map[errorKey]uint64
+	buildDurations map[string]*histogram // Result -> duration of finished builds
+	rateRemaining  float64               // GitHub X-RateLimit-Remaining, -1 until seen
+	rateLimit      float64               // GitHub X-RateLimit-Limit, -1 until seen
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return &Registry{
+		fetchDurations: make(map[string]*histogram),
+		fetchErrors:    make(map[errorKey]uint64),
+		buildDurations: make(map[string]*histogram),
+		rateRemaining:  -1,
+		rateLimit:      -1,
+	}
+}
+
+// ObserveFetch records the latency of one request to a backend
+// A statusCode of 0 means the request failed without a response; 4xx and 5xx
+// responses are counted as errors too
+func (r *Registry) ObserveFetch(backend string, duration time.Duration, statusCode int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.fetchDurations[backend]
+	if !ok {
+		h = newHistogram(fetchDurationBuckets)
+		r.fetchDurations[backend] = h
+	}
+	h.observe(duration.Seconds())
+
+	switch {
+	case statusCode == 0:
+		r.fetchErrors[errorKey{backend, "network"}]++
+	case statusCode >= 400:
+		r.fetchErrors[errorKey{backend, strconv.Itoa(statusCode)}]++
+	}
+}
+
+// SetRateLimit records GitHub's remaining and total API requests
+func (r *Registry) SetRateLimit(remaining, limit float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rateRemaining = remaining
+	r.rateLimit = limit
+}
+
+// ObserveBuild records the duration of a build that finished between two polls
+// Like hook scripts, a finished build counts if it was seen running or is newer
+// than the build before; the first fetch of a PR is not counted
+func (r *Registry) ObserveBuild(previous, current models.Build) {
+	if r == nil || !(current.IsSuccess() || current.IsFailure()) {
+		return
+	}
+	newBuild := current.BuildNumber != previous.BuildNumber
+	if !previous.IsRunning() && !(newBuild && previous.BuildNumber != 0) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := current.Status.String()
+	h, ok := r.buildDurations[result]
+	if !ok {
+		h = newHistogram(buildDurationBuckets)
+		r.buildDurations[result] = h
+	}
+	h.observe(float64(current.DurationSeconds))
+}
+
+// Handler serves the metrics, with per-PR gauges for the builds returned by builds
+func (r *Registry) Handler(builds func() []models.Build) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w, builds())
+	})
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer, builds []models.Build) error {
+	var b strings.Builder
+	writeBuildGauges(&b, builds)
+
+	if r != nil {
+		r.mu.Lock()
+		writeHistograms(&b, "jenkins_dash_build_duration_seconds", "Duration of builds seen finishing, by result.", "result", r.buildDurations)
+		writeHistograms(&b, "jenkins_dash_fetch_duration_seconds", "Latency of requests to Jenkins and GitHub.", "backend", r.fetchDurations)
+
+		header(&b, "jenkins_dash_fetch_errors_total", "Failed requests to Jenkins and GitHub, by HTTP status code (network = no response).", "counter")
+		keys := make([]errorKey, 0, len(r.fetchErrors))
+		for key := range r.fetchErrors {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].backend != keys[j].backend {
+				return keys[i].backend < keys[j].backend
+			}
+			return keys[i].code < keys[j].code
+		})
+		for _, key := range keys {
+			sample(&b, "jenkins_dash_fetch_errors_total", labels("backend", key.backend, "code", key.code), float64(r.fetchErrors[key]))
+		}
+
+		if r.rateRemaining >= 0 {
+			header(&b, "jenkins_dash_github_rate_limit_remaining", "GitHub API requests left in the current rate-limit window.", "gauge")
+			sample(&b, "jenkins_dash_github_rate_limit_remaining", "", r.rateRemaining)
+		}
+		if r.rateLimit >= 0 {
+			header(&b, "jenkins_dash_github_rate_limit", "GitHub API requests allowed per rate-limit window.", "gauge")
+			sample(&b, "jenkins_dash_github_rate_limit", "", r.rateLimit)
+		}
+		r.mu.Unlock()
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeBuildGauges writes the current state of every tracked PR
+func writeBuildGauges(b *strings.Builder, builds []models.Build) {
+	header(b, "jenkins_dash_tracked_prs", "Number of PRs on the dashboard.", "gauge")
+	sample(b, "jenkins_dash_tracked_prs", "", float64(len(builds)))
+
+	header(b, "jenkins_dash_build_status", "Status of each PR's latest build (1 for the current status).", "gauge")
+	for _, build := range builds {
+		for _, status := range statuses {
+			value := 0.0
+			if build.Status == status {
+				value = 1
+			}
+			sample(b, "jenkins_dash_build_status", labels("pr", build.PRNumber, "status", status.String()), value)
+		}
+	}
+
+	header(b, "jenkins_dash_build_number", "Number of each PR's latest build.", "gauge")
+	for _, build := range builds {
+		sample(b, "jenkins_dash_build_number", labels("pr", build.PRNumber), float64(build.BuildNumber))
+	}
+
+	header(b, "jenkins_dash_build_start_timestamp_seconds", "Start time of each PR's latest build.", "gauge")
+	for _, build := range builds {
+		if build.Timestamp > 0 {
+			sample(b, "jenkins_dash_build_start_timestamp_seconds", labels("pr", build.PRNumber), float64(build.Timestamp))
+		}
+	}
+
+	header(b, "jenkins_dash_build_elapsed_seconds", "Duration of each PR's latest build, so far if still running.", "gauge")
+	for _, build := range builds {
+		if build.BuildNumber > 0 {
+			sample(b, "jenkins_dash_build_elapsed_seconds", labels("pr", build.PRNumber), float64(build.GetCurrentDuration()))
+		}
+	}
+
+	header(b, "jenkins_dash_stage_duration_seconds", "Duration of each stage of each PR's latest build.", "gauge")
+	for _, build := range builds {
+		seen := make(map[string]bool) // Nested stages may share a name - one series each
+		for _, stage := range build.Stages {
+			if seen[stage.Name] {
+				continue
+			}
+			seen[stage.Name] = true
+			sample(b, "jenkins_dash_stage_duration_seconds", labels("pr", build.PRNumber, "stage", stage.Name), float64(stage.DurationMillis)/1000)
+		}
+	}
+}
+
+// writeHistograms writes one histogram per label value, sorted by label value
+func writeHistograms(b *strings.Builder, name, help, label string, histograms map[string]*histogram) {
+	header(b, name, help, "histogram")
+	values := make([]string, 0, len(histograms))
+	for value := range histograms {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	for _, value := range values {
+		h := histograms[value]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += h.counts[i]
+			sample(b, name+"_bucket", labels(label, value, "le", formatFloat(bound)), float64(cumulative))
+		}
+		sample(b, name+"_bucket", labels(label, value, "le", "+Inf"), float64(h.count))
+		sample(b, name+"_sum", labels(label, value), h.sum)
+		sample(b, name+"_count", labels(label, value), float64(h.count))
+	}
+}
+
+// header writes the HELP and TYPE lines of a metric
+func header(b *strings.Builder, name, help, kind string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample line
+func sample(b *strings.Builder, name, labels string, value float64) {
+	fmt.Fprintf(b, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// labels formats name/value pairs as {name="value",...}
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+escapeLabel(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escapeLabel escapes a label value (backslash, double quote and newline)
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a sample value or bucket bound
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/httpx"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// render returns the registry's output for the builds
+func render(t *testing.T, r *Registry, builds []models.Build) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.Write(&b, builds); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return b.String()
+}
+
+func TestWrite_BuildGauges(t *testing.T) {
+	builds := []models.Build{
+		{
+			PRNumber:        "3934",
+			BuildNumber:     8,
+			Status:          models.StatusFailure,
+			Timestamp:       1762541100,
+			DurationSeconds: 125,
+			Stages: []models.Stage{
+				{Name: "Build", DurationMillis: 61500},
+				{Name: `Deploy "eu"`, DurationMillis: 2000},
+				{Name: "Build", DurationMillis: 1000, Depth: 1}, // Same name nested - skipped
+			},
+		},
+		{PRNumber: "4001", Status: models.StatusPending},
+	}
+	out := render(t, nil, builds)
+
+	for _, want := range []string{
+		"# TYPE jenkins_dash_tracked_prs gauge\njenkins_dash_tracked_prs 2\n",
+		`jenkins_dash_build_status{pr="3934",status="failure"} 1`,
+		`jenkins_dash_build_status{pr="3934",status="running"} 0`,
+		`jenkins_dash_build_status{pr="4001",status="pending"} 1`,
+		`jenkins_dash_build_number{pr="3934"} 8`,
+		`jenkins_dash_build_start_timestamp_seconds{pr="3934"} 1.7625411e+09`,
+		`jenkins_dash_build_elapsed_seconds{pr="3934"} 125`,
+		`jenkins_dash_stage_duration_seconds{pr="3934",stage="Build"} 61.5`,
+		`jenkins_dash_stage_duration_seconds{pr="3934",stage="Deploy \"eu\""} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, `stage="Build"`) != 1 {
+		t.Errorf("Expected one series per stage name:\n%s", out)
+	}
+	if strings.Contains(out, `jenkins_dash_build_elapsed_seconds{pr="4001"}`) {
+		t.Error("A PR without a build should have no elapsed time")
+	}
+}
+
+func TestObserveBuild(t *testing.T) {
+	r := New()
+	running := models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning}
+
+	r.ObserveBuild(models.Build{}, models.Build{BuildNumber: 7, Status: models.StatusSuccess, DurationSeconds: 90}) // First fetch
+	r.ObserveBuild(running, running)
+	r.ObserveBuild(running, models.Build{BuildNumber: 8, Status: models.StatusSuccess, DurationSeconds: 400})
+	r.ObserveBuild(models.Build{BuildNumber: 8, Status: models.StatusSuccess}, models.Build{BuildNumber: 9, Status: models.StatusFailure, DurationSeconds: 50})
+	r.ObserveBuild(models.Build{BuildNumber: 9, Status: models.StatusFailure}, models.Build{BuildNumber: 9, Status: models.StatusFailure}) // Unchanged
+
+	out := render(t, r, nil)
+	for _, want := range []string{
+		`jenkins_dash_build_duration_seconds_bucket{result="success",le="300"} 0`,
+		`jenkins_dash_build_duration_seconds_bucket{result="success",le="600"} 1`,
+		`jenkins_dash_build_duration_seconds_bucket{result="success",le="+Inf"} 1`,
+		`jenkins_dash_build_duration_seconds_sum{result="success"} 400`,
+		`jenkins_dash_build_duration_seconds_count{result="failure"} 1`,
+		`jenkins_dash_build_duration_seconds_bucket{result="failure",le="60"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pulls/1":
+			w.Header().Set("X-RateLimit-Remaining", "4990")
+			w.Header().Set("X-RateLimit-Limit", "5000")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := New()
+	github := &http.Client{Transport: r.Transport(BackendGitHub, nil)}
+	jenkins := &http.Client{Transport: r.Transport(BackendJenkins, nil), Timeout: time.Second}
+
+	for _, url := range []string{server.URL + "/pulls/1", server.URL + "/missing"} {
+		resp, err := github.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := jenkins.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatal("Expected a connection error")
+	}
+
+	// An expected 404 from a best-effort endpoint is timed but not an error
+	req, _ := http.NewRequestWithContext(httpx.ExpectStatus(context.Background(), http.StatusNotFound), "GET", server.URL+"/missing", nil)
+	resp, err := jenkins.Do(req)
+	if err != nil {
+		t.Fatalf("GET /missing: %v", err)
+	}
+	resp.Body.Close()
+
+	out := render(t, r, nil)
+	for _, want := range []string{
+		`jenkins_dash_fetch_duration_seconds_count{backend="github"} 2`,
+		`jenkins_dash_fetch_duration_seconds_count{backend="jenkins"} 2`,
+		`jenkins_dash_fetch_errors_total{backend="github",code="404"} 1`,
+		`jenkins_dash_fetch_errors_total{backend="jenkins",code="network"} 1`,
+		"jenkins_dash_github_rate_limit_remaining 4990\n",
+		"jenkins_dash_github_rate_limit 5000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `backend="jenkins",code="404"`) {
+		t.Errorf("Expected 404 should not be counted as an error:\n%s", out)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/httpx"
+)
+
+// transport records the latency and errors of requests to one backend
+type transport struct {
+	backend  string
+	base     http.RoundTripper
+	registry *Registry
+}
+
+// Transport wraps base (nil = http.DefaultTransport) to record every request to
+// backend, and GitHub's rate-limit headers from any response that has them
+func (r *Registry) Transport(backend string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{backend: backend, base: base, registry: r}
+}
+
+// RoundTrip sends the request and records its outcome
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.registry.ObserveFetch(t.backend, time.Since(start), 0)
+		return nil, err
+	}
+	statusCode := resp.StatusCode
+	if httpx.Expected(req, statusCode) {
+		statusCode = http.StatusOK // An answer, not an error (see httpx.ExpectStatus)
+	}
+	t.registry.ObserveFetch(t.backend, time.Since(start), statusCode)
+
+	remaining, errRemaining := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Remaining"), 64)
+	limit, errLimit := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Limit"), 64)
+	if errRemaining == nil && errLimit == nil {
+		t.registry.SetRateLimit(remaining, limit)
+	}
+	return resp, nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,11 +15,72 @@ const (
 	StatusSuccess
 	StatusFailure
 	StatusError
+	StatusAborted // Stopped before it finished (e.g., by a user or a newer build)
 )
 
 // String returns the string representation of the BuildStatus
 func (s BuildStatus) String() string {
-	return [...]string{"pending", "running", "success", "failure", "error"}[s]
+	return [...]string{"pending", "running", "success", "failure", "error", "aborted"}[s]
+}
+
+// ErrorKind classifies why fetching a build failed
+type ErrorKind int
+
+const (
+	ErrorNone      ErrorKind = iota
+	ErrorUnknown             // Unclassified failure
+	ErrorAuth                // 401 - bad or missing credentials
+	ErrorForbidden           // 403 - credentials lack access to the job
+	ErrorNotFound            // 404 - PR has no build yet
+	ErrorServer              // 5xx or 429 - Jenkins is struggling
+	ErrorTimeout             // Request timed out
+	ErrorOffline             // DNS failure or connection refused
+)
+
+// String returns the string representation of the ErrorKind
+func (k ErrorKind) String() string {
+	return [...]string{"none", "unknown", "auth", "forbidden", "not_found", "server", "timeout", "offline"}[k]
+}
+
+// Transient returns true if retrying the request may succeed
+func (k ErrorKind) Transient() bool {
+	return k == ErrorServer || k == ErrorTimeout || k == ErrorOffline
+}
+
+// BuildCause describes why Jenkins started a build
+type BuildCause struct {
+	Type        string // Cause type from Jenkins' _class (e.g., "UserIdCause", "BranchIndexingCause")
+	Description string // Jenkins' short description (e.g., "Started by user Jane Doe")
+	UserID      string // Set for user-triggered builds
+	UserName    string // Set for user-triggered builds
+}
+
+// Commit is a single commit from a build's changeset or a PR's commit list
+type Commit struct {
+	SHA         string
+	Author      string
+	Message     string // First line of the commit message
+	Timestamp   int64  // Unix seconds, 0 if unknown
+	BuildNumber int    // Build that first included the commit (0 if unknown or not built)
+}
+
+// Stage is a pipeline stage of a build (from the Blue Ocean stage graph, or wfapi)
+// wfapi stages are flat; graph stages also carry their place in the pipeline
+type Stage struct {
+	Name           string
+	Status         string // SUCCESS, FAILED, IN_PROGRESS, NOT_EXECUTED, ABORTED, UNSTABLE, ...
+	StartMillis    int64  // Start time in milliseconds since epoch
+	DurationMillis int64  // Elapsed time so far for running stages
+	ID             string `json:",omitempty"` // Node ID, empty for wfapi stages
+	ParentID       string `json:",omitempty"` // Enclosing stage or parallel branch, empty at the top level
+	Depth          int    `json:",omitempty"` // Nesting level, 0 at the top level
+	Parallel       bool   `json:",omitempty"` // A branch of a parallel block
+}
+
+// Artifact is a file archived by a Jenkins build
+type Artifact struct {
+	FileName     string // e.g., "report.html"
+	RelativePath string // Path under the build's /artifact/ URL, e.g., "target/reports/report.html"
 }
 
 // Build represents a Jenkins build for a PR
@@ -38,6 +100,25 @@ type Build struct {
 	DurationSeconds int
 	Timestamp       int64
 	ErrorMessage    string
+	ErrorKind       ErrorKind         // Classification of ErrorMessage (ErrorNone when fetch succeeded)
+	BuiltSHA        string            // PR head (or branch head) Jenkins built; "" if only a merge commit is known
+	BaseSHA         string            // Target branch commit a PR build was merged with; "" for branch builds or if unknown
+	PRHeadSHA       string            // Current head commit of the PR on GitHub
+	NewerQueued     bool              // A newer build of the PR is waiting in the Jenkins queue
+	Stages          []Stage           // Pipeline stages (empty if wfapi wasn't available)
+	Causes          []BuildCause      // Why the build was started
+	Parameters      map[string]string // Build parameters by name
+	Changes         []Commit          // Commits new in this build (Jenkins changeSets)
+	PRCommits       []Commit          // All commits of the PR on GitHub, oldest first
+	LastGreenBuild  int               // Most recent successful build number seen (0 if unknown)
+	SinceGreen      []Commit          // Commits of builds after LastGreenBuild, when not green
+	FailureCategory string            // Root-cause category from the console log (e.g., "Out of memory")
+	FailureLine     string            // Console log line that matched the category
+	TestsChecked    bool              // Test results were scanned for flaky tests
+	FailedTests     []string          // Tests that failed in this build (from the Jenkins test report)
+	FlakyTests      []string          // Subset of FailedTests that are known to be flaky
+	SlowStages      []string          // Stages taking much longer than their historical baseline
+	SlowRatios      map[string]float64 // SlowStages -> duration / baseline median when the build was flagged
 }
 
 // IsRunning returns true if the build is currently running
@@ -66,8 +147,11 @@ func (b Build) GetCurrentDuration() int {
 
 // FormatDuration returns a human-readable duration string
 func (b Build) FormatDuration() string {
-	duration := b.GetCurrentDuration()
-	
+	return FormatSeconds(b.GetCurrentDuration())
+}
+
+// FormatSeconds formats a duration in seconds as "1h 2m 3s", "2m 3s" or "3s"
+func FormatSeconds(duration int) string {
 	if duration == 0 {
 		return "0s"
 	}
@@ -121,3 +205,105 @@ func (b Build) FormatCompletedTime() string {
 	
 	return fmt.Sprintf("%d/%d %d:%02d%s", month, day, displayHour, minute, ampm)
 }
+
+// Trigger returns a short description of why the build started, for display on the tile
+// Examples: "by jdoe", "branch indexing", "Pull request #3934 updated", "Replayed #5"
+// Returns "" if Jenkins reported no causes
+func (b Build) Trigger() string {
+	if len(b.Causes) == 0 {
+		return ""
+	}
+
+	// The first cause is the one Jenkins shows on the build page
+	cause := b.Causes[0]
+	switch cause.Type {
+	case "UserIdCause":
+		if cause.UserID != "" {
+			return "by " + cause.UserID
+		}
+		if cause.UserName != "" {
+			return "by " + cause.UserName
+		}
+		return "by user"
+	case "BranchIndexingCause":
+		return "branch indexing"
+	case "TimerTriggerCause":
+		return "timer"
+	case "SCMTriggerCause":
+		return "SCM poll"
+	}
+
+	if cause.Description != "" {
+		return cause.Description
+	}
+	return cause.Type
+}
+
+// TriggeredBy returns the user who started the build, or "" if it wasn't started by a user
+func (b Build) TriggeredBy() string {
+	for _, cause := range b.Causes {
+		if cause.UserID != "" {
+			return cause.UserID
+		}
+		if cause.UserName != "" {
+			return cause.UserName
+		}
+	}
+	return ""
+}
+
+// IsReindex returns true if the build was started by branch indexing rather than a new push
+func (b Build) IsReindex() bool {
+	return len(b.Causes) > 0 && b.Causes[0].Type == "BranchIndexingCause"
+}
+
+// IsStale returns true if the PR has commits newer than the one Jenkins last built
+// Returns false when either SHA is unknown
+func (b Build) IsStale() bool {
+	if b.BuiltSHA == "" || b.PRHeadSHA == "" {
+		return false
+	}
+	return !strings.EqualFold(b.BuiltSHA, b.PRHeadSHA)
+}
+
+// ShortSHA abbreviates a commit SHA to 7 characters
+func ShortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// TrackSinceGreen carries the "commits since last green build" history over from
+// the previous observation of the same PR, so a red build can show what changed
+// since the last green run even when several red builds happened in between
+// SinceGreen and LastGreenBuild already set on b are the builds between prev and
+// b that the fetch backfilled (see fetch.Build), and are merged in
+func (b *Build) TrackSinceGreen(prev Build) {
+	if b.IsSuccess() {
+		b.LastGreenBuild = b.BuildNumber
+		b.SinceGreen = nil
+		return
+	}
+
+	backfilled := b.SinceGreen
+	b.LastGreenBuild = max(prev.LastGreenBuild, b.LastGreenBuild)
+	if prev.IsSuccess() && prev.BuildNumber < b.BuildNumber {
+		b.LastGreenBuild = max(b.LastGreenBuild, prev.BuildNumber)
+	}
+
+	// Drop this build's and the backfilled builds' commits from the carried history
+	// (they may be refreshes), and those of builds up to the last green one, then re-add them
+	refetched := map[int]bool{b.BuildNumber: true}
+	for _, commit := range backfilled {
+		refetched[commit.BuildNumber] = true
+	}
+	var since []Commit
+	for _, commit := range prev.SinceGreen {
+		if !refetched[commit.BuildNumber] && commit.BuildNumber > b.LastGreenBuild {
+			since = append(since, commit)
+		}
+	}
+	since = append(since, backfilled...)
+	b.SinceGreen = append(since, b.Changes...)
+}
@@ -72,3 +72,119 @@ func TestBuild_HasRepositoryField(t *testing.T) {
 		t.Errorf("Expected Repository to be 'identity-manage/account', got '%s'", build.Repository)
 	}
 }
+
+func TestBuild_Trigger(t *testing.T) {
+	tests := []struct {
+		name        string
+		causes      []BuildCause
+		want        string
+		wantUser    string
+		wantReindex bool
+	}{
+		{
+			name: "no causes",
+			want: "",
+		},
+		{
+			name:     "started by user",
+			causes:   []BuildCause{{Type: "UserIdCause", Description: "Started by user Jane Doe", UserID: "jdoe", UserName: "Jane Doe"}},
+			want:     "by jdoe",
+			wantUser: "jdoe",
+		},
+		{
+			name:        "branch indexing",
+			causes:      []BuildCause{{Type: "BranchIndexingCause", Description: "Branch indexing"}},
+			want:        "branch indexing",
+			wantReindex: true,
+		},
+		{
+			name:   "pull request event uses Jenkins description",
+			causes: []BuildCause{{Type: "BranchEventCause", Description: "Pull request #3934 updated"}},
+			want:   "Pull request #3934 updated",
+		},
+		{
+			name: "replay keeps the replaying user",
+			causes: []BuildCause{
+				{Type: "ReplayCause", Description: "Replayed #5"},
+				{Type: "UserIdCause", Description: "Started by user Jane Doe", UserID: "jdoe"},
+			},
+			want:     "Replayed #5",
+			wantUser: "jdoe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := Build{Causes: tt.causes}
+			if got := build.Trigger(); got != tt.want {
+				t.Errorf("Trigger() = %q, want %q", got, tt.want)
+			}
+			if got := build.TriggeredBy(); got != tt.wantUser {
+				t.Errorf("TriggeredBy() = %q, want %q", got, tt.wantUser)
+			}
+			if got := build.IsReindex(); got != tt.wantReindex {
+				t.Errorf("IsReindex() = %v, want %v", got, tt.wantReindex)
+			}
+		})
+	}
+}
+
+func TestBuild_IsStale(t *testing.T) {
+	tests := []struct {
+		name     string
+		builtSHA string
+		headSHA  string
+		want     bool
+	}{
+		{"same commit", "abc1234def", "abc1234def", false},
+		{"same commit different case", "ABC1234DEF", "abc1234def", false},
+		{"PR has newer commit", "abc1234def", "fff9999aaa", true},
+		{"built SHA unknown", "", "fff9999aaa", false},
+		{"head SHA unknown", "abc1234def", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			build := Build{BuiltSHA: tt.builtSHA, PRHeadSHA: tt.headSHA}
+			if got := build.IsStale(); got != tt.want {
+				t.Errorf("IsStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_TrackSinceGreen(t *testing.T) {
+	green := Build{BuildNumber: 10, Status: StatusSuccess}
+
+	// First red build after green: its changes are the suspects
+	red1 := Build{BuildNumber: 11, Status: StatusFailure, Changes: []Commit{{SHA: "aaa", BuildNumber: 11}}}
+	red1.TrackSinceGreen(green)
+	if red1.LastGreenBuild != 10 {
+		t.Errorf("LastGreenBuild = %d, want 10", red1.LastGreenBuild)
+	}
+	if len(red1.SinceGreen) != 1 || red1.SinceGreen[0].SHA != "aaa" {
+		t.Errorf("SinceGreen = %+v, want [aaa]", red1.SinceGreen)
+	}
+
+	// Refreshing the same red build doesn't duplicate its commits
+	refresh := red1
+	refresh.SinceGreen = nil
+	refresh.TrackSinceGreen(red1)
+	if len(refresh.SinceGreen) != 1 {
+		t.Errorf("Refresh should not duplicate commits, got %+v", refresh.SinceGreen)
+	}
+
+	// Second red build accumulates
+	red2 := Build{BuildNumber: 12, Status: StatusFailure, Changes: []Commit{{SHA: "bbb", BuildNumber: 12}}}
+	red2.TrackSinceGreen(red1)
+	if red2.LastGreenBuild != 10 || len(red2.SinceGreen) != 2 {
+		t.Errorf("Expected 2 commits since green #10, got #%d %+v", red2.LastGreenBuild, red2.SinceGreen)
+	}
+
+	// Going green resets
+	green2 := Build{BuildNumber: 13, Status: StatusSuccess}
+	green2.TrackSinceGreen(red2)
+	if green2.LastGreenBuild != 13 || len(green2.SinceGreen) != 0 {
+		t.Errorf("Green build should reset history, got #%d %+v", green2.LastGreenBuild, green2.SinceGreen)
+	}
+}
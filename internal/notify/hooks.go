@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Hook events
+const (
+	HookStart         = "on_start"          // A new build started running
+	HookSuccess       = "on_success"        // A build passed
+	HookFailure       = "on_failure"        // A build failed
+	HookInputRequired = "on_input_required" // A stage is waiting for input (e.g., a deploy approval)
+)
+
+// HookEvents lists every hook event, in the order they are checked
+var HookEvents = []string{HookStart, HookSuccess, HookFailure, HookInputRequired}
+
+// defaultHookTimeout bounds how long a hook script may run
+const defaultHookTimeout = time.Minute
+
+// Hooks runs user-defined shell commands on build events
+type Hooks struct {
+	commands map[string]string // Event -> shell command
+	timeout  time.Duration
+}
+
+// NewHooks creates hooks from shell commands by event
+func NewHooks(commands map[string]string, timeout time.Duration) *Hooks {
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	return &Hooks{commands: commands, timeout: timeout}
+}
+
+// LoadHooks returns the hooks configured in environment variables
+// HOOK_ON_START, HOOK_ON_SUCCESS, HOOK_ON_FAILURE and HOOK_ON_INPUT_REQUIRED hold
+// shell commands; HOOK_TIMEOUT is a Go duration (default 1m)
+func LoadHooks() *Hooks {
+	commands := make(map[string]string)
+	for _, event := range HookEvents {
+		if command := os.Getenv("HOOK_" + strings.ToUpper(event)); command != "" {
+			commands[event] = command
+		}
+	}
+
+	var timeout time.Duration
+	if value := os.Getenv("HOOK_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			timeout = d
+		}
+	}
+	return NewHooks(commands, timeout)
+}
+
+// Has reports whether a command is configured for the event
+func (h *Hooks) Has(event string) bool {
+	return h != nil && h.commands[event] != ""
+}
+
+// Events returns the hook events a build triggered between two polls
+func Events(previous, current models.Build) []string {
+	newBuild := current.BuildNumber != previous.BuildNumber
+	var events []string
+
+	if current.IsRunning() && (newBuild || !previous.IsRunning()) {
+		events = append(events, HookStart)
+	}
+
+	// A finished build is announced if it was seen running, or if it's a newer
+	// build than the one before (it started and finished between polls). The
+	// first fetch of a newly added PR has no earlier build and isn't announced
+	finished := previous.IsRunning() || (newBuild && previous.BuildNumber != 0)
+	if finished && current.IsSuccess() {
+		events = append(events, HookSuccess)
+	}
+	if finished && current.IsFailure() {
+		events = append(events, HookFailure)
+	}
+
+	if awaitingInput(current) && (newBuild || !awaitingInput(previous)) {
+		events = append(events, HookInputRequired)
+	}
+	return events
+}
+
+// awaitingInput reports whether a stage of the build is paused for input
+func awaitingInput(build models.Build) bool {
+	if !build.IsRunning() {
+		return false
+	}
+	for _, stage := range build.Stages {
+		if stage.Status == "PAUSED_PENDING_INPUT" {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the event's command with the build as JSON on stdin and as
+// HOOK_* environment variables
+func (h *Hooks) Run(event string, previous, current models.Build) error {
+	if !h.Has(event) {
+		return nil
+	}
+
+	payload, err := json.Marshal(NewHookPayload(event, previous, current))
+	if err != nil {
+		return err
+	}
+	if err := runShell(h.commands[event], HookEnv(event, previous, current), bytes.NewReader(payload), h.timeout); err != nil {
+		return fmt.Errorf("%s hook: %w", event, err)
+	}
+	return nil
+}
+
+// HookEnv returns the build as HOOK_* environment variables
+func HookEnv(event string, previous, current models.Build) []string {
+	return []string{
+		"HOOK_EVENT=" + event,
+		"HOOK_PR=" + current.PRNumber,
+		"HOOK_BUILD=" + strconv.Itoa(current.BuildNumber),
+		"HOOK_STATUS=" + current.Status.String(),
+		"HOOK_PREVIOUS_STATUS=" + previous.Status.String(),
+		"HOOK_BRANCH=" + current.GitBranch,
+		"HOOK_AUTHOR=" + current.PRAuthor,
+		"HOOK_STAGE=" + current.Stage,
+		"HOOK_SHA=" + current.BuiltSHA,
+		"HOOK_JOB_PATH=" + current.JobPath,
+		"HOOK_BUILD_URL=" + current.BuildURL,
+		"HOOK_PR_URL=" + current.PRURL,
+	}
+}
+
+// HookPayload is the JSON a hook script receives on stdin
+type HookPayload struct {
+	Event           string            `json:"event"`
+	PRNumber        string            `json:"pr"`
+	BuildNumber     int               `json:"build"`
+	Status          string            `json:"status"`
+	PreviousStatus  string            `json:"previous_status"`
+	GitBranch       string            `json:"branch,omitempty"`
+	Author          string            `json:"author,omitempty"`
+	Repository      string            `json:"repository,omitempty"`
+	Stage           string            `json:"stage,omitempty"`
+	JobName         string            `json:"job,omitempty"`
+	JobPath         string            `json:"job_path,omitempty"`
+	Timestamp       int64             `json:"timestamp"` // Build start, Unix seconds
+	DurationSeconds int               `json:"duration_s"`
+	BuiltSHA        string            `json:"sha,omitempty"`
+	BuildURL        string            `json:"build_url,omitempty"`
+	PRURL           string            `json:"pr_url,omitempty"`
+	Causes          []string          `json:"causes,omitempty"`
+	Parameters      map[string]string `json:"parameters,omitempty"`
+	Stages          []HookStage       `json:"stages,omitempty"`
+	FailedTests     []string          `json:"failed_tests,omitempty"`
+}
+
+// HookStage is a pipeline stage in a hook payload
+type HookStage struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	StartMillis    int64  `json:"start_ms"`
+	DurationMillis int64  `json:"duration_ms"`
+	Depth          int    `json:"depth,omitempty"` // Nesting level in the stage graph
+}
+
+// NewHookPayload describes a build for a hook script
+func NewHookPayload(event string, previous, current models.Build) HookPayload {
+	payload := HookPayload{
+		Event:           event,
+		PRNumber:        current.PRNumber,
+		BuildNumber:     current.BuildNumber,
+		Status:          current.Status.String(),
+		PreviousStatus:  previous.Status.String(),
+		GitBranch:       current.GitBranch,
+		Author:          current.PRAuthor,
+		Repository:      current.Repository,
+		Stage:           current.Stage,
+		JobName:         current.JobName,
+		JobPath:         current.JobPath,
+		Timestamp:       current.Timestamp,
+		DurationSeconds: current.GetCurrentDuration(),
+		BuiltSHA:        current.BuiltSHA,
+		BuildURL:        current.BuildURL,
+		PRURL:           current.PRURL,
+		Parameters:      current.Parameters,
+		FailedTests:     current.FailedTests,
+	}
+	for _, cause := range current.Causes {
+		payload.Causes = append(payload.Causes, cause.Description)
+	}
+	for _, stage := range current.Stages {
+		payload.Stages = append(payload.Stages, HookStage{
+			Name:           stage.Name,
+			Status:         stage.Status,
+			StartMillis:    stage.StartMillis,
+			DurationMillis: stage.DurationMillis,
+			Depth:          stage.Depth,
+		})
+	}
+	return payload
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestEvents(t *testing.T) {
+	running := models.Build{BuildNumber: 8, Status: models.StatusRunning}
+	paused := models.Build{BuildNumber: 8, Status: models.StatusRunning, Stages: []models.Stage{
+		{Name: "Deploy approval", Status: "PAUSED_PENDING_INPUT"},
+	}}
+
+	tests := []struct {
+		name              string
+		previous, current models.Build
+		want              string
+	}{
+		{"queued build starts", models.Build{BuildNumber: 7, Status: models.StatusSuccess}, running, HookStart},
+		{"still running", running, running, ""},
+		{"running to success", running, models.Build{BuildNumber: 8, Status: models.StatusSuccess}, HookSuccess},
+		{"running to failure", running, models.Build{BuildNumber: 8, Status: models.StatusFailure}, HookFailure},
+		{"running to aborted", running, models.Build{BuildNumber: 8, Status: models.StatusError}, ""},
+		{"finished between polls", models.Build{BuildNumber: 7, Status: models.StatusSuccess}, models.Build{BuildNumber: 8, Status: models.StatusFailure}, HookFailure},
+		{"newly added PR", models.Build{Status: models.StatusPending}, models.Build{BuildNumber: 8, Status: models.StatusSuccess}, ""},
+		{"success polled again", models.Build{BuildNumber: 8, Status: models.StatusSuccess}, models.Build{BuildNumber: 8, Status: models.StatusSuccess}, ""},
+		{"waiting for input", running, paused, HookInputRequired},
+		{"still waiting for input", paused, paused, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(Events(tt.previous, tt.current), ","); got != tt.want {
+				t.Errorf("Events() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHooks_RunPassesBuildOnStdinAndEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	hooks := NewHooks(map[string]string{
+		HookFailure: `cat > ` + filepath.Join(dir, "stdin.json") + `; echo "$HOOK_EVENT $HOOK_PR $HOOK_STATUS $HOOK_BUILD_URL" > ` + filepath.Join(dir, "env"),
+	}, 0)
+
+	previous := models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning}
+	current := models.Build{
+		PRNumber:    "3934",
+		BuildNumber: 8,
+		Status:      models.StatusFailure,
+		BuildURL:    "https://jenkins/build/8",
+		Stages:      []models.Stage{{Name: "Test", Status: "FAILED", DurationMillis: 5000}},
+	}
+	if err := hooks.Run(HookFailure, previous, current); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	env, _ := os.ReadFile(filepath.Join(dir, "env"))
+	if got := strings.TrimSpace(string(env)); got != "on_failure 3934 failure https://jenkins/build/8" {
+		t.Errorf("Hook environment = %q", got)
+	}
+
+	var payload HookPayload
+	data, _ := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("Hook stdin is not JSON: %v\n%s", err, data)
+	}
+	if payload.PreviousStatus != "running" || len(payload.Stages) != 1 || payload.Stages[0].Status != "FAILED" {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	// Events without a command do nothing
+	if err := hooks.Run(HookSuccess, previous, current); err != nil {
+		t.Errorf("Run() without a command error = %v", err)
+	}
+}
+
+func TestHooks_RunReportsFailuresAndTimeouts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	hooks := NewHooks(map[string]string{HookStart: "echo no dev env >&2; exit 1", HookSuccess: "sleep 5"}, 100*time.Millisecond)
+
+	if err := hooks.Run(HookStart, models.Build{}, models.Build{}); err == nil || !strings.Contains(err.Error(), "on_start hook: exit status 1: no dev env") {
+		t.Errorf("Expected the script's error output, got %v", err)
+	}
+	if err := hooks.Run(HookSuccess, models.Build{}, models.Build{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+func TestLoadHooks(t *testing.T) {
+	t.Setenv("HOOK_ON_INPUT_REQUIRED", "say 'approval needed'")
+	t.Setenv("HOOK_TIMEOUT", "5m")
+	hooks := LoadHooks()
+
+	if !hooks.Has(HookInputRequired) || hooks.Has(HookSuccess) {
+		t.Errorf("LoadHooks() commands = %v", hooks.commands)
+	}
+	if hooks.timeout != 5*time.Minute {
+		t.Errorf("LoadHooks() timeout = %v, want 5m", hooks.timeout)
+	}
+}
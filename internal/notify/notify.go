@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Notification methods
+const (
+	MethodBell    = "bell"    // Terminal bell (BEL)
+	MethodOSC9    = "osc9"    // OSC 9 desktop notification (iTerm2, Windows Terminal, WezTerm, ...)
+	MethodOSC777  = "osc777"  // OSC 777 desktop notification (rxvt-unicode, foot, Ghostty, ...)
+	MethodCommand = "command" // External command, e.g., notify-send
+)
+
+// commandTimeout bounds how long a notification command may run
+const commandTimeout = 10 * time.Second
+
+// Event is a change of a tracked build's status between two polls
+type Event struct {
+	PRNumber        string
+	BuildNumber     int
+	Status          models.BuildStatus
+	Previous        models.BuildStatus // Status at the previous poll
+	GitBranch       string
+	Author          string // GitHub login of the PR author, if known
+	Stage           string
+	Duration        string // e.g., "12m 5s"
+	FailureCategory string
+	BuildURL        string
+	PRURL           string
+}
+
+// NewEvent describes the current state of a build and the status it had before
+func NewEvent(previous, current models.Build) Event {
+	return Event{
+		PRNumber:        current.PRNumber,
+		BuildNumber:     current.BuildNumber,
+		Status:          current.Status,
+		Previous:        previous.Status,
+		GitBranch:       current.GitBranch,
+		Author:          current.PRAuthor,
+		Stage:           current.Stage,
+		Duration:        current.FormatDuration(),
+		FailureCategory: current.FailureCategory,
+		BuildURL:        current.BuildURL,
+		PRURL:           current.PRURL,
+	}
+}
+
+// Changed returns the event for a build whose status changed between two polls
+// A new build number counts as a change even if the status is the same
+func Changed(previous, current models.Build) (Event, bool) {
+	if previous.Status == current.Status && previous.BuildNumber == current.BuildNumber {
+		return Event{}, false
+	}
+	return NewEvent(previous, current), true
+}
+
+// Transition returns the event for a build that went from running to success
+// or failure between two polls of the same tile
+func Transition(previous, current models.Build) (Event, bool) {
+	if !previous.IsRunning() || !(current.IsSuccess() || current.IsFailure()) {
+		return Event{}, false
+	}
+	return NewEvent(previous, current), true
+}
+
+// Title returns the notification title (e.g., "✓ PR-3934 passed")
+func (e Event) Title() string {
+	switch e.Status {
+	case models.StatusSuccess:
+		return fmt.Sprintf("✓ PR-%s passed", e.PRNumber)
+	case models.StatusFailure:
+		return fmt.Sprintf("✗ PR-%s failed", e.PRNumber)
+	case models.StatusRunning:
+		return fmt.Sprintf("▶ PR-%s started", e.PRNumber)
+	case models.StatusPending:
+		return fmt.Sprintf("… PR-%s queued", e.PRNumber)
+	case models.StatusAborted:
+		return fmt.Sprintf("■ PR-%s aborted", e.PRNumber)
+	default:
+		return fmt.Sprintf("⚠ PR-%s errored", e.PRNumber)
+	}
+}
+
+// Body returns the notification text (e.g., "Build #8 of feature/login in 12m 5s")
+func (e Event) Body() string {
+	body := fmt.Sprintf("Build #%d", e.BuildNumber)
+	if e.GitBranch != "" {
+		body += " of " + e.GitBranch
+	}
+	if e.Duration != "" && e.finished() {
+		body += " in " + e.Duration
+	}
+	if e.FailureCategory != "" {
+		body += " (" + e.FailureCategory + ")"
+	}
+	return body
+}
+
+// finished reports whether the event is a final result
+func (e Event) finished() bool {
+	return e.Status != models.StatusRunning && e.Status != models.StatusPending
+}
+
+// Config selects how notifications are delivered
+type Config struct {
+	Methods []string
+	Command string // Shell command for MethodCommand
+}
+
+// LoadConfig returns the notification settings from environment variables
+// NOTIFY is a comma-separated list of methods (bell, osc9, osc777, command), off by default
+// NOTIFY_COMMAND is run through the shell with the event in NOTIFY_* variables;
+// setting it enables the command method even if NOTIFY doesn't list it
+func LoadConfig() (Config, error) {
+	cfg := Config{Command: os.Getenv("NOTIFY_COMMAND")}
+
+	methods, err := ParseMethods(os.Getenv("NOTIFY"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Methods = methods
+
+	if cfg.Command != "" && !cfg.has(MethodCommand) {
+		cfg.Methods = append(cfg.Methods, MethodCommand)
+	}
+	if cfg.has(MethodCommand) && cfg.Command == "" {
+		return Config{}, fmt.Errorf("NOTIFY includes %q but NOTIFY_COMMAND is not set", MethodCommand)
+	}
+	return cfg, nil
+}
+
+// ParseMethods parses a comma-separated list of notification methods
+// "" and "off" disable notifications
+func ParseMethods(value string) ([]string, error) {
+	var methods []string
+	for _, field := range strings.Split(value, ",") {
+		method := strings.ToLower(strings.TrimSpace(field))
+		switch method {
+		case "", "off":
+		case MethodBell, MethodOSC9, MethodOSC777, MethodCommand:
+			methods = append(methods, method)
+		default:
+			return nil, fmt.Errorf("unknown notification method %q (use bell, osc9, osc777 or command)", method)
+		}
+	}
+	return methods, nil
+}
+
+// has reports whether a method is enabled
+func (c Config) has(method string) bool {
+	for _, m := range c.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier delivers build notifications to the terminal and/or a command
+type Notifier struct {
+	cfg Config
+	out io.Writer // Terminal the escape sequences are written to (nil = none)
+}
+
+// New creates a notifier that writes terminal notifications to out
+// Without a terminal (out is nil, e.g., serve mode) only the command method is used
+func New(cfg Config, out io.Writer) *Notifier {
+	return &Notifier{cfg: cfg, out: out}
+}
+
+// Enabled reports whether any notification method is configured
+func (n *Notifier) Enabled() bool {
+	return n != nil && len(n.cfg.Methods) > 0
+}
+
+// Notify delivers the event with every configured method
+// Both the terminal and the command methods are attempted; the first error is returned
+func (n *Notifier) Notify(event Event) error {
+	terminalErr := n.Terminal(event)
+	if err := n.Command(event); err != nil && terminalErr == nil {
+		return err
+	}
+	return terminalErr
+}
+
+// Terminal writes the event's terminal notifications (bell, OSC 9, OSC 777)
+// in a single write
+// Call it from the goroutine that owns the terminal (e.g., Bubbletea's Update,
+// which also writes the window title), not from a background command that
+// could write into the middle of a frame
+func (n *Notifier) Terminal(event Event) error {
+	if !n.Enabled() || n.out == nil {
+		return nil
+	}
+
+	var sequences strings.Builder
+	for _, method := range n.cfg.Methods {
+		switch method {
+		case MethodBell:
+			sequences.WriteString("\a")
+		case MethodOSC9:
+			sequences.WriteString(OSC9(event))
+		case MethodOSC777:
+			sequences.WriteString(OSC777(event))
+		}
+	}
+	if sequences.Len() == 0 {
+		return nil
+	}
+	if _, err := io.WriteString(n.out, sequences.String()); err != nil {
+		return fmt.Errorf("terminal notification: %w", err)
+	}
+	return nil
+}
+
+// Command runs the notification command, if the command method is configured
+// It may take a while, so it is meant to run in the background
+func (n *Notifier) Command(event Event) error {
+	if !n.Enabled() || !n.cfg.has(MethodCommand) {
+		return nil
+	}
+	if err := runCommand(n.cfg.Command, event); err != nil {
+		return fmt.Errorf("%s notification: %w", MethodCommand, err)
+	}
+	return nil
+}
+
+// OSC9 returns the OSC 9 escape sequence for the event (message only, no title)
+func OSC9(event Event) string {
+	return "\x1b]9;" + sanitize(event.Title()+": "+event.Body()) + "\a"
+}
+
+// OSC777 returns the OSC 777 escape sequence for the event
+// Fields are separated by ";", so it is removed from the title
+func OSC777(event Event) string {
+	title := strings.ReplaceAll(sanitize(event.Title()), ";", ",")
+	return "\x1b]777;notify;" + title + ";" + sanitize(event.Body()) + "\a"
+}
+
+// sanitize drops control characters, which would end the escape sequence early
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// runCommand runs the notification command through the shell
+// The event is passed in environment variables rather than substituted into
+// the command, so branch names can't inject shell syntax
+func runCommand(command string, event Event) error {
+	return runShell(command, Env(event), nil, commandTimeout)
+}
+
+// runShell runs a command through the platform shell with extra environment
+// variables and optional stdin, killing it after timeout
+func runShell(command string, env []string, stdin io.Reader, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.WaitDelay = time.Second // Don't wait for children of a killed shell that still hold its output
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		if text := strings.TrimSpace(string(output)); text != "" {
+			return fmt.Errorf("%w: %s", err, firstLine(text))
+		}
+		return err
+	}
+	return nil
+}
+
+// Env returns the event as NOTIFY_* environment variables
+func Env(event Event) []string {
+	return []string{
+		"NOTIFY_TITLE=" + event.Title(),
+		"NOTIFY_BODY=" + event.Body(),
+		"NOTIFY_PR=" + event.PRNumber,
+		"NOTIFY_BUILD=" + strconv.Itoa(event.BuildNumber),
+		"NOTIFY_STATUS=" + event.Status.String(),
+		"NOTIFY_BRANCH=" + event.GitBranch,
+	}
+}
+
+// firstLine returns the first line of a command's output
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
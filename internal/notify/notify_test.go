@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestTransition(t *testing.T) {
+	running := models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning}
+	tests := []struct {
+		name     string
+		previous models.Build
+		current  models.BuildStatus
+		want     bool
+	}{
+		{"running to success", running, models.StatusSuccess, true},
+		{"running to failure", running, models.StatusFailure, true},
+		{"running to aborted", running, models.StatusError, false},
+		{"still running", running, models.StatusRunning, false},
+		{"success polled again", models.Build{Status: models.StatusSuccess}, models.StatusSuccess, false},
+		{"pending to failure", models.Build{Status: models.StatusPending}, models.StatusFailure, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := models.Build{PRNumber: "3934", BuildNumber: 8, Status: tt.current, GitBranch: "feature/login", DurationSeconds: 65}
+			event, ok := Transition(tt.previous, current)
+			if ok != tt.want {
+				t.Fatalf("Transition() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && event.Body() != "Build #8 of feature/login in 1m 5s" {
+				t.Errorf("Body() = %q", event.Body())
+			}
+		})
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	methods, err := ParseMethods(" Bell, osc777 ,")
+	if err != nil || strings.Join(methods, ",") != "bell,osc777" {
+		t.Errorf("ParseMethods() = %v, %v", methods, err)
+	}
+	if methods, _ := ParseMethods("off"); len(methods) != 0 {
+		t.Errorf("Expected no methods for off, got %v", methods)
+	}
+	if _, err := ParseMethods("bell,popup"); err == nil {
+		t.Error("Expected an error for an unknown method")
+	}
+}
+
+func TestLoadConfig_CommandImpliesMethod(t *testing.T) {
+	t.Setenv("NOTIFY", "bell")
+	t.Setenv("NOTIFY_COMMAND", "notify-send \"$NOTIFY_TITLE\"")
+	cfg, err := LoadConfig()
+	if err != nil || strings.Join(cfg.Methods, ",") != "bell,command" {
+		t.Errorf("LoadConfig() = %+v, %v", cfg, err)
+	}
+
+	t.Setenv("NOTIFY", "command")
+	t.Setenv("NOTIFY_COMMAND", "")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("Expected an error for the command method without NOTIFY_COMMAND")
+	}
+}
+
+func TestNotify_TerminalSequences(t *testing.T) {
+	var out strings.Builder
+	n := New(Config{Methods: []string{MethodBell, MethodOSC9, MethodOSC777}}, &out)
+	event := Event{PRNumber: "3934", BuildNumber: 8, Status: models.StatusSuccess, GitBranch: "fix;\x1b[31mred"}
+
+	if err := n.Notify(event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	want := "\a" +
+		"\x1b]9;✓ PR-3934 passed: Build #8 of fix;[31mred\a" +
+		"\x1b]777;notify;✓ PR-3934 passed;Build #8 of fix;[31mred\a"
+	if out.String() != want {
+		t.Errorf("Notify() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestNotify_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	path := filepath.Join(t.TempDir(), "notified")
+	n := New(Config{Methods: []string{MethodCommand}, Command: `printf '%s %s' "$NOTIFY_STATUS" "$NOTIFY_PR" > ` + path}, nil)
+
+	if err := n.Notify(Event{PRNumber: "3934", Status: models.StatusFailure}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "failure 3934" {
+		t.Errorf("Command saw %q, %v", data, err)
+	}
+
+	n = New(Config{Methods: []string{MethodCommand}, Command: "echo broken >&2; exit 3"}, nil)
+	if err := n.Notify(Event{}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected the command's output in the error, got %v", err)
+	}
+}
+
+func TestNotifier_DisabledWithoutMethods(t *testing.T) {
+	var n *Notifier
+	if n.Enabled() || New(Config{}, nil).Enabled() {
+		t.Error("Notifier without methods should be disabled")
+	}
+}
@@ -0,0 +1,347 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+const webhooksFileName = ".jenkins-dash-webhooks.json"
+
+// webhookTimeout bounds a single webhook request
+const webhookTimeout = 10 * time.Second
+
+// Webhook payload formats
+const (
+	FormatSlack   = "slack"   // {"text": ...}, also accepted by Mattermost, Rocket.Chat and Google Chat
+	FormatTeams   = "teams"   // Office 365 connector MessageCard
+	FormatGeneric = "generic" // The event as JSON
+)
+
+// Target is a webhook URL with the builds it wants to hear about
+type Target struct {
+	Name     string            `json:"name"` // Shown in errors, defaults to the URL host
+	URL      string            `json:"url"`
+	Format   string            `json:"format"`   // slack, teams or generic (default)
+	Template string            `json:"template"` // Optional Go template for the JSON body, overrides Format
+	Headers  map[string]string `json:"headers"`  // Extra request headers (e.g., Authorization)
+	Filter   Filter            `json:"filter"`
+
+	tmpl *template.Template
+}
+
+// Filter selects events; empty fields match everything
+type Filter struct {
+	Statuses []string `json:"statuses"` // New status: pending, running, success, failure, error, aborted
+	Authors  []string `json:"authors"`  // PR author GitHub logins
+	PRs      []string `json:"prs"`      // PR numbers
+}
+
+// Match reports whether an event passes the filter
+func (f Filter) Match(event Event) bool {
+	if len(f.Statuses) > 0 && !containsFold(f.Statuses, event.Status.String()) {
+		return false
+	}
+	if len(f.Authors) > 0 && !containsFold(f.Authors, event.Author) {
+		return false
+	}
+	if len(f.PRs) > 0 && !slices.Contains(f.PRs, event.PRNumber) {
+		return false
+	}
+	return true
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhooksFile is the on-disk format of the webhooks config file
+type WebhooksFile struct {
+	Webhooks []Target `json:"webhooks"`
+}
+
+// GetWebhooksPath returns the path to the webhooks config file
+// Reads from WEBHOOKS_FILE or defaults to ~/.jenkins-dash-webhooks.json
+func GetWebhooksPath() string {
+	if path := os.Getenv("WEBHOOKS_FILE"); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return webhooksFileName
+	}
+	return filepath.Join(homeDir, webhooksFileName)
+}
+
+// LoadWebhooks reads and validates webhook targets from path
+// Returns no targets if the file doesn't exist (not an error)
+func LoadWebhooks(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file WebhooksFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return CompileTargets(file.Webhooks)
+}
+
+// CompileTargets validates targets and parses their templates, returning compiled copies
+func CompileTargets(targets []Target) ([]Target, error) {
+	compiled := make([]Target, 0, len(targets))
+	for i, target := range targets {
+		if !strings.HasPrefix(target.URL, "http://") && !strings.HasPrefix(target.URL, "https://") {
+			return nil, fmt.Errorf("webhook %d: url must be http(s), got %q", i+1, target.URL)
+		}
+		if target.Name == "" {
+			target.Name = hostOf(target.URL)
+		}
+
+		switch target.Format {
+		case "":
+			target.Format = FormatGeneric
+		case FormatSlack, FormatTeams, FormatGeneric:
+		default:
+			return nil, fmt.Errorf("webhook %q: unknown format %q (use slack, teams or generic)", target.Name, target.Format)
+		}
+
+		if target.Template != "" {
+			tmpl, err := template.New(target.Name).Funcs(templateFuncs).Parse(target.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %q: %w", target.Name, err)
+			}
+			target.tmpl = tmpl
+		}
+		compiled = append(compiled, target)
+	}
+	return compiled, nil
+}
+
+// hostOf returns the host of an http(s) URL
+func hostOf(rawURL string) string {
+	rest := rawURL[strings.Index(rawURL, "://")+3:]
+	host, _, _ := strings.Cut(rest, "/")
+	return host
+}
+
+// templateFuncs are available in payload templates
+// {{json .Title}} writes a quoted, escaped JSON string
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Payload renders the JSON body for an event
+func (t Target) Payload(event Event) ([]byte, error) {
+	if t.tmpl != nil {
+		var b bytes.Buffer
+		if err := t.tmpl.Execute(&b, event); err != nil {
+			return nil, err
+		}
+		if !json.Valid(b.Bytes()) {
+			return nil, fmt.Errorf("template produced invalid JSON: %s", firstLine(b.String()))
+		}
+		return b.Bytes(), nil
+	}
+
+	switch t.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": slackText(event)})
+	case FormatTeams:
+		return json.Marshal(teamsCard(event))
+	default:
+		return json.Marshal(newGenericPayload(event))
+	}
+}
+
+// slackText formats an event as Slack mrkdwn with links to the build and PR
+func slackText(event Event) string {
+	text := "*" + event.Title() + "*: " + event.Body()
+	var links []string
+	if event.BuildURL != "" {
+		links = append(links, "<"+event.BuildURL+"|Build>")
+	}
+	if event.PRURL != "" {
+		links = append(links, "<"+event.PRURL+"|PR>")
+	}
+	if len(links) > 0 {
+		text += " · " + strings.Join(links, " · ")
+	}
+	return text
+}
+
+// teamsCard formats an event as an Office 365 connector MessageCard
+func teamsCard(event Event) map[string]any {
+	var actions []map[string]any
+	for _, link := range []struct{ name, url string }{{"Open build", event.BuildURL}, {"Open PR", event.PRURL}} {
+		if link.url != "" {
+			actions = append(actions, map[string]any{
+				"@type":   "OpenUri",
+				"name":    link.name,
+				"targets": []map[string]string{{"os": "default", "uri": link.url}},
+			})
+		}
+	}
+
+	card := map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    event.Title(),
+		"themeColor": themeColor(event.Status),
+		"title":      event.Title(),
+		"text":       event.Body(),
+	}
+	if len(actions) > 0 {
+		card["potentialAction"] = actions
+	}
+	return card
+}
+
+// themeColor returns the card accent color for a status (hex without "#")
+func themeColor(status models.BuildStatus) string {
+	switch status {
+	case models.StatusSuccess:
+		return "2EB67D"
+	case models.StatusFailure, models.StatusError:
+		return "E01E5A"
+	case models.StatusAborted:
+		return "9E9E9E" // Grey, as Jenkins shows aborted builds
+	default:
+		return "36C5F0"
+	}
+}
+
+// genericPayload is the JSON body of generic webhooks
+type genericPayload struct {
+	Title           string `json:"title"`
+	Text            string `json:"text"`
+	PRNumber        string `json:"pr"`
+	BuildNumber     int    `json:"build"`
+	Status          string `json:"status"`
+	Previous        string `json:"previous_status"`
+	GitBranch       string `json:"branch,omitempty"`
+	Author          string `json:"author,omitempty"`
+	Stage           string `json:"stage,omitempty"`
+	Duration        string `json:"duration,omitempty"`
+	FailureCategory string `json:"failure_category,omitempty"`
+	BuildURL        string `json:"build_url,omitempty"`
+	PRURL           string `json:"pr_url,omitempty"`
+}
+
+// newGenericPayload converts an event, writing statuses as names rather than numbers
+func newGenericPayload(event Event) genericPayload {
+	return genericPayload{
+		Title:           event.Title(),
+		Text:            event.Body(),
+		PRNumber:        event.PRNumber,
+		BuildNumber:     event.BuildNumber,
+		Status:          event.Status.String(),
+		Previous:        event.Previous.String(),
+		GitBranch:       event.GitBranch,
+		Author:          event.Author,
+		Stage:           event.Stage,
+		Duration:        event.Duration,
+		FailureCategory: event.FailureCategory,
+		BuildURL:        event.BuildURL,
+		PRURL:           event.PRURL,
+	}
+}
+
+// Webhooks posts events to the targets whose filters match
+type Webhooks struct {
+	targets    []Target
+	httpClient *http.Client
+}
+
+// NewWebhooks creates a webhook notifier for compiled targets
+func NewWebhooks(targets []Target) *Webhooks {
+	return &Webhooks{targets: targets, httpClient: &http.Client{Timeout: webhookTimeout}}
+}
+
+// Wants reports whether any target would receive the event
+func (w *Webhooks) Wants(event Event) bool {
+	if w == nil {
+		return false
+	}
+	for _, target := range w.targets {
+		if target.Filter.Match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send posts the event to every matching target
+// All targets are attempted; failures are joined into one error
+func (w *Webhooks) Send(event Event) error {
+	if w == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, target := range w.targets {
+		if !target.Filter.Match(event) {
+			continue
+		}
+		if err := w.post(target, event); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %q: %w", target.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post sends one event to one target
+func (w *Webhooks) post(target Target, event Event) error {
+	payload, err := target.Payload(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range target.Headers {
+		req.Header.Set(name, os.ExpandEnv(value)) // Keep secrets in the environment, e.g., "Bearer ${CHAT_TOKEN}"
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(body)); text != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, firstLine(text))
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// receiver is a local webhook endpoint that records the requests it gets
+type receiver struct {
+	*httptest.Server
+	bodies  []string
+	headers []http.Header
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.bodies = append(r.bodies, string(body))
+		r.headers = append(r.headers, req.Header.Clone())
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func failedEvent() Event {
+	return Event{
+		PRNumber:        "3934",
+		BuildNumber:     8,
+		Status:          models.StatusFailure,
+		Previous:        models.StatusRunning,
+		GitBranch:       "feature/login",
+		Author:          "mpetters",
+		Duration:        "12m 5s",
+		FailureCategory: "Out of memory",
+		BuildURL:        "https://jenkins/build/8",
+	}
+}
+
+func TestWebhooks_SendsFormattedPayloads(t *testing.T) {
+	slack, teams, generic := newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK), newReceiver(t, http.StatusOK)
+	targets, err := CompileTargets([]Target{
+		{URL: slack.URL, Format: FormatSlack},
+		{URL: teams.URL, Format: FormatTeams},
+		{URL: generic.URL},
+	})
+	if err != nil {
+		t.Fatalf("CompileTargets() error = %v", err)
+	}
+
+	if err := NewWebhooks(targets).Send(failedEvent()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var slackBody map[string]string
+	json.Unmarshal([]byte(slack.bodies[0]), &slackBody)
+	want := "*✗ PR-3934 failed*: Build #8 of feature/login in 12m 5s (Out of memory) · <https://jenkins/build/8|Build>"
+	if slackBody["text"] != want {
+		t.Errorf("Slack text = %q, want %q", slackBody["text"], want)
+	}
+	if slack.headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON content type, got %q", slack.headers[0].Get("Content-Type"))
+	}
+
+	var card map[string]any
+	json.Unmarshal([]byte(teams.bodies[0]), &card)
+	if card["@type"] != "MessageCard" || card["themeColor"] != "E01E5A" || card["title"] != "✗ PR-3934 failed" {
+		t.Errorf("Unexpected Teams card: %s", teams.bodies[0])
+	}
+
+	var payload map[string]any
+	json.Unmarshal([]byte(generic.bodies[0]), &payload)
+	if payload["status"] != "failure" || payload["previous_status"] != "running" || payload["author"] != "mpetters" {
+		t.Errorf("Unexpected generic payload: %s", generic.bodies[0])
+	}
+}
+
+func TestWebhooks_Filters(t *testing.T) {
+	mine := newReceiver(t, http.StatusOK)
+	targets, _ := CompileTargets([]Target{
+		{URL: mine.URL, Filter: Filter{Statuses: []string{"failure"}, Authors: []string{"MPetters"}}},
+	})
+	webhooks := NewWebhooks(targets)
+
+	passed := failedEvent()
+	passed.Status = models.StatusSuccess
+	someoneElse := failedEvent()
+	someoneElse.Author = "jdoe"
+
+	for _, event := range []Event{failedEvent(), passed, someoneElse} {
+		if err := webhooks.Send(event); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if len(mine.bodies) != 1 {
+		t.Errorf("Expected only failures on my PRs, got %d requests", len(mine.bodies))
+	}
+	if webhooks.Wants(passed) || !webhooks.Wants(failedEvent()) {
+		t.Error("Wants() should follow the filters")
+	}
+}
+
+func TestWebhooks_FiltersAborted(t *testing.T) {
+	receiver := newReceiver(t, http.StatusOK)
+	targets, _ := CompileTargets([]Target{
+		{URL: receiver.URL, Format: "teams", Filter: Filter{Statuses: []string{"aborted"}}},
+	})
+	webhooks := NewWebhooks(targets)
+
+	aborted := failedEvent()
+	aborted.Status = models.StatusAborted
+	for _, event := range []Event{failedEvent(), aborted} {
+		if err := webhooks.Send(event); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if len(receiver.bodies) != 1 {
+		t.Fatalf("Expected only the aborted build posted, got %d requests", len(receiver.bodies))
+	}
+	if !strings.Contains(receiver.bodies[0], themeColor(models.StatusAborted)) {
+		t.Errorf("Aborted card should have its own color, got %s", receiver.bodies[0])
+	}
+	if themeColor(models.StatusAborted) == themeColor(models.StatusRunning) {
+		t.Error("Aborted builds should not share the running color")
+	}
+}
+
+func TestWebhooks_TemplateAndHeaders(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	t.Setenv("CHAT_TOKEN", "s3cret")
+	targets, err := CompileTargets([]Target{{
+		URL:      r.URL,
+		Template: `{"msg": {{json .Title}}, "pr": {{.PRNumber}}, "status": "{{.Status}}"}`,
+		Headers:  map[string]string{"Authorization": "Bearer ${CHAT_TOKEN}"},
+	}})
+	if err != nil {
+		t.Fatalf("CompileTargets() error = %v", err)
+	}
+
+	if err := NewWebhooks(targets).Send(failedEvent()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if want := `{"msg": "✗ PR-3934 failed", "pr": 3934, "status": "failure"}`; r.bodies[0] != want {
+		t.Errorf("Body = %s, want %s", r.bodies[0], want)
+	}
+	if got := r.headers[0].Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want the expanded token", got)
+	}
+}
+
+func TestWebhooks_ReportsFailures(t *testing.T) {
+	broken := newReceiver(t, http.StatusBadRequest)
+	ok := newReceiver(t, http.StatusOK)
+	targets, _ := CompileTargets([]Target{
+		{Name: "team-chat", URL: broken.URL},
+		{URL: ok.URL, Template: `{"text": {{.Title}}}`}, // Unquoted - invalid JSON
+	})
+
+	err := NewWebhooks(targets).Send(failedEvent())
+	if err == nil || !strings.Contains(err.Error(), `"team-chat": HTTP 400`) || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("Expected both failures to be reported, got %v", err)
+	}
+	if len(ok.bodies) != 0 {
+		t.Error("Invalid payloads should not be sent")
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	if targets, err := LoadWebhooks(filepath.Join(t.TempDir(), "missing.json")); err != nil || targets != nil {
+		t.Errorf("Missing file should mean no webhooks, got %v, %v", targets, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	os.WriteFile(path, []byte(`{"webhooks": [{"url": "https://hooks.example.com/T0/B0", "format": "slack", "filter": {"statuses": ["failure"]}}]}`), 0644)
+	targets, err := LoadWebhooks(path)
+	if err != nil || len(targets) != 1 || targets[0].Name != "hooks.example.com" {
+		t.Errorf("LoadWebhooks() = %+v, %v", targets, err)
+	}
+
+	os.WriteFile(path, []byte(`{"webhooks": [{"url": "https://hooks.example.com", "format": "irc"}]}`), 0644)
+	if _, err := LoadWebhooks(path); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	running := models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning}
+	if _, ok := Changed(running, running); ok {
+		t.Error("Same build and status is not a change")
+	}
+	event, ok := Changed(running, models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusFailure})
+	if !ok || event.Previous != models.StatusRunning {
+		t.Errorf("Changed() = %+v, %v", event, ok)
+	}
+	if _, ok := Changed(running, models.Build{PRNumber: "3934", BuildNumber: 9, Status: models.StatusRunning}); !ok {
+		t.Error("A new build is a change")
+	}
+}
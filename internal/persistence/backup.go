@@ -0,0 +1,42 @@
+package persistence
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	backupCount    = 3         // Backups kept, "<file>.bak.1" (newest) to "<file>.bak.3"
+	backupInterval = time.Hour // Minimum age of the newest backup before the next one
+)
+
+// backupPath returns the path of the nth newest backup of a file
+func backupPath(filePath string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", filePath, n)
+}
+
+// backup copies a file to "<file>.bak.1" before it is replaced, rotating the
+// older backups, if the newest backup is more than backupInterval old
+// A file of an older version (force) is always backed up, so a migration can
+// be undone by restoring it
+// Does nothing if the file doesn't exist (caller holds the lock)
+func backup(filePath string, force bool) error {
+	if info, err := os.Stat(backupPath(filePath, 1)); err == nil && !force && time.Since(info.ModTime()) < backupInterval {
+		return nil
+	}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for n := backupCount - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(filePath, n), backupPath(filePath, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return WriteAtomic(backupPath(filePath, 1), data)
+}
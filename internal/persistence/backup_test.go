@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestWriteFile_LeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "builds.json")
+	for i := 0; i < 3; i++ {
+		if err := SaveBuilds(path, []models.Build{{PRNumber: "3859"}}); err != nil {
+			t.Fatalf("SaveBuilds() error = %v", err)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") || strings.HasSuffix(entry.Name(), ".lock") {
+			t.Errorf("left behind %s", entry.Name())
+		}
+	}
+}
+
+func TestBackup_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	old := time.Now().Add(-2 * backupInterval)
+
+	// Each write replaces a file whose newest backup is old enough
+	for _, pr := range []string{"1", "2", "3", "4", "5"} {
+		if err := SaveBuilds(path, []models.Build{{PRNumber: pr}}); err != nil {
+			t.Fatalf("SaveBuilds() error = %v", err)
+		}
+		os.Chtimes(backupPath(path, 1), old, old)
+	}
+
+	// The newest backups hold the files each write replaced
+	for n, want := range map[int]string{1: "4", 2: "3", 3: "2"} {
+		builds, err := LoadBuilds(backupPath(path, n))
+		if err != nil || len(builds) != 1 || builds[0].PRNumber != want {
+			t.Errorf("backup %d = %+v, %v; want PR-%s", n, builds, err, want)
+		}
+	}
+	if _, err := os.Stat(backupPath(path, backupCount+1)); !os.IsNotExist(err) {
+		t.Errorf("expected at most %d backups", backupCount)
+	}
+}
+
+func TestBackup_WaitsForInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	for _, pr := range []string{"1", "2", "3"} {
+		if err := SaveBuilds(path, []models.Build{{PRNumber: pr}}); err != nil {
+			t.Fatalf("SaveBuilds() error = %v", err)
+		}
+	}
+
+	builds, _ := LoadBuilds(backupPath(path, 1))
+	if len(builds) != 1 || builds[0].PRNumber != "1" {
+		t.Errorf("backup 1 = %+v, want PR-1 until the interval passes", builds)
+	}
+	if _, err := os.Stat(backupPath(path, 2)); !os.IsNotExist(err) {
+		t.Error("expected a single backup within the interval")
+	}
+}
+
+func TestBackup_KeepsFileBeforeMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	legacy := `[{"PRNumber": "3859"}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backupPath(path, 1), []byte(legacy), 0644); err != nil {
+		t.Fatal(err) // A recent backup doesn't stop the migration backup
+	}
+
+	if _, err := AddPRs(path, []string{"4001"}); err != nil {
+		t.Fatalf("AddPRs() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(backupPath(path, 1))
+	if string(data) != legacy {
+		t.Errorf("backup 1 = %s, want the file as it was before migration", data)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), `"version": 2`) {
+		t.Errorf("saved file = %s, want version 2", data)
+	}
+}
@@ -0,0 +1,46 @@
+package persistence
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockTimeout  = 2 * time.Second  // How long to wait for another instance's write
+	staleLockAge = 10 * time.Second // Lock files older than this were left by a crashed instance
+)
+
+// lock takes the lock on a saved builds file, so instances don't interleave
+// their read-merge-write cycles. The lock is a sibling "<file>.lock" created
+// exclusively, which works on every platform
+// Returns a function that releases the lock
+func lock(filePath string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+
+	lockPath := filePath + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked by another instance (delete %s if none is running)", filePath, lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
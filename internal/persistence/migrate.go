@@ -0,0 +1,81 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the version of the saved builds file this build writes
+//
+//	0: a bare JSON array of builds
+//	1: {"generation", "builds"}
+//	2: {"version", "generation", "builds", "ui"}
+const CurrentVersion = 2
+
+// migrations[v] upgrades a version v file to version v+1
+// Files are migrated as generic JSON before being decoded, so a migration can
+// rename or reshape fields the current Build struct no longer has
+var migrations = []func(doc map[string]any) (map[string]any, error){
+	migrateV0,
+	migrateV1,
+}
+
+// migrate upgrades a file from version to CurrentVersion
+func migrate(doc map[string]any, version int) (map[string]any, error) {
+	if version < 0 || version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported version %d (this build reads up to %d; was it written by a newer jenkins-dash?)", version, CurrentVersion)
+	}
+	for v := version; v < CurrentVersion; v++ {
+		var err error
+		if doc, err = migrations[v](doc); err != nil {
+			return nil, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+	}
+	return doc, nil
+}
+
+// migrateV0 wraps the builds of a bare array file, starting at generation 0
+func migrateV0(doc map[string]any) (map[string]any, error) {
+	doc["generation"] = 0
+	return doc, nil
+}
+
+// migrateV1 adds the version and an empty UI state
+func migrateV1(doc map[string]any) (map[string]any, error) {
+	doc["version"] = 2
+	doc["ui"] = map[string]any{}
+	return doc, nil
+}
+
+// decodeDocument parses a saved builds file as generic JSON, returning it as
+// an object and its version
+func decodeDocument(data []byte) (map[string]any, int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep generations and build numbers exact
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, 0, err
+	}
+
+	switch doc := value.(type) {
+	case []any:
+		return map[string]any{"builds": doc}, 0, nil // Version 0 was a bare array
+	case map[string]any:
+		raw, ok := doc["version"]
+		if !ok {
+			return doc, 1, nil // Version 1 had no version field
+		}
+		number, ok := raw.(json.Number)
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid version %v", raw)
+		}
+		version, err := number.Int64()
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid version %v", raw)
+		}
+		return doc, int(version), nil
+	default:
+		return nil, 0, fmt.Errorf("expected a JSON object, got %T", value)
+	}
+}
@@ -0,0 +1,89 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFile_MigratesOldVersions(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		wantGeneration int64
+	}{
+		{
+			name: "version 0 (bare array)",
+			data: `[{"PRNumber": "3859", "BuildNumber": 42}]`,
+		},
+		{
+			name:           "version 1 (generation)",
+			data:           `{"generation": 7, "builds": [{"PRNumber": "3859", "BuildNumber": 42}]}`,
+			wantGeneration: 7,
+		},
+		{
+			name:           "version 2",
+			data:           `{"version": 2, "generation": 8, "builds": [{"PRNumber": "3859", "BuildNumber": 42}], "ui": {"selected_pr": "3859", "detail": true}}`,
+			wantGeneration: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "builds.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			saved, err := readFile(path)
+			if err != nil {
+				t.Fatalf("readFile() error = %v", err)
+			}
+			if saved.Version != CurrentVersion || saved.Generation != tt.wantGeneration {
+				t.Errorf("version %d, generation %d; want %d, %d", saved.Version, saved.Generation, CurrentVersion, tt.wantGeneration)
+			}
+			if len(saved.Builds) != 1 || saved.Builds[0].PRNumber != "3859" || saved.Builds[0].BuildNumber != 42 {
+				t.Errorf("builds = %+v, want PR-3859 #42", saved.Builds)
+			}
+		})
+	}
+}
+
+func TestReadFile_KeepsUIState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	data := `{"version": 2, "generation": 1, "builds": [], "ui": {"selected_pr": "3859", "detail": true}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := readFile(path)
+	if err != nil {
+		t.Fatalf("readFile() error = %v", err)
+	}
+	if saved.UI != (UIState{SelectedPR: "3859", Detail: true}) || saved.migrated {
+		t.Errorf("readFile() UI = %+v, migrated %v", saved.UI, saved.migrated)
+	}
+}
+
+func TestReadFile_RejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "builds": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readFile(path); err == nil || !strings.Contains(err.Error(), "unsupported version 99") {
+		t.Errorf("readFile() error = %v, want unsupported version", err)
+	}
+}
+
+func TestReadFile_RejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	if err := os.WriteFile(path, []byte(`[{"PRNumber": "38`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readFile(path); err == nil {
+		t.Error("readFile() of a truncated file should fail")
+	}
+}
@@ -1,7 +1,9 @@
 package persistence
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -20,44 +22,164 @@ func GetConfigPath() string {
 	return filepath.Join(homeDir, configFileName)
 }
 
-// SaveBuilds saves the builds list to a JSON file
-func SaveBuilds(filePath string, builds []models.Build) error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+// UIState is the dashboard state saved along with the tiles
+type UIState struct {
+	SelectedPR string `json:"selected_pr,omitempty"` // PR of the selected tile
+	Detail     bool   `json:"detail,omitempty"`      // Detail view shown
+}
 
-	// Convert to JSON
-	data, err := json.MarshalIndent(builds, "", "  ")
-	if err != nil {
-		return err
-	}
+// savedFile is the on-disk format of the saved builds (see CurrentVersion)
+type savedFile struct {
+	Version    int            `json:"version"`
+	Generation int64          `json:"generation"` // Incremented by every write, so instances notice each other's changes
+	Builds     []models.Build `json:"builds"`
+	UI         UIState        `json:"ui"`
+
+	migrated bool     // Read from an older version, so the next write backs the file up first
+	sum      [32]byte // SHA-256 of the file as read (zero for a missing file)
+}
 
-	// Write to file
-	return os.WriteFile(filePath, data, 0644)
+// SaveBuilds replaces the saved builds
+func SaveBuilds(filePath string, builds []models.Build) error {
+	_, err := update(filePath, func([]models.Build) []models.Build { return builds })
+	return err
 }
 
 // LoadBuilds loads builds from a JSON file
 // Returns empty list if file doesn't exist (not an error)
 func LoadBuilds(filePath string) ([]models.Build, error) {
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return []models.Build{}, nil
+	saved, err := readFile(filePath)
+	if err != nil {
+		return nil, err
 	}
+	return saved.Builds, nil
+}
 
-	// Read file
+// readFile reads the saved builds, migrating files of older versions
+// A missing file is empty at generation 0
+func readFile(filePath string) (savedFile, error) {
 	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return savedFile{Version: CurrentVersion, Builds: []models.Build{}}, nil
+	}
 	if err != nil {
-		return nil, err
+		return savedFile{}, err
 	}
 
-	// Parse JSON
-	var builds []models.Build
-	if err := json.Unmarshal(data, &builds); err != nil {
-		return nil, err
+	saved, err := decode(data)
+	if err != nil {
+		return savedFile{}, fmt.Errorf("reading %s: %w", filePath, err)
+	}
+	saved.sum = sha256.Sum256(data)
+	return saved, nil
+}
+
+// sumOf returns the SHA-256 of a file (zero for a missing file)
+func sumOf(filePath string) ([32]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return [32]byte{}, nil
+	}
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// decode parses a saved builds file of any version
+func decode(data []byte) (savedFile, error) {
+	doc, version, err := decodeDocument(data)
+	if err != nil {
+		return savedFile{}, err
+	}
+	doc, err = migrate(doc, version)
+	if err != nil {
+		return savedFile{}, err
+	}
+
+	// Round-trip the migrated document into the current format
+	migratedData, err := json.Marshal(doc)
+	if err != nil {
+		return savedFile{}, err
 	}
+	var saved savedFile
+	if err := json.Unmarshal(migratedData, &saved); err != nil {
+		return savedFile{}, err
+	}
+	if saved.Builds == nil {
+		saved.Builds = []models.Build{}
+	}
+	saved.migrated = version < CurrentVersion
+	return saved, nil
+}
+
+// writeFile writes the saved builds as the current version, backing up the
+// file it replaces (see backup)
+// The file is written to a temporary file and renamed over the old one, so a
+// crash mid-write leaves the old file intact
+func writeFile(filePath string, saved savedFile) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	data, err := encode(saved)
+	if err != nil {
+		return err
+	}
+	if err := backup(filePath, saved.migrated); err != nil {
+		return fmt.Errorf("backing up %s: %w", filePath, err)
+	}
+	return WriteAtomic(filePath, data)
+}
+
+// encode returns the file content of the saved builds, as the current version
+func encode(saved savedFile) ([]byte, error) {
+	saved.Version = CurrentVersion
+	if saved.Builds == nil {
+		saved.Builds = []models.Build{}
+	}
+	return json.MarshalIndent(saved, "", "  ")
+}
+
+// WriteAtomic replaces a file with data via a temporary file in the same directory,
+// so readers (including other instances) never see a partly written file
+func WriteAtomic(filePath string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
 
-	return builds, nil
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
 }
 
+// update changes the saved builds under the file lock and writes them as the
+// next generation, returning what was written
+func update(filePath string, change func([]models.Build) []models.Build) (savedFile, error) {
+	unlock, err := lock(filePath)
+	if err != nil {
+		return savedFile{}, err
+	}
+	defer unlock()
+
+	saved, err := readFile(filePath)
+	if err != nil {
+		return savedFile{}, err
+	}
+	saved.Builds = change(saved.Builds)
+	saved.Generation++
+	return saved, writeFile(filePath, saved)
+}
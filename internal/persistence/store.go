@@ -0,0 +1,263 @@
+package persistence
+
+import (
+	"crypto/sha256"
+	"os"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Store is one instance's view of the saved builds file, which other instances
+// (a second dashboard, `jenkins-dash serve`, `jenkins-dash add`) may change
+// Changes on either side are merged rather than overwritten: a PR missing on
+// one side was removed there if both sides had it at the last sync, and was
+// added on the other side if not
+// A Store is not safe for concurrent use
+type Store struct {
+	path       string
+	generation int64           // Generation last read or written
+	sum        [32]byte        // SHA-256 of the file as last read or written, to notice hand edits that keep the generation
+	base       map[string]bool // PRs this instance had at the last sync
+	stamp      fileStamp       // File as last read or written, to skip reading it unchanged
+	stale      bool            // The file has changes this instance hasn't taken yet
+	ui         UIState         // UI state as last read, or as set by SetUI
+	ownUI      bool            // ui was set by SetUI, so Save writes it
+}
+
+// fileStamp identifies a version of a file without reading it
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampOf returns the current stamp of a file
+func stampOf(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// NewStore creates a store for the saved builds file ("" = keep nothing)
+func NewStore(path string) *Store {
+	return &Store{path: path, base: map[string]bool{}}
+}
+
+// Load reads the saved builds
+func (s *Store) Load() ([]models.Build, error) {
+	if s.path == "" {
+		return []models.Build{}, nil
+	}
+	unlock, err := lock(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	saved, err := readFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.synced(saved, saved.Builds)
+	s.ui = saved.UI
+	return saved.Builds, nil
+}
+
+// UI returns the UI state read by Load
+func (s *Store) UI() UIState {
+	return s.ui
+}
+
+// SetUI sets the UI state written by the next Save
+// Until it is called, Save keeps the UI state already in the file
+func (s *Store) SetUI(ui UIState) {
+	s.ui = ui
+	s.ownUI = true
+}
+
+// Save writes this instance's builds, merged with changes another instance
+// saved since the last sync. The merged tiles are taken by the next Reload
+func (s *Store) Save(builds []models.Build) error {
+	if s.path == "" {
+		return nil
+	}
+	unlock, err := lock(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	saved, err := readFile(s.path)
+	if err != nil {
+		return err
+	}
+	next := savedFile{Generation: saved.Generation + 1, Builds: builds, UI: saved.UI, migrated: saved.migrated}
+	if s.ownUI {
+		next.UI = s.ui
+	}
+	conflict := s.stale || s.changed(saved)
+	if !conflict && s.unchanged(saved, next) {
+		s.synced(saved, builds)
+		return nil // Keep the generation, so other instances don't reload for nothing
+	}
+	if conflict {
+		next.Builds = Merge(s.base, builds, saved.Builds)
+	}
+	if err := writeFile(s.path, next); err != nil {
+		return err
+	}
+
+	s.generation = next.Generation
+	s.sum, _ = sumOf(s.path)
+	s.base = prSet(builds)
+	s.stamp, _ = stampOf(s.path)
+	s.stale = conflict && !samePRs(next.Builds, builds)
+	return nil
+}
+
+// Reload returns the builds merged with changes another instance saved since
+// the last sync, and whether there were any
+func (s *Store) Reload(builds []models.Build) ([]models.Build, bool, error) {
+	if s.path == "" {
+		return builds, false, nil
+	}
+	stamp, err := stampOf(s.path)
+	if os.IsNotExist(err) {
+		return builds, false, nil
+	}
+	if err != nil {
+		return builds, false, err
+	}
+	if stamp == s.stamp && !s.stale {
+		return builds, false, nil
+	}
+
+	unlock, err := lock(s.path)
+	if err != nil {
+		return builds, false, err
+	}
+	defer unlock()
+
+	saved, err := readFile(s.path)
+	if err != nil {
+		s.stamp = stamp // Don't retry a broken file until it changes again
+		return builds, false, err
+	}
+	if !s.changed(saved) && !s.stale {
+		s.stamp = stamp
+		return builds, false, nil
+	}
+
+	merged := Merge(s.base, builds, saved.Builds)
+	s.synced(saved, merged)
+	return merged, true, nil
+}
+
+// changed reports whether the file was written since this instance last read
+// or wrote it: by another instance (a new generation) or by hand
+func (s *Store) changed(saved savedFile) bool {
+	return saved.Generation != s.generation || saved.sum != s.sum
+}
+
+// unchanged reports whether writing next would leave the saved file as it is
+// (e.g., a poll that found nothing new)
+func (s *Store) unchanged(saved, next savedFile) bool {
+	next.Generation = saved.Generation
+	data, err := encode(next)
+	return err == nil && sha256.Sum256(data) == saved.sum
+}
+
+// synced records that this instance now has builds, and the file is saved
+// (caller holds the lock)
+func (s *Store) synced(saved savedFile, builds []models.Build) {
+	s.generation = saved.Generation
+	s.sum = saved.sum
+	s.base = prSet(builds)
+	s.stale = false
+	s.stamp, _ = stampOf(s.path)
+}
+
+// Merge combines this instance's builds (mine) with the saved builds another
+// instance wrote (theirs). base is the set of PRs this instance had at its
+// last sync: a PR only one side has was removed by the other side if it's in
+// base, and added by this side if not
+// Saved order is kept, with this instance's additions at the end. A PR on both
+// sides keeps this instance's build unless the other side has a newer one
+func Merge(base map[string]bool, mine, theirs []models.Build) []models.Build {
+	own := make(map[string]models.Build, len(mine))
+	for _, build := range mine {
+		own[build.PRNumber] = build
+	}
+
+	merged := make([]models.Build, 0, len(theirs)+len(mine))
+	seen := make(map[string]bool, len(theirs)+len(mine))
+	for _, build := range theirs {
+		if seen[build.PRNumber] {
+			continue
+		}
+		mineBuild, ok := own[build.PRNumber]
+		switch {
+		case ok && build.BuildNumber > mineBuild.BuildNumber:
+			merged = append(merged, build)
+		case ok:
+			merged = append(merged, mineBuild)
+		case base[build.PRNumber]:
+			continue // Removed by this instance
+		default:
+			merged = append(merged, build) // Added by the other instance
+		}
+		seen[build.PRNumber] = true
+	}
+
+	saved := prSet(theirs)
+	for _, build := range mine {
+		if seen[build.PRNumber] || (base[build.PRNumber] && !saved[build.PRNumber]) {
+			continue // Already merged, or removed by the other instance
+		}
+		merged = append(merged, build)
+		seen[build.PRNumber] = true
+	}
+	return merged
+}
+
+// Changes returns the PRs only after has (added) and only before has
+// (removed), each in list order
+func Changes(before, after []models.Build) (added, removed []string) {
+	had, has := prSet(before), prSet(after)
+	for _, build := range after {
+		if !had[build.PRNumber] {
+			added = append(added, build.PRNumber)
+		}
+	}
+	for _, build := range before {
+		if !has[build.PRNumber] {
+			removed = append(removed, build.PRNumber)
+		}
+	}
+	return added, removed
+}
+
+// prSet returns the PR numbers of builds
+func prSet(builds []models.Build) map[string]bool {
+	set := make(map[string]bool, len(builds))
+	for _, build := range builds {
+		set[build.PRNumber] = true
+	}
+	return set
+}
+
+// samePRs reports whether two lists have the same PRs, in any order
+func samePRs(a, b []models.Build) bool {
+	setA, setB := prSet(a), prSet(b)
+	if len(setA) != len(setB) {
+		return false
+	}
+	for pr := range setA {
+		if !setB[pr] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// prs returns the PR numbers of builds, in order
+func prs(builds []models.Build) string {
+	numbers := make([]string, len(builds))
+	for i, build := range builds {
+		numbers[i] = build.PRNumber
+	}
+	return strings.Join(numbers, ",")
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   []string
+		mine   []models.Build
+		theirs []models.Build
+		want   string
+	}{
+		{
+			name:   "they added a PR",
+			base:   []string{"1"},
+			mine:   []models.Build{{PRNumber: "1"}},
+			theirs: []models.Build{{PRNumber: "1"}, {PRNumber: "2"}},
+			want:   "1,2",
+		},
+		{
+			name:   "they removed a PR",
+			base:   []string{"1", "2"},
+			mine:   []models.Build{{PRNumber: "1"}, {PRNumber: "2"}},
+			theirs: []models.Build{{PRNumber: "2"}},
+			want:   "2",
+		},
+		{
+			name:   "I added a PR",
+			base:   []string{"1"},
+			mine:   []models.Build{{PRNumber: "1"}, {PRNumber: "3"}},
+			theirs: []models.Build{{PRNumber: "1"}, {PRNumber: "2"}},
+			want:   "1,2,3",
+		},
+		{
+			name:   "I removed a PR",
+			base:   []string{"1", "2"},
+			mine:   []models.Build{{PRNumber: "2"}},
+			theirs: []models.Build{{PRNumber: "1"}, {PRNumber: "2"}},
+			want:   "2",
+		},
+		{
+			name:   "both removed the same PR",
+			base:   []string{"1", "2"},
+			mine:   []models.Build{{PRNumber: "2"}},
+			theirs: []models.Build{{PRNumber: "2"}},
+			want:   "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := map[string]bool{}
+			for _, pr := range tt.base {
+				base[pr] = true
+			}
+			if got := prs(Merge(base, tt.mine, tt.theirs)); got != tt.want {
+				t.Errorf("Merge() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge_KeepsNewerBuild(t *testing.T) {
+	base := map[string]bool{"1": true, "2": true}
+	mine := []models.Build{{PRNumber: "1", BuildNumber: 5}, {PRNumber: "2", BuildNumber: 9}}
+	theirs := []models.Build{{PRNumber: "1", BuildNumber: 6}, {PRNumber: "2", BuildNumber: 8}}
+
+	merged := Merge(base, mine, theirs)
+	if merged[0].BuildNumber != 6 {
+		t.Errorf("PR-1 build = #%d, want their newer #6", merged[0].BuildNumber)
+	}
+	if merged[1].BuildNumber != 9 {
+		t.Errorf("PR-2 build = #%d, want my newer #9", merged[1].BuildNumber)
+	}
+}
+
+func TestStore_ConcurrentInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	if err := SaveBuilds(path, []models.Build{{PRNumber: "1"}}); err != nil {
+		t.Fatalf("SaveBuilds() error = %v", err)
+	}
+
+	a, b := NewStore(path), NewStore(path)
+	buildsA, err := a.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	buildsB, err := b.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Each instance adds a PR without seeing the other's
+	buildsA = append(buildsA, models.Build{PRNumber: "2"})
+	if err := a.Save(buildsA); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	buildsB = append(buildsB, models.Build{PRNumber: "3"})
+	if err := b.Save(buildsB); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	saved, err := LoadBuilds(path)
+	if err != nil {
+		t.Fatalf("LoadBuilds() error = %v", err)
+	}
+	if got := prs(saved); got != "1,2,3" {
+		t.Errorf("saved = %s, want 1,2,3", got)
+	}
+
+	// Both take the merged tiles on reload
+	for name, store := range map[string]*Store{"a": a, "b": b} {
+		builds := buildsA
+		if name == "b" {
+			builds = buildsB
+		}
+		merged, changed, err := store.Reload(builds)
+		if err != nil {
+			t.Fatalf("%s: Reload() error = %v", name, err)
+		}
+		if !changed || prs(merged) != "1,2,3" {
+			t.Errorf("%s: Reload() = %s, %v, want 1,2,3, true", name, prs(merged), changed)
+		}
+	}
+
+	// Nothing changed since
+	if _, changed, err := a.Reload(saved); err != nil || changed {
+		t.Errorf("Reload() after sync = %v, %v, want unchanged", changed, err)
+	}
+}
+
+func TestStore_ReloadTakesRemoval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	if err := SaveBuilds(path, []models.Build{{PRNumber: "1"}, {PRNumber: "2"}}); err != nil {
+		t.Fatalf("SaveBuilds() error = %v", err)
+	}
+
+	store := NewStore(path)
+	builds, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, err := RemovePRs(path, []string{"1"}); err != nil {
+		t.Fatalf("RemovePRs() error = %v", err)
+	}
+
+	merged, changed, err := store.Reload(builds)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !changed || prs(merged) != "2" {
+		t.Errorf("Reload() = %s, %v, want 2, true", prs(merged), changed)
+	}
+
+	// A later save doesn't bring the removed PR back
+	if err := store.Save(merged); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	saved, _ := LoadBuilds(path)
+	if got := prs(saved); got != "2" {
+		t.Errorf("saved = %s, want 2", got)
+	}
+}
+
+func TestStore_ReloadTakesHandEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	store := NewStore(path)
+	builds := []models.Build{{PRNumber: "1"}, {PRNumber: "2"}}
+	if err := store.Save(builds); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Edited by hand: PR 1 swapped for PR 3, generation untouched
+	edited := `{"version": 2, "generation": 1, "builds": [{"PRNumber": "2"}, {"PRNumber": "3"}], "ui": {}}`
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, changed, err := store.Reload(builds)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if !changed || prs(merged) != "2,3" {
+		t.Errorf("Reload() = %s, %v, want 2,3, true", prs(merged), changed)
+	}
+
+	// A save before the reload merges the edit instead of overwriting it
+	if err := os.WriteFile(path, []byte(strings.Replace(edited, `"3"`, `"4"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(merged); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	saved, _ := LoadBuilds(path)
+	if got := prs(saved); got != "2,4" {
+		t.Errorf("saved = %s, want 2,4", got)
+	}
+}
+
+func TestStore_SaveUnchangedKeepsGeneration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	store := NewStore(path)
+	builds := []models.Build{{PRNumber: "1", Status: models.StatusRunning}}
+	if err := store.Save(builds); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A poll that found nothing new doesn't write the file
+	if err := store.Save(builds); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved, _ := readFile(path); saved.Generation != 1 {
+		t.Errorf("generation after unchanged save = %d, want 1", saved.Generation)
+	}
+
+	builds[0].Status = models.StatusSuccess
+	if err := store.Save(builds); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved, _ := readFile(path); saved.Generation != 2 || saved.Builds[0].Status != models.StatusSuccess {
+		t.Errorf("saved = generation %d, %v, want generation 2, success", saved.Generation, saved.Builds[0].Status)
+	}
+}
+
+func TestLoadBuilds_LegacyArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	if err := os.WriteFile(path, []byte(`[{"PRNumber": "3859"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builds, err := LoadBuilds(path)
+	if err != nil {
+		t.Fatalf("LoadBuilds() error = %v", err)
+	}
+	if len(builds) != 1 || builds[0].PRNumber != "3859" {
+		t.Errorf("LoadBuilds() = %+v, want PR-3859", builds)
+	}
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	unlock, err := lock(path)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+
+	if _, err := lock(path); err == nil || !strings.Contains(err.Error(), "locked by another instance") {
+		t.Errorf("second lock() error = %v, want locked", err)
+	}
+
+	unlock()
+	unlock, err = lock(path)
+	if err != nil {
+		t.Fatalf("lock() after unlock error = %v", err)
+	}
+	unlock()
+}
+
+func TestLock_RemovesStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "builds.json")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lock(path)
+	if err != nil {
+		t.Fatalf("lock() error = %v, want stale lock removed", err)
+	}
+	unlock()
+}
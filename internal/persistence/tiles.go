@@ -0,0 +1,72 @@
+package persistence
+
+import (
+	"slices"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// NewTile returns the placeholder build shown for a PR until its first fetch
+func NewTile(prNumber string) models.Build {
+	return models.Build{
+		PRNumber: prNumber,
+		Status:   models.StatusPending,
+		Stage:    "Loading...",
+		JobName:  "Fetching data...",
+	}
+}
+
+// AddPRs appends tiles for the PRs that aren't saved yet, under the file lock
+// Returns the PRs that were added, in order
+func AddPRs(filePath string, prNumbers []string) ([]string, error) {
+	var added []string
+	_, err := update(filePath, func(builds []models.Build) []models.Build {
+		for _, prNumber := range prNumbers {
+			if indexOf(builds, prNumber) >= 0 {
+				continue
+			}
+			builds = append(builds, NewTile(prNumber))
+			added = append(added, prNumber)
+		}
+		return builds
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemovePRs removes the tiles of the given PRs, under the file lock
+// Returns the PRs that were removed, in order
+func RemovePRs(filePath string, prNumbers []string) ([]string, error) {
+	var removed []string
+	_, err := update(filePath, func(builds []models.Build) []models.Build {
+		for _, prNumber := range prNumbers {
+			if i := indexOf(builds, prNumber); i >= 0 {
+				builds = slices.Delete(builds, i, i+1)
+				removed = append(removed, prNumber)
+			}
+		}
+		return builds
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// ClearPRs removes every tile, under the file lock
+// Returns how many tiles were removed
+func ClearPRs(filePath string) (int, error) {
+	var removed int
+	_, err := update(filePath, func(builds []models.Build) []models.Build {
+		removed = len(builds)
+		return []models.Build{}
+	})
+	return removed, err
+}
+
+// indexOf returns the index of a PR's tile, or -1
+func indexOf(builds []models.Build, prNumber string) int {
+	return slices.IndexFunc(builds, func(b models.Build) bool { return b.PRNumber == prNumber })
+}
@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestAddRemovePRs(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "builds.json")
+	if err := SaveBuilds(testFile, []models.Build{{PRNumber: "3859", Status: models.StatusSuccess, BuildNumber: 42}}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	added, err := AddPRs(testFile, []string{"3934", "3859", "3935", "3934"})
+	if err != nil {
+		t.Fatalf("AddPRs() error = %v", err)
+	}
+	if !slices.Equal(added, []string{"3934", "3935"}) {
+		t.Errorf("Expected 3934 and 3935 to be added, got %v", added)
+	}
+
+	builds, _ := LoadBuilds(testFile)
+	if len(builds) != 3 || builds[0].BuildNumber != 42 || builds[1].PRNumber != "3934" || builds[1].Stage != "Loading..." {
+		t.Errorf("Unexpected saved builds %+v", builds)
+	}
+
+	removed, err := RemovePRs(testFile, []string{"3859", "1"})
+	if err != nil {
+		t.Fatalf("RemovePRs() error = %v", err)
+	}
+	if !slices.Equal(removed, []string{"3859"}) {
+		t.Errorf("Expected 3859 to be removed, got %v", removed)
+	}
+	builds, _ = LoadBuilds(testFile)
+	if len(builds) != 2 || builds[0].PRNumber != "3934" {
+		t.Errorf("Unexpected saved builds %+v", builds)
+	}
+}
+
+func TestAddPRs_CreatesFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "new", "builds.json")
+	if _, err := AddPRs(testFile, []string{"3934"}); err != nil {
+		t.Fatalf("AddPRs() error = %v", err)
+	}
+	if builds, _ := LoadBuilds(testFile); len(builds) != 1 {
+		t.Errorf("Expected 1 saved build, got %d", len(builds))
+	}
+}
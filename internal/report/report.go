@@ -0,0 +1,279 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Output formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// topN is how many stages and categories are listed
+const topN = 5
+
+// legacyUnclassified is the category old history records carry for failed
+// builds no rule matched, counted as classifier.Unclassified
+const legacyUnclassified = "Failed"
+
+// Count is a name with the number of failed builds it appeared in
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report summarizes pipeline health over a time window
+type Report struct {
+	Since                  time.Time `json:"since"`
+	Until                  time.Time `json:"until"`
+	Builds                 int       `json:"builds"` // Passed and failed; aborted builds aren't counted
+	Passed                 int       `json:"passed"`
+	Failed                 int       `json:"failed"`
+	Aborted                int       `json:"aborted"`
+	SuccessRate            float64   `json:"success_rate"` // 0-1, 0 if no builds
+	MeanTimeToGreenSeconds int       `json:"mean_time_to_green_seconds"`
+	Recoveries             int       `json:"recoveries"` // Red streaks that turned green
+	StillRed               int       `json:"still_red"`  // PRs whose latest build is red
+	MedianDurationSeconds  int       `json:"median_duration_seconds"`
+	TopFailingStages       []Count   `json:"top_failing_stages"`
+	TopFailureCategories   []Count   `json:"top_failure_categories"`
+}
+
+// Generate computes a report from history records for builds that finished in [since, until)
+// Aborted builds are only counted: they say nothing about the health of the
+// pipeline, so they don't count as failures or break a green streak
+func Generate(records []history.Record, since, until time.Time) Report {
+	report := Report{Since: since, Until: until}
+
+	var builds []history.Record
+	for _, record := range history.Finished(history.Latest(records)) {
+		finished := record.FinishedAt()
+		if finished.Before(since) || !finished.Before(until) {
+			continue
+		}
+		if record.Status == models.StatusAborted {
+			report.Aborted++
+			continue
+		}
+		builds = append(builds, record)
+	}
+
+	var durations []int
+	stages := make(map[string]int)
+	categories := make(map[string]int)
+	for _, build := range builds {
+		report.Builds++
+		durations = append(durations, build.DurationSeconds)
+		if build.Status == models.StatusSuccess {
+			report.Passed++
+			continue
+		}
+
+		report.Failed++
+		if stage := failedStage(build.Stages); stage != "" {
+			stages[stage]++
+		}
+		category := build.FailureCategory
+		if category == "" || category == legacyUnclassified {
+			category = classifier.Unclassified
+		}
+		categories[category]++
+	}
+
+	if report.Builds > 0 {
+		report.SuccessRate = float64(report.Passed) / float64(report.Builds)
+	}
+	report.MedianDurationSeconds = median(durations)
+	report.MeanTimeToGreenSeconds, report.Recoveries, report.StillRed = timeToGreen(builds)
+	report.TopFailingStages = top(stages)
+	report.TopFailureCategories = top(categories)
+	return report
+}
+
+// failedStage returns the stage a failed build broke in (the first failed, or
+// else the last stage that didn't succeed)
+func failedStage(stages []models.Stage) string {
+	var lastUnsuccessful string
+	for _, stage := range stages {
+		if strings.HasSuffix(stage.Name, ":") {
+			continue // Phase label
+		}
+		switch stage.Status {
+		case "FAILED":
+			return stage.Name
+		case "SUCCESS", "NOT_EXECUTED":
+		default:
+			lastUnsuccessful = stage.Name
+		}
+	}
+	return lastUnsuccessful
+}
+
+// timeToGreen measures red streaks per PR: the time from the first failure of a
+// streak to the next successful build. Returns the mean in seconds, the number
+// of streaks that recovered, and the number of PRs that are still red
+func timeToGreen(builds []history.Record) (meanSeconds, recoveries, stillRed int) {
+	var total time.Duration
+	for _, prBuilds := range history.ByPR(builds) {
+		var redSince time.Time
+		for _, build := range prBuilds { // Ordered by start time
+			switch {
+			case build.Status != models.StatusSuccess && redSince.IsZero():
+				redSince = build.FinishedAt()
+			case build.Status == models.StatusSuccess && !redSince.IsZero():
+				total += build.FinishedAt().Sub(redSince)
+				recoveries++
+				redSince = time.Time{}
+			}
+		}
+		if !redSince.IsZero() {
+			stillRed++
+		}
+	}
+
+	if recoveries == 0 {
+		return 0, 0, stillRed
+	}
+	return int(total.Seconds()) / recoveries, recoveries, stillRed
+}
+
+// median returns the median of the values (0 if empty)
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// top returns the most frequent names, most frequent first (ties by name)
+func top(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > topN {
+		result = result[:topN]
+	}
+	return result
+}
+
+// Render formats the report as text, markdown or JSON
+func Render(report Report, format string) (string, error) {
+	switch format {
+	case FormatText, "":
+		return report.Text(), nil
+	case FormatMarkdown, "md":
+		return report.Markdown(), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	default:
+		return "", fmt.Errorf("unknown format %q (use text, markdown or json)", format)
+	}
+}
+
+// Text renders the report for the terminal
+func (r Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pipeline health %s - %s\n\n", r.Since.Format("2006-01-02"), r.Until.Format("2006-01-02"))
+	fmt.Fprintf(&b, "  %-20s %d (%d passed, %d failed)%s\n", "Builds:", r.Builds, r.Passed, r.Failed, r.aborted())
+	fmt.Fprintf(&b, "  %-20s %s\n", "Success rate:", r.successRate())
+	fmt.Fprintf(&b, "  %-20s %s\n", "Mean time to green:", r.meanTimeToGreen())
+	fmt.Fprintf(&b, "  %-20s %s\n", "Median duration:", r.medianDuration())
+
+	writeCounts := func(title string, counts []Count) {
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		if len(counts) == 0 {
+			b.WriteString("  none\n")
+		}
+		for _, c := range counts {
+			fmt.Fprintf(&b, "  %4d  %s\n", c.Count, c.Name)
+		}
+	}
+	writeCounts("Top failing stages", r.TopFailingStages)
+	writeCounts("Top failure categories", r.TopFailureCategories)
+	return b.String()
+}
+
+// Markdown renders the report for pasting into docs and chat
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Pipeline health %s – %s\n\n", r.Since.Format("2006-01-02"), r.Until.Format("2006-01-02"))
+	b.WriteString("| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Builds | %d (%d passed, %d failed)%s |\n", r.Builds, r.Passed, r.Failed, r.aborted())
+	fmt.Fprintf(&b, "| Success rate | %s |\n", r.successRate())
+	fmt.Fprintf(&b, "| Mean time to green | %s |\n", r.meanTimeToGreen())
+	fmt.Fprintf(&b, "| Median duration | %s |\n", r.medianDuration())
+
+	writeCounts := func(title, column string, counts []Count) {
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		if len(counts) == 0 {
+			b.WriteString("None\n")
+			return
+		}
+		fmt.Fprintf(&b, "| %s | Failed builds |\n|---|---|\n", column)
+		for _, c := range counts {
+			fmt.Fprintf(&b, "| %s | %d |\n", strings.ReplaceAll(c.Name, "|", `\|`), c.Count)
+		}
+	}
+	writeCounts("Top failing stages", "Stage", r.TopFailingStages)
+	writeCounts("Top failure categories", "Category", r.TopFailureCategories)
+	return b.String()
+}
+
+func (r Report) aborted() string {
+	if r.Aborted == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d aborted", r.Aborted)
+}
+
+func (r Report) successRate() string {
+	if r.Builds == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", r.SuccessRate*100)
+}
+
+func (r Report) meanTimeToGreen() string {
+	text := "-"
+	if r.Recoveries > 0 {
+		text = fmt.Sprintf("%s (%d recoveries)", models.FormatSeconds(r.MeanTimeToGreenSeconds), r.Recoveries)
+	}
+	if r.StillRed > 0 {
+		text += fmt.Sprintf(", %d PR(s) still red", r.StillRed)
+	}
+	return text
+}
+
+func (r Report) medianDuration() string {
+	if r.Builds == 0 {
+		return "-"
+	}
+	return models.FormatSeconds(r.MedianDurationSeconds)
+}
@@ -0,0 +1,159 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+var reportStart = time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC)
+
+// record returns a finished build starting the given number of hours into the window
+func record(pr string, build int, status models.BuildStatus, startHour, durationMinutes int) history.Record {
+	start := reportStart.Add(time.Duration(startHour) * time.Hour)
+	return history.Record{
+		ObservedAt:      start.Add(time.Duration(durationMinutes) * time.Minute),
+		PRNumber:        pr,
+		BuildNumber:     build,
+		Status:          status,
+		Timestamp:       start.Unix(),
+		DurationSeconds: durationMinutes * 60,
+	}
+}
+
+func sampleRecords() []history.Record {
+	running := record("1", 1, models.StatusRunning, 0, 0)
+
+	failed := record("1", 1, models.StatusFailure, 0, 10)
+	failed.FailureCategory = "Test failure"
+	failed.Stages = []models.Stage{
+		{Name: "BUILD:", Status: "SUCCESS"},
+		{Name: "Compile", Status: "SUCCESS"},
+		{Name: "QAL Tests", Status: "FAILED"},
+	}
+
+	failedAgain := record("1", 2, models.StatusFailure, 1, 10)
+	failedAgain.Stages = []models.Stage{{Name: "QAL Tests", Status: "FAILED"}}
+
+	oom := record("2", 1, models.StatusFailure, 3, 20)
+	oom.FailureCategory = "Out of memory"
+	oom.Stages = []models.Stage{{Name: "Compile", Status: "ABORTED"}}
+
+	return []history.Record{
+		running,
+		failed,
+		failedAgain,
+		record("1", 3, models.StatusSuccess, 2, 30), // Green 2h20m after build 1 failed at 0h10m
+		oom,
+		record("3", 1, models.StatusSuccess, 4, 40),
+		record("3", 2, models.StatusSuccess, 24*30, 5), // Outside the window
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	r := Generate(sampleRecords(), reportStart, reportStart.Add(7*24*time.Hour))
+
+	if r.Builds != 5 || r.Passed != 2 || r.Failed != 3 {
+		t.Errorf("Expected 5 builds (2 passed, 3 failed), got %d (%d, %d)", r.Builds, r.Passed, r.Failed)
+	}
+	if r.SuccessRate != 0.4 {
+		t.Errorf("SuccessRate = %v, want 0.4", r.SuccessRate)
+	}
+	if r.Recoveries != 1 || r.MeanTimeToGreenSeconds != int((2*time.Hour+20*time.Minute).Seconds()) {
+		t.Errorf("Expected 1 recovery after 2h20m, got %d after %ds", r.Recoveries, r.MeanTimeToGreenSeconds)
+	}
+	if r.StillRed != 1 {
+		t.Errorf("Expected PR 2 to be still red, got %d", r.StillRed)
+	}
+	if r.MedianDurationSeconds != 20*60 {
+		t.Errorf("MedianDurationSeconds = %d, want 1200", r.MedianDurationSeconds)
+	}
+
+	wantStages := []Count{{Name: "QAL Tests", Count: 2}, {Name: "Compile", Count: 1}}
+	if len(r.TopFailingStages) != 2 || r.TopFailingStages[0] != wantStages[0] || r.TopFailingStages[1] != wantStages[1] {
+		t.Errorf("TopFailingStages = %v, want %v", r.TopFailingStages, wantStages)
+	}
+	if len(r.TopFailureCategories) != 3 || r.TopFailureCategories[0].Name != "Out of memory" {
+		t.Errorf("Expected categories with ties sorted by name, got %v", r.TopFailureCategories)
+	}
+}
+
+func TestGenerate_SkipsAbortedAndBucketsLegacyCategories(t *testing.T) {
+	legacy := record("1", 1, models.StatusFailure, 0, 10)
+	legacy.FailureCategory = "Failed" // Written before unmatched logs were "Unclassified"
+	unclassified := record("2", 1, models.StatusFailure, 1, 10)
+	unclassified.FailureCategory = "Unclassified"
+	records := []history.Record{
+		legacy,
+		unclassified,
+		record("1", 2, models.StatusAborted, 2, 1), // Doesn't end the red streak
+		record("1", 3, models.StatusSuccess, 3, 10),
+		record("3", 1, models.StatusAborted, 4, 2),
+	}
+
+	r := Generate(records, reportStart, reportStart.Add(24*time.Hour))
+	if r.Builds != 3 || r.Failed != 2 || r.Aborted != 2 {
+		t.Errorf("Expected 3 builds (2 failed) and 2 aborted, got %d (%d failed), %d aborted", r.Builds, r.Failed, r.Aborted)
+	}
+	if r.MeanTimeToGreenSeconds != int((3 * time.Hour).Seconds()) {
+		t.Errorf("Aborted build should not affect time to green, got %ds", r.MeanTimeToGreenSeconds)
+	}
+	if len(r.TopFailureCategories) != 1 || r.TopFailureCategories[0] != (Count{Name: "Unclassified", Count: 2}) {
+		t.Errorf("Expected legacy categories counted as Unclassified, got %v", r.TopFailureCategories)
+	}
+	if !strings.Contains(r.Text(), "3 (1 passed, 2 failed), 2 aborted") {
+		t.Errorf("Text report should count aborted builds apart:\n%s", r.Text())
+	}
+}
+
+func TestGenerate_Empty(t *testing.T) {
+	r := Generate(nil, reportStart, reportStart.Add(24*time.Hour))
+	if r.Builds != 0 || r.SuccessRate != 0 {
+		t.Errorf("Expected an empty report, got %+v", r)
+	}
+	if !strings.Contains(r.Text(), "Success rate:        -") {
+		t.Errorf("Empty report should show '-' for the success rate:\n%s", r.Text())
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := Generate(sampleRecords(), reportStart, reportStart.Add(7*24*time.Hour))
+
+	text, err := Render(r, FormatText)
+	if err != nil {
+		t.Fatalf("Render(text) error = %v", err)
+	}
+	for _, want := range []string{"Success rate:        40.0%", "Mean time to green:  2h 20m 0s (1 recoveries), 1 PR(s) still red", "     2  QAL Tests"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text report should contain %q:\n%s", want, text)
+		}
+	}
+
+	markdown, err := Render(r, FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Render(markdown) error = %v", err)
+	}
+	if !strings.Contains(markdown, "| Success rate | 40.0% |") || !strings.Contains(markdown, "| QAL Tests | 2 |") {
+		t.Errorf("Unexpected markdown report:\n%s", markdown)
+	}
+
+	data, err := Render(r, FormatJSON)
+	if err != nil {
+		t.Fatalf("Render(json) error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("JSON report should parse: %v", err)
+	}
+	if decoded.Builds != 5 || decoded.TopFailingStages[0].Name != "QAL Tests" {
+		t.Errorf("Unexpected JSON report %+v", decoded)
+	}
+
+	if _, err := Render(r, "html"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
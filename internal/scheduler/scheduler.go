@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Default polling intervals (can be overridden with environment variables)
+const (
+	defaultRunningInterval = 10 * time.Second
+	defaultRecentInterval  = 1 * time.Minute
+	defaultStaleInterval   = 10 * time.Minute // Still polled, so a new push to an old PR shows up
+	defaultRecentWindow    = 1 * time.Hour
+	defaultMaxBackoff      = 5 * time.Minute
+)
+
+// minBackoff is the first retry delay after an error when the base interval
+// is off, so errored tiles are still retried
+const minBackoff = 10 * time.Second
+
+// Config holds the polling cadence for each class of build
+type Config struct {
+	Running      time.Duration // Running and pending builds
+	Recent       time.Duration // Builds that finished within RecentWindow
+	Stale        time.Duration // Builds that finished before RecentWindow (0 = never poll, opt-out)
+	RecentWindow time.Duration // How long a finished build counts as "recent"
+	MaxBackoff   time.Duration // Upper bound for exponential backoff on errors
+}
+
+// DefaultConfig returns the built-in polling configuration
+func DefaultConfig() Config {
+	return Config{
+		Running:      defaultRunningInterval,
+		Recent:       defaultRecentInterval,
+		Stale:        defaultStaleInterval,
+		RecentWindow: defaultRecentWindow,
+		MaxBackoff:   defaultMaxBackoff,
+	}
+}
+
+// LoadConfig returns the polling configuration from environment variables,
+// falling back to DefaultConfig for anything unset or invalid
+func LoadConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Running = getDurationOrDefault("POLL_RUNNING_INTERVAL", cfg.Running)
+	cfg.Recent = getDurationOrDefault("POLL_RECENT_INTERVAL", cfg.Recent)
+	cfg.Stale = getDurationOrDefault("POLL_STALE_INTERVAL", cfg.Stale)
+	cfg.RecentWindow = getDurationOrDefault("POLL_RECENT_WINDOW", cfg.RecentWindow)
+	cfg.MaxBackoff = getDurationOrDefault("POLL_MAX_BACKOFF", cfg.MaxBackoff)
+	return cfg
+}
+
+// ParseInterval parses a polling interval such as "30s" or "5m"
+// "off", "never" and "0" all mean the build is not polled
+func ParseInterval(value string) (time.Duration, error) {
+	cleaned := strings.TrimSpace(strings.ToLower(value))
+	switch cleaned {
+	case "off", "never", "0":
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(cleaned)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("interval cannot be negative, got: %s", value)
+	}
+	return d, nil
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := ParseInterval(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// Interval returns how often the given build should be polled
+// Returns 0 if the build should not be polled at all
+func (c Config) Interval(build models.Build, now time.Time) time.Duration {
+	switch build.Status {
+	case models.StatusRunning, models.StatusPending, models.StatusError:
+		return c.Running
+	}
+
+	// Finished build - cadence depends on how long ago it completed
+	if build.Timestamp == 0 {
+		return c.Recent
+	}
+	finishedAt := time.Unix(build.Timestamp+int64(build.DurationSeconds), 0)
+	if now.Sub(finishedAt) <= c.RecentWindow {
+		return c.Recent
+	}
+	return c.Stale
+}
+
+// Backoff returns the delay after the given number of consecutive failures
+// Doubles the base interval (minBackoff if off) per failure, capped at MaxBackoff
+func (c Config) Backoff(base time.Duration, failures int) time.Duration {
+	if base <= 0 {
+		base = minBackoff
+	}
+	delay := base
+	for i := 0; i < failures; i++ {
+		delay *= 2
+		if c.MaxBackoff > 0 && delay >= c.MaxBackoff {
+			return c.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// tileState tracks the polling schedule of a single tile
+type tileState struct {
+	next     time.Time
+	interval time.Duration
+	failures int
+	inFlight bool
+}
+
+// Scheduler decides when each tracked PR is due for a refresh
+// Tiles are keyed by PR number so the schedule survives reordering and deletes
+type Scheduler struct {
+	mu     sync.Mutex
+	config Config
+	tiles  map[string]*tileState
+}
+
+// New creates a Scheduler with the given configuration
+func New(config Config) *Scheduler {
+	return &Scheduler{
+		config: config,
+		tiles:  make(map[string]*tileState),
+	}
+}
+
+// Config returns the scheduler's polling configuration
+func (s *Scheduler) Config() Config {
+	return s.config
+}
+
+// Due returns true if the PR should be fetched now
+// PRs that have never been scheduled are always due
+func (s *Scheduler) Due(prNumber string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tile, ok := s.tiles[prNumber]
+	if !ok {
+		return true
+	}
+	if tile.inFlight || tile.interval == 0 {
+		return false
+	}
+	return !now.Before(tile.next)
+}
+
+// Started marks a fetch for the PR as in flight
+func (s *Scheduler) Started(prNumber string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tile(prNumber).inFlight = true
+}
+
+// Finished records the outcome of a fetch and schedules the next one
+func (s *Scheduler) Finished(prNumber string, build models.Build, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tile := s.tile(prNumber)
+	tile.inFlight = false
+
+	if err != nil {
+		tile.failures++
+		tile.interval = s.config.Backoff(s.config.Running, tile.failures)
+	} else {
+		tile.failures = 0
+		tile.interval = s.config.Interval(build, now)
+	}
+	tile.next = now.Add(tile.interval)
+}
+
+// Forget drops the schedule for a PR (e.g., when its tile is deleted)
+func (s *Scheduler) Forget(prNumber string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tiles, prNumber)
+}
+
+// Cadence describes the current refresh schedule of a tile
+type Cadence struct {
+	Interval time.Duration // 0 = not polled
+	Next     time.Time
+	Failures int
+	InFlight bool
+	Known    bool // false until the first fetch completes
+}
+
+// Cadence returns the current refresh schedule for a PR
+func (s *Scheduler) Cadence(prNumber string) Cadence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tile, ok := s.tiles[prNumber]
+	if !ok {
+		return Cadence{}
+	}
+	return Cadence{
+		Interval: tile.interval,
+		Next:     tile.next,
+		Failures: tile.failures,
+		InFlight: tile.inFlight,
+		Known:    !tile.next.IsZero(),
+	}
+}
+
+// tile returns the state for a PR, creating it if needed (caller holds mu)
+func (s *Scheduler) tile(prNumber string) *tileState {
+	tile, ok := s.tiles[prNumber]
+	if !ok {
+		tile = &tileState{}
+		s.tiles[prNumber] = tile
+	}
+	return tile
+}
+
+// String returns a human-readable description of the cadence
+// Example: "every 10s (next in 4s)", "paused", "backing off 40s (3 errors)"
+func (c Cadence) String(now time.Time) string {
+	if c.InFlight {
+		return "fetching..."
+	}
+	if !c.Known {
+		return "not scheduled yet"
+	}
+	if c.Interval == 0 {
+		return "paused (stale build)"
+	}
+
+	next := c.Next.Sub(now).Round(time.Second)
+	if next < 0 {
+		next = 0
+	}
+	if c.Failures > 0 {
+		errorWord := "errors"
+		if c.Failures == 1 {
+			errorWord = "error"
+		}
+		return fmt.Sprintf("backing off %s (%d %s, next in %s)", c.Interval, c.Failures, errorWord, next)
+	}
+	return fmt.Sprintf("every %s (next in %s)", c.Interval, next)
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func testConfig() Config {
+	return Config{
+		Running:      10 * time.Second,
+		Recent:       1 * time.Minute,
+		Stale:        0,
+		RecentWindow: 1 * time.Hour,
+		MaxBackoff:   2 * time.Minute,
+	}
+}
+
+func TestConfig_Interval(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cfg := testConfig()
+
+	tests := []struct {
+		name  string
+		build models.Build
+		want  time.Duration
+	}{
+		{
+			name:  "running build polls fast",
+			build: models.Build{Status: models.StatusRunning, Timestamp: now.Unix() - 60},
+			want:  10 * time.Second,
+		},
+		{
+			name:  "pending build polls fast",
+			build: models.Build{Status: models.StatusPending},
+			want:  10 * time.Second,
+		},
+		{
+			name:  "recently finished build polls slowly",
+			build: models.Build{Status: models.StatusSuccess, Timestamp: now.Unix() - 600, DurationSeconds: 300},
+			want:  1 * time.Minute,
+		},
+		{
+			name:  "stale finished build is not polled",
+			build: models.Build{Status: models.StatusFailure, Timestamp: now.Unix() - 5*3600, DurationSeconds: 300},
+			want:  0,
+		},
+		{
+			name:  "finished build without timestamp counts as recent",
+			build: models.Build{Status: models.StatusSuccess},
+			want:  1 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.Interval(tt.build, now); got != tt.want {
+				t.Errorf("Interval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Backoff(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 10 * time.Second},
+		{failures: 1, want: 20 * time.Second},
+		{failures: 3, want: 80 * time.Second},
+		{failures: 4, want: 2 * time.Minute}, // Capped at MaxBackoff
+		{failures: 50, want: 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.Backoff(10*time.Second, tt.failures); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Backoff_RunningOff(t *testing.T) {
+	cfg := testConfig()
+	cfg.Running = 0
+
+	// Errored tiles keep being retried even if running builds aren't polled
+	if got := cfg.Backoff(cfg.Running, 0); got != minBackoff {
+		t.Errorf("Backoff(0, 0) = %v, want %v", got, minBackoff)
+	}
+	if got := cfg.Backoff(cfg.Running, 2); got != 4*minBackoff {
+		t.Errorf("Backoff(0, 2) = %v, want %v", got, 4*minBackoff)
+	}
+}
+
+func TestDefaultConfig_PollsStaleBuilds(t *testing.T) {
+	if cfg := DefaultConfig(); cfg.Stale <= 0 {
+		t.Errorf("Stale = %v, want a slow nonzero default so new pushes show up", cfg.Stale)
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30s", want: 30 * time.Second},
+		{input: " 5m ", want: 5 * time.Minute},
+		{input: "off", want: 0},
+		{input: "NEVER", want: 0},
+		{input: "0", want: 0},
+		{input: "-5s", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseInterval(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseInterval(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_FromEnv(t *testing.T) {
+	t.Setenv("POLL_RUNNING_INTERVAL", "5s")
+	t.Setenv("POLL_STALE_INTERVAL", "30m")
+	t.Setenv("POLL_RECENT_INTERVAL", "garbage")
+
+	cfg := LoadConfig()
+
+	if cfg.Running != 5*time.Second {
+		t.Errorf("Running = %v, want 5s", cfg.Running)
+	}
+	if cfg.Stale != 30*time.Minute {
+		t.Errorf("Stale = %v, want 30m", cfg.Stale)
+	}
+	if cfg.Recent != defaultRecentInterval {
+		t.Errorf("Invalid value should fall back to default, got %v", cfg.Recent)
+	}
+}
+
+func TestScheduler_DueLifecycle(t *testing.T) {
+	s := New(testConfig())
+	now := time.Unix(1700000000, 0)
+
+	if !s.Due("3934", now) {
+		t.Fatal("Unknown PR should be due immediately")
+	}
+
+	s.Started("3934")
+	if s.Due("3934", now) {
+		t.Error("PR with a fetch in flight should not be due")
+	}
+
+	running := models.Build{Status: models.StatusRunning, Timestamp: now.Unix()}
+	s.Finished("3934", running, nil, now)
+
+	if s.Due("3934", now.Add(5*time.Second)) {
+		t.Error("Running build should not be due before its interval elapses")
+	}
+	if !s.Due("3934", now.Add(10*time.Second)) {
+		t.Error("Running build should be due once its interval elapses")
+	}
+
+	s.Forget("3934")
+	if !s.Due("3934", now) {
+		t.Error("Forgotten PR should be due again")
+	}
+}
+
+func TestScheduler_StaleBuildNeverDue(t *testing.T) {
+	s := New(testConfig())
+	now := time.Unix(1700000000, 0)
+
+	stale := models.Build{Status: models.StatusSuccess, Timestamp: now.Unix() - 24*3600}
+	s.Started("3934")
+	s.Finished("3934", stale, nil, now)
+
+	if s.Due("3934", now.Add(24*time.Hour)) {
+		t.Error("Stale build should never be due when Stale interval is 0")
+	}
+	if got := s.Cadence("3934").String(now); !strings.Contains(got, "paused") {
+		t.Errorf("Cadence should report paused, got %q", got)
+	}
+}
+
+func TestScheduler_BacksOffOnErrors(t *testing.T) {
+	s := New(testConfig())
+	now := time.Unix(1700000000, 0)
+	fetchErr := errors.New("connection refused")
+
+	s.Finished("3934", models.Build{}, fetchErr, now)
+	if got := s.Cadence("3934").Interval; got != 20*time.Second {
+		t.Errorf("After 1 error interval = %v, want 20s", got)
+	}
+
+	s.Finished("3934", models.Build{}, fetchErr, now)
+	cadence := s.Cadence("3934")
+	if cadence.Interval != 40*time.Second || cadence.Failures != 2 {
+		t.Errorf("After 2 errors got interval=%v failures=%d, want 40s and 2", cadence.Interval, cadence.Failures)
+	}
+	if !strings.Contains(cadence.String(now), "backing off") {
+		t.Errorf("Cadence should report backoff, got %q", cadence.String(now))
+	}
+
+	// A successful fetch resets the backoff
+	s.Finished("3934", models.Build{Status: models.StatusRunning}, nil, now)
+	if got := s.Cadence("3934"); got.Failures != 0 || got.Interval != 10*time.Second {
+		t.Errorf("Success should reset backoff, got interval=%v failures=%d", got.Interval, got.Failures)
+	}
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/metrics"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+	"github.com/mpetters/jenkins-dash/internal/scheduler"
+	"github.com/mpetters/jenkins-dash/internal/tracker"
+)
+
+// Errors returned by Engine.Add and Engine.Remove
+var (
+	ErrExists   = errors.New("PR is already tracked")
+	ErrNotFound = errors.New("PR is not tracked")
+)
+
+// Client is the part of the Jenkins client the engine needs
+type Client interface {
+	fetch.Client
+	tracker.Client
+}
+
+// Engine polls the tracked PRs on the dashboard's schedule without a terminal
+// It is safe for concurrent use by HTTP handlers and its polling loop
+type Engine struct {
+	client    Client
+	gh        fetch.GitHub
+	store     *persistence.Store // Saved tiles, shared with the dashboard
+	scheduler *scheduler.Scheduler
+	tracker   *tracker.Tracker // Merges, analyzes and announces fetched builds (guarded by mu)
+	metrics   *metrics.Registry
+	logger    *log.Logger
+
+	mu       sync.RWMutex
+	state    *models.DashboardState
+	inFlight sync.WaitGroup // Background fetches, announcements, history writes and analyses
+}
+
+// NewEngine creates an engine that saves its tiles to configPath ("" = don't save)
+func NewEngine(client Client, gh fetch.GitHub, configPath string) *Engine {
+	return &Engine{
+		client:    client,
+		gh:        gh,
+		store:     persistence.NewStore(configPath),
+		scheduler: scheduler.New(scheduler.LoadConfig()),
+		tracker:   tracker.New(),
+		logger:    log.Default(),
+		state:     &models.DashboardState{Builds: []models.Build{}},
+	}
+}
+
+// SetFailureRules replaces the rules used to classify failed builds
+func (e *Engine) SetFailureRules(rules []classifier.Rule) {
+	e.tracker.SetFailureRules(rules)
+}
+
+// SetFlakyStore replaces the persistent list of known flaky tests
+func (e *Engine) SetFlakyStore(store *flaky.Store) {
+	e.tracker.SetFlakyStore(store)
+}
+
+// SetStageHistory replaces the stage duration history used for baselines
+func (e *Engine) SetStageHistory(history *analytics.StageHistory) {
+	e.tracker.SetStageHistory(history)
+}
+
+// SetBuildHistory replaces the store every observed build result is appended to
+func (e *Engine) SetBuildHistory(store *history.Store) {
+	e.tracker.SetBuildHistory(store)
+}
+
+// SetNotifier replaces the notifier used when builds finish
+func (e *Engine) SetNotifier(notifier *notify.Notifier) {
+	e.tracker.SetNotifier(notifier)
+}
+
+// SetWebhooks replaces the webhooks posted to when builds change status
+func (e *Engine) SetWebhooks(webhooks *notify.Webhooks) {
+	e.tracker.SetWebhooks(webhooks)
+}
+
+// SetHooks replaces the user-defined hook scripts run on build events
+func (e *Engine) SetHooks(hooks *notify.Hooks) {
+	e.tracker.SetHooks(hooks)
+}
+
+// SetMetrics sets the registry finished builds are recorded in (nil = no metrics)
+func (e *Engine) SetMetrics(registry *metrics.Registry) {
+	e.metrics = registry
+	e.tracker.SetMetrics(registry)
+}
+
+// Metrics returns the registry set with SetMetrics
+func (e *Engine) Metrics() *metrics.Registry {
+	return e.metrics
+}
+
+// SetLogger replaces the logger fetch and notification errors are written to
+func (e *Engine) SetLogger(logger *log.Logger) {
+	e.logger = logger
+}
+
+// Load reads the saved tiles
+func (e *Engine) Load() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	builds, err := e.store.Load()
+	if err != nil {
+		return err
+	}
+	e.state.Builds = builds
+	return nil
+}
+
+// Builds returns a copy of the tracked builds, in tile order
+func (e *Engine) Builds() []models.Build {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]models.Build(nil), e.state.Builds...)
+}
+
+// Build returns the tracked build of a PR
+func (e *Engine) Build(prNumber string) (models.Build, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if i := e.index(prNumber); i >= 0 {
+		return e.state.Builds[i], true
+	}
+	return models.Build{}, false
+}
+
+// Add starts tracking a PR; it is fetched on the next poll
+func (e *Engine) Add(prNumber string) (models.Build, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index(prNumber) >= 0 {
+		return models.Build{}, ErrExists
+	}
+
+	build := persistence.NewTile(prNumber)
+	e.state.AddBuild(build)
+	e.save()
+	return build, nil
+}
+
+// Remove stops tracking a PR
+func (e *Engine) Remove(prNumber string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.state.RemoveBuild(e.index(prNumber)) {
+		return ErrNotFound
+	}
+	e.scheduler.Forget(prNumber)
+	e.tracker.Forget(prNumber)
+	e.save()
+	return nil
+}
+
+// Run polls due PRs every second until ctx is canceled
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	e.Poll(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.Poll(now)
+		}
+	}
+}
+
+// Poll takes tiles other instances saved, then starts a fetch of every PR
+// whose polling interval has elapsed
+// Fetches run in the background and update the tile when they finish
+func (e *Engine) Poll(now time.Time) {
+	e.mu.Lock()
+	e.reload()
+	var due []models.Build
+	for _, build := range e.state.Builds {
+		if e.scheduler.Due(build.PRNumber, now) {
+			e.scheduler.Started(build.PRNumber)
+			due = append(due, build)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, build := range due {
+		e.inFlight.Add(1)
+		go func() {
+			defer e.inFlight.Done()
+			e.refresh(build)
+		}()
+	}
+}
+
+// Wait blocks until the fetches and classifications started so far finish,
+// so their results are saved (e.g., on shutdown)
+// Nothing may call Poll while it waits: call it after Run has returned
+func (e *Engine) Wait() {
+	e.inFlight.Wait()
+}
+
+// refresh fetches one PR and applies the result
+func (e *Engine) refresh(existing models.Build) {
+	fetched, err := fetch.Build(e.client, e.gh, existing.PRNumber, existing)
+	e.apply(existing.PRNumber, fetched, err)
+}
+
+// apply stores a fetch result in the PR's tile if it is still tracked, and
+// starts the announcements, history writes and analyses it calls for
+func (e *Engine) apply(prNumber string, fetched *models.Build, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	i := e.index(prNumber)
+	if i < 0 {
+		return // Removed while fetching
+	}
+
+	var outcome models.Build
+	if fetched != nil {
+		outcome = *fetched
+	}
+	now := time.Now()
+	e.scheduler.Finished(prNumber, outcome, err, now)
+
+	applied := e.tracker.Apply(e.state.Builds[i], fetched, err, now)
+	e.state.Builds[i] = applied.Build
+	if err != nil {
+		e.logger.Printf("PR-%s: %v", prNumber, err)
+	}
+	e.save()
+
+	for _, deliver := range applied.Notify {
+		if err := deliver(); err != nil {
+			e.logger.Print(err)
+		}
+	}
+	for _, task := range append(applied.Announce, applied.Persist...) {
+		e.inFlight.Add(1)
+		go func() {
+			defer e.inFlight.Done()
+			if err := task(); err != nil {
+				e.logger.Print(err)
+			}
+		}()
+	}
+	for _, analysis := range applied.Analyses {
+		e.inFlight.Add(1)
+		go func() {
+			defer e.inFlight.Done()
+			e.finish(analysis(e.client))
+		}()
+	}
+}
+
+// finish applies the result of a failure analysis to the tiles
+func (e *Engine) finish(result tracker.Result) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if message := e.tracker.Finish(e.state.Builds, result, time.Now()); message != "" {
+		e.logger.Print(message)
+	}
+	e.save()
+}
+
+// index returns the tile index of a PR, or -1 (caller holds mu)
+func (e *Engine) index(prNumber string) int {
+	for i, build := range e.state.Builds {
+		if build.PRNumber == prNumber {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the tiles to disk (caller holds mu)
+func (e *Engine) save() {
+	if err := e.store.Save(e.state.Builds); err != nil {
+		e.logger.Print(fmt.Errorf("saving builds: %w", err))
+	}
+}
+
+// reload takes the tiles other instances added or removed (caller holds mu)
+func (e *Engine) reload() {
+	merged, changed, err := e.store.Reload(e.state.Builds)
+	if err != nil {
+		e.logger.Print(fmt.Errorf("reloading builds: %w", err))
+		return
+	}
+	if !changed {
+		return
+	}
+
+	_, removed := persistence.Changes(e.state.Builds, merged)
+	for _, prNumber := range removed {
+		e.scheduler.Forget(prNumber)
+		e.tracker.Forget(prNumber)
+	}
+	e.state.Builds = merged
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+// mockClient returns a build per branch, or an error for unknown branches
+type mockClient struct {
+	mu     sync.Mutex
+	builds map[string]models.Build // Branch -> build
+	log    string
+}
+
+func (m *mockClient) set(branch string, build models.Build) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.builds[branch] = build
+}
+
+func (m *mockClient) GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	build, ok := m.builds[branch]
+	if !ok {
+		return nil, fmt.Errorf("HTTP 404: not found")
+	}
+	return &build, nil
+}
+
+func (m *mockClient) IsQueued(jobPath, branch string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockClient) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	return nil, nil
+}
+
+func (m *mockClient) GetConsoleLog(jobPath, branch string, buildNum int) (string, error) {
+	return m.log, nil
+}
+
+func (m *mockClient) GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error) {
+	return nil, nil
+}
+
+func newTestEngine(t *testing.T) (*Engine, *mockClient, string) {
+	t.Helper()
+	client := &mockClient{builds: map[string]models.Build{}}
+	path := filepath.Join(t.TempDir(), "builds.json")
+	engine := NewEngine(client, fetch.GitHub{}, path)
+	t.Cleanup(engine.Wait) // Before the temp dir is removed
+	return engine, client, path
+}
+
+func TestEngine_AddRemoveSaves(t *testing.T) {
+	engine, _, path := newTestEngine(t)
+
+	if _, err := engine.Add("3934"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, err := engine.Add("3934"); err != ErrExists {
+		t.Errorf("Adding a tracked PR: err = %v, want ErrExists", err)
+	}
+	engine.Add("4001")
+
+	saved, _ := persistence.LoadBuilds(path)
+	if len(saved) != 2 || saved[0].PRNumber != "3934" || saved[1].PRNumber != "4001" {
+		t.Fatalf("Saved builds = %+v", saved)
+	}
+
+	if err := engine.Remove("3934"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if err := engine.Remove("3934"); err != ErrNotFound {
+		t.Errorf("Removing an untracked PR: err = %v, want ErrNotFound", err)
+	}
+	saved, _ = persistence.LoadBuilds(path)
+	if len(saved) != 1 || saved[0].PRNumber != "4001" {
+		t.Errorf("Saved builds after remove = %+v", saved)
+	}
+
+	// A new engine picks the tiles up again
+	reloaded := NewEngine(&mockClient{}, fetch.GitHub{}, path)
+	if err := reloaded.Load(); err != nil || len(reloaded.Builds()) != 1 {
+		t.Errorf("Load() = %v, %d builds", err, len(reloaded.Builds()))
+	}
+}
+
+func TestEngine_ReloadsSavedTiles(t *testing.T) {
+	engine, _, path := newTestEngine(t)
+	engine.Add("3934")
+	engine.Add("4001")
+
+	// Another instance changes the file
+	if _, err := persistence.AddPRs(path, []string{"4100"}); err != nil {
+		t.Fatalf("AddPRs() error = %v", err)
+	}
+	if _, err := persistence.RemovePRs(path, []string{"3934"}); err != nil {
+		t.Fatalf("RemovePRs() error = %v", err)
+	}
+
+	engine.mu.Lock()
+	engine.reload()
+	engine.mu.Unlock()
+
+	builds := engine.Builds()
+	if len(builds) != 2 || builds[0].PRNumber != "4001" || builds[1].PRNumber != "4100" {
+		t.Errorf("Builds after reload = %+v, want PR-4001, PR-4100", builds)
+	}
+
+	// Saving keeps the other instance's changes
+	engine.Add("4200")
+	saved, _ := persistence.LoadBuilds(path)
+	if len(saved) != 3 || saved[0].PRNumber != "4001" || saved[2].PRNumber != "4200" {
+		t.Errorf("Saved builds = %+v", saved)
+	}
+}
+
+func TestEngine_Refresh(t *testing.T) {
+	engine, client, _ := newTestEngine(t)
+	engine.Add("3934")
+	existing, _ := engine.Build("3934")
+	existing.GitBranch = "feature/login"
+
+	// Fetch errors mark the tile and keep it
+	engine.refresh(existing)
+	build, _ := engine.Build("3934")
+	if build.Status != models.StatusError || build.ErrorMessage == "" {
+		t.Errorf("Expected an error tile, got %+v", build)
+	}
+
+	client.set("PR-3934", models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning})
+	engine.refresh(existing)
+	build, _ = engine.Build("3934")
+	if build.BuildNumber != 8 || build.Status != models.StatusRunning || build.GitBranch != "feature/login" {
+		t.Errorf("Expected running build #8 of feature/login, got %+v", build)
+	}
+	if cadence := engine.scheduler.Cadence("3934"); !cadence.Known || cadence.Failures != 0 {
+		t.Errorf("Expected the next poll to be scheduled, got %+v", cadence)
+	}
+
+	// Results for a PR removed while fetching are dropped
+	engine.Remove("3934")
+	engine.refresh(existing)
+	if _, ok := engine.Build("3934"); ok {
+		t.Error("A removed PR came back")
+	}
+}
+
+func TestEngine_ClassifiesFailures(t *testing.T) {
+	engine, client, _ := newTestEngine(t)
+	client.log = "java.lang.OutOfMemoryError: Java heap space"
+	engine.Add("3934")
+	existing, _ := engine.Build("3934")
+
+	client.set("PR-3934", models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusFailure})
+	engine.refresh(existing)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		build, _ := engine.Build("3934")
+		if build.FailureCategory != "" {
+			if build.FailureCategory != "Out of memory" {
+				t.Errorf("Expected the log to match the out of memory rule, got %q", build.FailureCategory)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Failed build was not classified")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/status"
+)
+
+// indexHTML is the web dashboard, which renders the grid from the JSON API
+//
+//go:embed index.html
+var indexHTML []byte
+
+// Detail is a build with its pipeline stages, as returned by GET /api/builds/{pr}
+type Detail struct {
+	status.Entry
+	Stages []Stage `json:"stages"`
+}
+
+// Stage is a pipeline stage in a Detail
+type Stage struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	StartMillis    int64  `json:"start_ms"`
+	DurationMillis int64  `json:"duration_ms"`
+	Depth          int    `json:"depth,omitempty"` // Nesting level in the stage graph
+}
+
+// NewDetail describes a build and its stages
+func NewDetail(build models.Build) Detail {
+	detail := Detail{Entry: status.NewEntry(fetch.Result{PRNumber: build.PRNumber, Build: &build}), Stages: []Stage{}}
+	if build.Status == models.StatusError {
+		detail.Error = build.ErrorMessage
+	}
+	for _, stage := range build.Stages {
+		detail.Stages = append(detail.Stages, Stage{
+			Name:           stage.Name,
+			Status:         stage.Status,
+			StartMillis:    stage.StartMillis,
+			DurationMillis: stage.DurationMillis,
+			Depth:          stage.Depth,
+		})
+	}
+	return detail
+}
+
+// addRequest is the body of POST /api/builds
+type addRequest struct {
+	PR string `json:"pr"` // "3934" or "PR-3934"
+}
+
+// NewHandler returns the HTTP API and web dashboard for an engine
+// If token is set, requests that add or remove PRs must send it as a bearer token
+//
+//	GET    /                  Web dashboard
+//	GET    /api/builds        All tiles, in order
+//	GET    /api/builds/{pr}   One build with its stages
+//	POST   /api/builds        Track a PR: {"pr": "3934"}
+//	DELETE /api/builds/{pr}   Stop tracking a PR
+//	GET    /metrics           Prometheus metrics, if the engine has a registry
+func NewHandler(engine *Engine, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+	})
+
+	mux.HandleFunc("GET /api/builds", func(w http.ResponseWriter, r *http.Request) {
+		builds := engine.Builds()
+		entries := make([]status.Entry, 0, len(builds))
+		for _, build := range builds {
+			entries = append(entries, NewDetail(build).Entry)
+		}
+		writeJSON(w, http.StatusOK, entries)
+	})
+
+	mux.HandleFunc("GET /api/builds/{pr}", func(w http.ResponseWriter, r *http.Request) {
+		prNumber, err := jenkins.ParsePRNumber(r.PathValue("pr"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		build, ok := engine.Build(prNumber)
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, NewDetail(build))
+	})
+
+	mux.HandleFunc("POST /api/builds", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		var req addRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(`expected {"pr": "<number>"}`))
+			return
+		}
+		prNumber, err := jenkins.ParsePRNumber(req.PR)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		build, err := engine.Add(prNumber)
+		if errors.Is(err, ErrExists) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		engine.Poll(time.Now()) // Fetch it now rather than on the next tick
+		writeJSON(w, http.StatusCreated, NewDetail(build).Entry)
+	}))
+
+	mux.HandleFunc("DELETE /api/builds/{pr}", requireToken(token, func(w http.ResponseWriter, r *http.Request) {
+		prNumber, err := jenkins.ParsePRNumber(r.PathValue("pr"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := engine.Remove(prNumber); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	if registry := engine.Metrics(); registry != nil {
+		mux.Handle("GET /metrics", registry.Handler(engine.Builds))
+	}
+
+	return mux
+}
+
+// requireToken rejects requests without the bearer token, if one is configured
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	if token == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes {"error": "..."}
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
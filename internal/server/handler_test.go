@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/metrics"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/status"
+)
+
+func do(t *testing.T, handler http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_API(t *testing.T) {
+	engine, client, _ := newTestEngine(t)
+	client.set("PR-3934", models.Build{
+		PRNumber:    "3934",
+		BuildNumber: 8,
+		Status:      models.StatusRunning,
+		Stage:       "Build",
+		Stages:      []models.Stage{{Name: "Checkout", Status: "SUCCESS"}, {Name: "Build", Status: "IN_PROGRESS"}},
+	})
+	handler := NewHandler(engine, "")
+
+	if rec := do(t, handler, "GET", "/api/builds", "", ""); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Empty list: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "PR-3934"}`, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("Add: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "3934"}`, ""); rec.Code != http.StatusConflict {
+		t.Errorf("Duplicate add: %d", rec.Code)
+	}
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "abc"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Invalid PR: %d", rec.Code)
+	}
+
+	existing, _ := engine.Build("3934")
+	engine.refresh(existing)
+
+	rec := do(t, handler, "GET", "/api/builds", "", "")
+	var entries []status.Entry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || len(entries) != 1 || entries[0].Status != "running" {
+		t.Errorf("List: %v %s", err, rec.Body.String())
+	}
+
+	rec = do(t, handler, "GET", "/api/builds/PR-3934", "", "")
+	var detail Detail
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil || detail.BuildNumber != 8 || len(detail.Stages) != 2 {
+		t.Errorf("Get: %v %s", err, rec.Body.String())
+	}
+	if rec := do(t, handler, "GET", "/api/builds/1", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Get untracked: %d", rec.Code)
+	}
+
+	if rec := do(t, handler, "DELETE", "/api/builds/3934", "", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Delete: %d", rec.Code)
+	}
+	if rec := do(t, handler, "DELETE", "/api/builds/3934", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Delete untracked: %d", rec.Code)
+	}
+
+	rec = do(t, handler, "GET", "/", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "api/builds") {
+		t.Errorf("Dashboard page: %d", rec.Code)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	engine, _, _ := newTestEngine(t)
+	handler := NewHandler(engine, "s3cret")
+
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "3934"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Add without token: %d", rec.Code)
+	}
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "3934"}`, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Add with wrong token: %d", rec.Code)
+	}
+	if rec := do(t, handler, "POST", "/api/builds", `{"pr": "3934"}`, "s3cret"); rec.Code != http.StatusCreated {
+		t.Errorf("Add with token: %d", rec.Code)
+	}
+	// Reading needs no token, so a TV can show the page
+	if rec := do(t, handler, "GET", "/api/builds", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("List without token: %d", rec.Code)
+	}
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	engine, client, _ := newTestEngine(t)
+	if rec := do(t, NewHandler(engine, ""), "GET", "/metrics", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Metrics without a registry: %d", rec.Code)
+	}
+
+	engine.SetMetrics(metrics.New())
+	handler := NewHandler(engine, "")
+	engine.Add("3934")
+	existing, _ := engine.Build("3934")
+	client.set("PR-3934", models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning})
+	engine.refresh(existing)
+	running, _ := engine.Build("3934")
+	client.set("PR-3934", models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusSuccess, DurationSeconds: 400})
+	engine.refresh(running)
+
+	rec := do(t, handler, "GET", "/metrics", "", "")
+	for _, want := range []string{
+		`jenkins_dash_build_status{pr="3934",status="success"} 1`,
+		`jenkins_dash_build_duration_seconds_count{result="success"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Metrics are missing %q:\n%s", want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Output formats
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// Entry is the status of one PR's latest build, as printed by the status subcommand
+type Entry struct {
+	PRNumber        string    `json:"pr"`
+	BuildNumber     int       `json:"build"`
+	Status          string    `json:"status"` // pending, running, success, failure, error, aborted
+	Stage           string    `json:"stage,omitempty"`
+	Job             string    `json:"job,omitempty"`
+	GitBranch       string    `json:"branch,omitempty"`
+	Author          string    `json:"author,omitempty"`
+	Checks          string    `json:"checks,omitempty"`
+	DurationSeconds int       `json:"duration_s"`
+	StartedAt       time.Time `json:"started_at,omitzero"`
+	SHA             string    `json:"sha,omitempty"`
+	Stale           bool      `json:"stale,omitempty"`        // Built commit is behind the PR head
+	NewerQueued     bool      `json:"newer_queued,omitempty"` // A build of the PR head is queued
+	FailureCategory string    `json:"failure_category,omitempty"`
+	BuildURL        string    `json:"build_url,omitempty"`
+	PRURL           string    `json:"pr_url,omitempty"`
+	Error           string    `json:"error,omitempty"` // Why the build couldn't be fetched
+}
+
+// NewEntry describes a fetched build, or the error fetching it
+func NewEntry(result fetch.Result) Entry {
+	if result.Err != nil || result.Build == nil {
+		entry := Entry{PRNumber: result.PRNumber, Status: models.StatusError.String()}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		return entry
+	}
+
+	build := *result.Build
+	entry := Entry{
+		PRNumber:        build.PRNumber,
+		BuildNumber:     build.BuildNumber,
+		Status:          build.Status.String(),
+		Stage:           build.Stage,
+		Job:             build.JobName,
+		GitBranch:       build.GitBranch,
+		Author:          build.PRAuthor,
+		Checks:          build.PRCheckStatus,
+		DurationSeconds: build.GetCurrentDuration(),
+		SHA:             build.BuiltSHA,
+		Stale:           build.IsStale(),
+		NewerQueued:     build.NewerQueued,
+		FailureCategory: build.FailureCategory,
+		BuildURL:        build.BuildURL,
+		PRURL:           build.PRURL,
+	}
+	if build.Timestamp > 0 {
+		entry.StartedAt = time.Unix(build.Timestamp, 0).UTC()
+	}
+	return entry
+}
+
+// Render writes the entries as a table or JSON
+func Render(w io.Writer, entries []Entry, format string) error {
+	switch format {
+	case FormatTable, "":
+		return RenderTable(w, entries)
+	case FormatJSON:
+		if entries == nil {
+			entries = []Entry{} // "[]" rather than "null" for scripts
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	default:
+		return fmt.Errorf("unknown format %q (use table or json)", format)
+	}
+}
+
+// RenderTable writes one aligned row per PR
+func RenderTable(w io.Writer, entries []Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PR\tBUILD\tSTATUS\tSTAGE\tDURATION\tBRANCH\tCHECKS")
+	for _, e := range entries {
+		if e.Error != "" {
+			fmt.Fprintf(tw, "PR-%s\t-\t%s\t%s\t-\t-\t-\n", e.PRNumber, e.Status, e.Error)
+			continue
+		}
+
+		stage := e.Stage
+		if e.FailureCategory != "" {
+			stage = e.FailureCategory
+		}
+		if e.Stale {
+			stage += " (stale)"
+		}
+		fmt.Fprintf(tw, "PR-%s\t#%d\t%s\t%s\t%s\t%s\t%s\n",
+			e.PRNumber, e.BuildNumber, e.Status, orDash(stage), models.FormatSeconds(e.DurationSeconds), orDash(e.GitBranch), orDash(e.Checks))
+	}
+	return tw.Flush()
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func sampleResults() []fetch.Result {
+	return []fetch.Result{
+		{PRNumber: "3934", Build: &models.Build{
+			PRNumber:        "3934",
+			BuildNumber:     8,
+			Status:          models.StatusFailure,
+			Stage:           "Failed",
+			FailureCategory: "Out of memory",
+			GitBranch:       "feature/login",
+			PRCheckStatus:   "5/8 checks",
+			DurationSeconds: 125,
+			Timestamp:       1762541100,
+		}},
+		{PRNumber: "4001", Err: errors.New("HTTP 404: not found")},
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	var entries []Entry
+	for _, result := range sampleResults() {
+		entries = append(entries, NewEntry(result))
+	}
+
+	var out strings.Builder
+	if err := Render(&out, entries, FormatTable); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "PR-3934 #8 failure Out of memory 2m 5s feature/login 5/8 checks" {
+		t.Errorf("Row = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "error") || !strings.Contains(lines[2], "HTTP 404") {
+		t.Errorf("Failed fetch row = %q", lines[2])
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var entries []Entry
+	for _, result := range sampleResults() {
+		entries = append(entries, NewEntry(result))
+	}
+
+	var out strings.Builder
+	if err := Render(&out, entries, FormatJSON); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, out.String())
+	}
+	if decoded[0]["status"] != "failure" || decoded[0]["started_at"] != "2025-11-07T18:45:00Z" || decoded[0]["duration_s"] != 125.0 {
+		t.Errorf("Unexpected entry %v", decoded[0])
+	}
+	if decoded[1]["error"] != "HTTP 404: not found" || decoded[1]["started_at"] != nil {
+		t.Errorf("Unexpected error entry %v", decoded[1])
+	}
+
+	out.Reset()
+	Render(&out, nil, FormatJSON)
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("No entries should render as [], got %q", out.String())
+	}
+	if err := Render(&out, nil, "yaml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,127 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// DefaultWaitInterval is how often Wait polls Jenkins
+const DefaultWaitInterval = 15 * time.Second
+
+// maxWaitErrors is how many transient errors in a row Wait tolerates
+const maxWaitErrors = 5
+
+// ErrWaitTimeout is returned when the build doesn't finish before the timeout
+var ErrWaitTimeout = errors.New("timed out waiting for the build")
+
+// WaitOptions configures Wait
+type WaitOptions struct {
+	Interval time.Duration // Poll interval, DefaultWaitInterval if zero
+	Timeout  time.Duration // Give up after this long, zero = wait forever
+	Progress io.Writer     // Receives a line whenever the build, status or stage changes, nil = quiet
+}
+
+// Wait polls a PR until its latest build has finished, built the PR head (when
+// GitHub is configured) and no newer build is queued, then returns that build
+// A PR without a build yet is waited for like a queued one; transient Jenkins
+// errors (timeouts, 5xx, offline) are retried; other errors are returned
+// immediately
+func Wait(client fetch.Client, gh fetch.GitHub, prNumber string, opts WaitOptions) (*models.Build, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWaitInterval
+	}
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+
+	jobPath := jenkins.InferJobPath(prNumber)
+	branch := "PR-" + prNumber
+	lastLine := ""
+	announced := "" // Why a finished build isn't the answer yet, once per build
+	errorsInARow := 0
+	var last models.Build // Keeps the PR info from GitHub between polls
+
+	for {
+		build, err := fetch.Build(client, gh, prNumber, last)
+		if err == nil {
+			errorsInARow = 0
+			last = *build
+			if line := progressLine(*build); line != lastLine {
+				progress(opts.Progress, line)
+				lastLine = line
+				announced = ""
+			}
+			if finished(*build) {
+				// The queue check is best effort: if it fails, the finished build is the answer
+				var reason string
+				if build.IsStale() {
+					reason = fmt.Sprintf("PR-%s: waiting for a build of %s", prNumber, models.ShortSHA(build.PRHeadSHA))
+				} else if queued, qErr := client.IsQueued(jobPath, branch); qErr == nil && queued {
+					reason = fmt.Sprintf("PR-%s: a newer build is queued", prNumber)
+				} else {
+					return build, nil
+				}
+				if reason != announced {
+					progress(opts.Progress, reason)
+					announced = reason
+				}
+			}
+		} else if jenkins.ErrorKindOf(err) == models.ErrorNotFound {
+			errorsInARow = 0
+			if line := fmt.Sprintf("PR-%s: no build yet", prNumber); line != lastLine {
+				progress(opts.Progress, line)
+				lastLine = line
+			}
+		} else {
+			if !jenkins.ErrorKindOf(err).Transient() {
+				return nil, err
+			}
+			errorsInARow++
+			if errorsInARow >= maxWaitErrors {
+				return nil, fmt.Errorf("giving up after %d failed polls: %w", errorsInARow, err)
+			}
+			progress(opts.Progress, fmt.Sprintf("PR-%s: %v (retrying)", prNumber, err))
+		}
+
+		sleep := opts.Interval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, ErrWaitTimeout
+			}
+			sleep = min(sleep, remaining)
+		}
+		time.Sleep(sleep)
+	}
+}
+
+// finished reports whether a build has a final result
+func finished(build models.Build) bool {
+	return build.Status != models.StatusRunning && build.Status != models.StatusPending
+}
+
+// progressLine describes a build's state (e.g., "PR-3934 #8 running: Build")
+func progressLine(build models.Build) string {
+	line := fmt.Sprintf("PR-%s #%d %s", build.PRNumber, build.BuildNumber, build.Status)
+	if build.Stage != "" {
+		line += ": " + build.Stage
+	}
+	if finished(build) {
+		line += " in " + build.FormatDuration()
+	}
+	return line
+}
+
+// progress writes a progress line if progress output is enabled
+func progress(w io.Writer, line string) {
+	if w != nil {
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,132 @@
+package status
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// pollResult is one scripted response of sequenceClient
+type pollResult struct {
+	build  models.Build
+	err    error
+	queued bool
+}
+
+// sequenceClient returns scripted poll results in order, repeating the last one
+type sequenceClient struct {
+	polls []pollResult
+	calls int
+}
+
+func (c *sequenceClient) current() pollResult {
+	return c.polls[min(c.calls, len(c.polls))-1]
+}
+
+func (c *sequenceClient) GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error) {
+	c.calls++
+	poll := c.current()
+	if poll.err != nil {
+		return nil, poll.err
+	}
+	build := poll.build
+	return &build, nil
+}
+
+func (c *sequenceClient) IsQueued(jobPath, branch string) (bool, error) {
+	return c.current().queued, nil
+}
+
+func (c *sequenceClient) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	return nil, nil
+}
+
+func TestWait_UntilFinished(t *testing.T) {
+	previous := models.Build{PRNumber: "3934", BuildNumber: 7, Status: models.StatusSuccess}
+	client := &sequenceClient{polls: []pollResult{
+		{build: previous, queued: true}, // Old build finished, new one queued
+		{build: models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning, Stage: "Build"}},
+		{err: &jenkins.APIError{Kind: models.ErrorServer, StatusCode: 502}},
+		{build: models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusRunning, Stage: "Build"}},
+		{build: models.Build{PRNumber: "3934", BuildNumber: 8, Status: models.StatusFailure, Stage: "Failed", DurationSeconds: 65}},
+	}}
+
+	var progress strings.Builder
+	build, err := Wait(client, fetch.GitHub{}, "3934", WaitOptions{Interval: time.Millisecond, Progress: &progress})
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if build.BuildNumber != 8 || build.Status != models.StatusFailure || client.calls != 5 {
+		t.Errorf("Got build #%d %s after %d polls", build.BuildNumber, build.Status, client.calls)
+	}
+
+	want := []string{
+		"PR-3934 #7 success in 0s",
+		"PR-3934: a newer build is queued",
+		"PR-3934 #8 running: Build",
+		"(retrying)",
+		"PR-3934 #8 failure: Failed in 1m 5s",
+	}
+	lines := strings.Split(strings.TrimSpace(progress.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Progress =\n%s", progress.String())
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("Progress line %d = %q, want %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestWait_Errors(t *testing.T) {
+	// Non-transient errors end the wait at once
+	client := &sequenceClient{polls: []pollResult{{err: &jenkins.APIError{Kind: models.ErrorAuth, StatusCode: 401}}}}
+	if _, err := Wait(client, fetch.GitHub{}, "1", WaitOptions{Interval: time.Millisecond}); err == nil || client.calls != 1 {
+		t.Errorf("Expected an auth error after 1 poll, got %v after %d", err, client.calls)
+	}
+
+	// Transient errors are retried a few times
+	client = &sequenceClient{polls: []pollResult{{err: &jenkins.APIError{Kind: models.ErrorServer, StatusCode: 503}}}}
+	if _, err := Wait(client, fetch.GitHub{}, "1", WaitOptions{Interval: time.Millisecond}); err == nil || client.calls != maxWaitErrors {
+		t.Errorf("Expected to give up after %d polls, got %v after %d", maxWaitErrors, err, client.calls)
+	}
+
+	client = &sequenceClient{polls: []pollResult{{build: models.Build{Status: models.StatusRunning}}}}
+	if _, err := Wait(client, fetch.GitHub{}, "1", WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond}); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+func TestWait_ForBuildOfPRHead(t *testing.T) {
+	client := &sequenceClient{polls: []pollResult{
+		{err: &jenkins.APIError{Kind: models.ErrorNotFound, StatusCode: 404}}, // PR just opened
+		{build: models.Build{PRNumber: "3934", BuildNumber: 1, Status: models.StatusSuccess, BuiltSHA: "aaa1111", PRHeadSHA: "bbb2222"}},
+		{build: models.Build{PRNumber: "3934", BuildNumber: 1, Status: models.StatusSuccess, BuiltSHA: "aaa1111", PRHeadSHA: "bbb2222"}},
+		{build: models.Build{PRNumber: "3934", BuildNumber: 2, Status: models.StatusSuccess, BuiltSHA: "bbb2222", PRHeadSHA: "bbb2222"}},
+	}}
+
+	var progress strings.Builder
+	build, err := Wait(client, fetch.GitHub{}, "3934", WaitOptions{Interval: time.Millisecond, Progress: &progress})
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if build.BuildNumber != 2 || client.calls != 4 {
+		t.Errorf("Expected build #2 of the PR head after 4 polls, got #%d after %d", build.BuildNumber, client.calls)
+	}
+	for _, want := range []string{"PR-3934: no build yet", "PR-3934: waiting for a build of bbb2222"} {
+		if strings.Count(progress.String(), want) != 1 {
+			t.Errorf("Progress should say %q once:\n%s", want, progress.String())
+		}
+	}
+
+	// Without a build, the wait ends at the timeout
+	client = &sequenceClient{polls: []pollResult{{err: &jenkins.APIError{Kind: models.ErrorNotFound, StatusCode: 404}}}}
+	if _, err := Wait(client, fetch.GitHub{}, "1", WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond}); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
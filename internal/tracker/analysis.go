@@ -0,0 +1,161 @@
+package tracker
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// Analysis kinds, tracked so an analysis doesn't run twice at once
+const (
+	analysisClassify  = "classify"
+	analysisFlakyScan = "flaky"
+)
+
+// maxCachedReports bounds the test report cache
+const maxCachedReports = 512
+
+// Analysis is a slow look at a failed build that runs in the background
+// It only reads from Jenkins; its result is applied with Tracker.Finish
+type Analysis func(client Client) Result
+
+// Result is what an analysis found out about a build
+type Result struct {
+	PRNumber    string
+	BuildNumber int
+	kind        string // Analysis kind, tracked while it runs
+	err         error  // The analysis failed and is retried later
+
+	classified bool
+	match      classifier.Match // Root cause from the console log
+
+	scanned    bool
+	failed     []string // Tests that failed in the build
+	detections []flaky.Detection
+}
+
+// classify returns the analysis that labels a failed build with its root cause
+// from the console log
+func classify(build models.Build, rules []classifier.Rule) Analysis {
+	return func(client Client) Result {
+		result := Result{PRNumber: build.PRNumber, BuildNumber: build.BuildNumber, kind: analysisClassify}
+		log, err := client.GetConsoleLog(jenkins.JobPathOf(build), "PR-"+build.PRNumber, build.BuildNumber)
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		match, ok := classifier.Classify(log, rules)
+		if !ok {
+			match = classifier.Match{Category: classifier.Unclassified}
+		}
+		result.classified = true
+		result.match = match
+		return result
+	}
+}
+
+// scanFlakyTests returns the analysis that fetches the test reports of recent
+// PR and main builds and looks for flaky tests, along with the failed tests of
+// the build
+func scanFlakyTests(build models.Build, cfg flaky.Config, reports *testReports) Analysis {
+	return func(client Client) Result {
+		result := Result{PRNumber: build.PRNumber, BuildNumber: build.BuildNumber, kind: analysisFlakyScan}
+		jobPath := jenkins.JobPathOf(build)
+		branch := "PR-" + build.PRNumber
+
+		runs, err := fetchTestRuns(client, reports, jobPath, branch, cfg.HistoryBuilds)
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		found := false
+		for _, run := range runs {
+			if run.BuildNumber == build.BuildNumber {
+				result.failed = flaky.FailedTests(run.Results)
+				found = true
+			}
+		}
+		if !found {
+			results, err := reports.get(client, jobPath, branch, build.BuildNumber)
+			if err != nil {
+				result.err = err
+				return result
+			}
+			result.failed = flaky.FailedTests(results)
+		}
+
+		result.detections = flaky.Detect(runs)
+		if cfg.MainBranch != "" {
+			if mainRuns, err := fetchTestRuns(client, reports, jobPath, cfg.MainBranch, cfg.HistoryBuilds); err == nil {
+				result.detections = append(result.detections, flaky.Detect(mainRuns)...)
+			}
+		}
+		result.scanned = true
+		return result
+	}
+}
+
+// fetchTestRuns returns the test results of the recent completed builds of a branch
+// Builds without a test report (or whose report can't be fetched) are skipped
+func fetchTestRuns(client Client, reports *testReports, jobPath, branch string, limit int) ([]flaky.Run, error) {
+	builds, err := client.ListBuilds(jobPath, branch, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []flaky.Run
+	for _, build := range builds {
+		if build.IsRunning() || build.Status == models.StatusPending {
+			continue
+		}
+		results, err := reports.get(client, jobPath, branch, build.BuildNumber)
+		if err != nil || len(results) == 0 {
+			continue
+		}
+		runs = append(runs, flaky.NewRun(build, branch, results))
+	}
+	return runs, nil
+}
+
+// testReports caches the test results of completed builds, which never change,
+// so a flaky test scan only fetches the reports of builds no scan has seen
+// Scans run in the background, so it has its own lock
+type testReports struct {
+	mu      sync.Mutex
+	results map[string]map[string]bool // "<job path>/<branch>#<build>" -> test name -> passed
+}
+
+// newTestReports creates an empty cache
+func newTestReports() *testReports {
+	return &testReports{results: make(map[string]map[string]bool)}
+}
+
+// get returns the test results of a completed build, fetching them if not cached
+func (r *testReports) get(client Client, jobPath, branch string, buildNum int) (map[string]bool, error) {
+	key := fmt.Sprintf("%s/%s#%d", jobPath, branch, buildNum)
+	r.mu.Lock()
+	results, ok := r.results[key]
+	r.mu.Unlock()
+	if ok {
+		return results, nil
+	}
+
+	results, err := client.GetTestResults(jobPath, branch, buildNum)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.results) >= maxCachedReports {
+		clear(r.results) // Simple bound; reports are refetched as needed
+	}
+	r.results[key] = results
+	return results, nil
+}
@@ -0,0 +1,337 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/fetch"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/metrics"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+)
+
+// Retry delays of a failed analysis (e.g., Jenkins unreachable), doubling per failure
+const (
+	analysisRetry    = time.Minute
+	maxAnalysisRetry = 30 * time.Minute
+)
+
+// Client is the part of the Jenkins client failure analysis needs
+type Client interface {
+	GetConsoleLog(jobPath, branch string, buildNum int) (string, error)
+	ListBuilds(jobPath, branch string, limit int) ([]models.Build, error)
+	GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error)
+}
+
+// Tracker applies fetch results to tiles the same way for the dashboard and
+// serve mode: it merges a fetched build into its tile, compares its stages to
+// their baselines, records it in the build history and decides which
+// notifications and failure analyses it calls for
+// It is not safe for concurrent use; the dashboard calls it from Update and
+// the engine under its lock
+type Tracker struct {
+	rules        []classifier.Rule
+	flakyTests   *flaky.Store
+	flakyConfig  flaky.Config
+	stageHistory *analytics.StageHistory
+	stageConfig  analytics.Config
+	history      *history.Store
+	metrics      *metrics.Registry
+	notifier     *notify.Notifier
+	webhooks     *notify.Webhooks
+	hooks        *notify.Hooks
+
+	lastGood map[string]models.Build // PR -> last successfully fetched build, what changes are announced against
+	attempts map[attemptKey]attempt  // Analyses running or waiting to be retried
+	reports  *testReports            // Test results of completed builds, shared by flaky test scans
+}
+
+// attemptKey identifies an analysis of a build
+type attemptKey struct {
+	kind        string
+	prNumber    string
+	buildNumber int
+}
+
+// attempt tracks an analysis that is running or failed
+type attempt struct {
+	running  bool
+	failures int
+	retryAt  time.Time
+}
+
+// New creates a tracker with the default rules and in-memory histories
+func New() *Tracker {
+	return &Tracker{
+		rules:        classifier.DefaultRules(),
+		flakyTests:   flaky.NewStore(""),
+		flakyConfig:  flaky.LoadConfig(),
+		stageHistory: analytics.NewStageHistory(""),
+		stageConfig:  analytics.LoadConfig(),
+		history:      history.NewStore("", history.Config{}),
+		notifier:     notify.New(notify.Config{}, nil),
+		lastGood:     make(map[string]models.Build),
+		attempts:     make(map[attemptKey]attempt),
+		reports:      newTestReports(),
+	}
+}
+
+// SetFailureRules replaces the rules used to classify failed builds
+func (t *Tracker) SetFailureRules(rules []classifier.Rule) {
+	t.rules = rules
+}
+
+// SetFlakyStore replaces the persistent list of known flaky tests
+func (t *Tracker) SetFlakyStore(store *flaky.Store) {
+	t.flakyTests = store
+}
+
+// SetStageHistory replaces the stage duration history used for baselines
+func (t *Tracker) SetStageHistory(history *analytics.StageHistory) {
+	t.stageHistory = history
+}
+
+// SetBuildHistory replaces the store every observed build result is appended to
+func (t *Tracker) SetBuildHistory(store *history.Store) {
+	t.history = store
+}
+
+// SetMetrics sets the registry finished builds are recorded in (nil = no metrics)
+func (t *Tracker) SetMetrics(registry *metrics.Registry) {
+	t.metrics = registry
+}
+
+// SetNotifier replaces the notifier used when builds finish
+func (t *Tracker) SetNotifier(notifier *notify.Notifier) {
+	t.notifier = notifier
+}
+
+// SetWebhooks replaces the webhooks posted to when builds change status
+func (t *Tracker) SetWebhooks(webhooks *notify.Webhooks) {
+	t.webhooks = webhooks
+}
+
+// SetHooks replaces the user-defined hook scripts run on build events
+func (t *Tracker) SetHooks(hooks *notify.Hooks) {
+	t.hooks = hooks
+}
+
+// Forget drops what is known about a PR that is no longer tracked
+func (t *Tracker) Forget(prNumber string) {
+	delete(t.lastGood, prNumber)
+	for key := range t.attempts {
+		if key.prNumber == prNumber {
+			delete(t.attempts, key)
+		}
+	}
+}
+
+// Baseline returns the median and p90 duration of a stage of a job
+func (t *Tracker) Baseline(jobPath, stage string) (analytics.Stats, bool) {
+	return t.stageHistory.Baseline(jobPath, stage)
+}
+
+// Outcome is a fetch result applied to a tile
+type Outcome struct {
+	Build    models.Build   // The tile after the fetch
+	Previous models.Build   // The tile before the fetch
+	Notify   []func() error // Terminal notifications, to deliver from the goroutine that owns the terminal
+	Announce []func() error // Notification commands, webhook posts and hook scripts to deliver in the background
+	Persist  []func() error // Stage history and build history writes to do in the background
+	Analyses []Analysis     // Failure analyses to run in the background (see Finish)
+}
+
+// Apply stores the result of fetching a tile's PR
+// A fetch error marks the tile; a fetched build is merged with the tile,
+// compared to the stage baselines and recorded in the stage and build
+// histories, whose writes are left to the caller (see Outcome.Persist)
+func (t *Tracker) Apply(tile models.Build, fetched *models.Build, err error, now time.Time) Outcome {
+	if err != nil {
+		kind := jenkins.ErrorKindOf(err)
+		tile.Status = models.StatusError
+		tile.ErrorMessage = err.Error()
+		tile.ErrorKind = kind
+		return Outcome{Build: tile, Previous: tile}
+	}
+	if fetched == nil {
+		return Outcome{Build: tile, Previous: tile}
+	}
+
+	// Keep the green streak, failure analysis and Git branch from the previous poll
+	previous := tile
+	build := *fetched
+	fetch.Merge(previous, &build)
+
+	// Compare stages to their baseline before this build joins the history;
+	// the comparison is kept on the build since it would find nothing after
+	build.SlowStages, build.SlowRatios = nil, nil
+	for _, regression := range t.stageHistory.Regressions(build, t.stageConfig) {
+		if build.SlowRatios == nil {
+			build.SlowRatios = make(map[string]float64)
+		}
+		build.SlowStages = append(build.SlowStages, regression.Stage)
+		build.SlowRatios[regression.Stage] = regression.Ratio
+	}
+	var persist []func() error
+	if t.stageHistory.Record(build) {
+		save := t.stageHistory.Snapshot()
+		persist = append(persist, func() error {
+			if err := save(); err != nil {
+				return fmt.Errorf("saving stage history: %w", err)
+			}
+			return nil
+		})
+	}
+
+	last, known := t.baseline(previous)
+	t.lastGood[build.PRNumber] = build
+
+	t.metrics.ObserveBuild(last, build)
+	store := t.history
+	persist = append(persist, func() error {
+		if err := store.Observe(build, now); err != nil {
+			return fmt.Errorf("recording build history: %w", err)
+		}
+		return nil
+	})
+
+	outcome := Outcome{Build: build, Previous: previous, Persist: persist, Analyses: t.analyses(build, now)}
+	if known {
+		outcome.Notify, outcome.Announce = t.announcements(last, build)
+	}
+	return outcome
+}
+
+// baseline returns the build a fetched build's changes are announced against:
+// the last build fetched for the PR, or the tile if it holds a build fetched
+// before (e.g., by an earlier run)
+// A tile that is a placeholder for a PR never fetched, or whose status was
+// overwritten by a fetch error, is no baseline - announcing against it would
+// report changes that didn't happen
+func (t *Tracker) baseline(tile models.Build) (models.Build, bool) {
+	if last, ok := t.lastGood[tile.PRNumber]; ok {
+		return last, true
+	}
+	if tile.BuildNumber == 0 || tile.Status == models.StatusError {
+		return models.Build{}, false
+	}
+	return tile, true
+}
+
+// announcements returns the desktop notification, webhook post and hook
+// scripts a build's change since the build fetched before (see baseline)
+// calls for
+func (t *Tracker) announcements(last, current models.Build) (terminal, background []func() error) {
+	if event, ok := notify.Transition(last, current); ok && t.notifier.Enabled() {
+		notifier := t.notifier
+		terminal = append(terminal, func() error { return notifier.Terminal(event) })
+		background = append(background, func() error { return notifier.Command(event) })
+	}
+	if event, ok := notify.Changed(last, current); ok && t.webhooks.Wants(event) {
+		webhooks := t.webhooks
+		background = append(background, func() error { return webhooks.Send(event) })
+	}
+	for _, event := range notify.Events(last, current) {
+		if t.hooks.Has(event) {
+			hooks := t.hooks
+			background = append(background, func() error { return hooks.Run(event, last, current) })
+		}
+	}
+	return terminal, background
+}
+
+// analyses returns the analyses a newly failed build still needs: its root
+// cause from the console log and its flaky tests from the test history
+// An analysis isn't started again while it runs, or before its retry delay
+// after it failed
+func (t *Tracker) analyses(build models.Build, now time.Time) []Analysis {
+	if !build.IsFailure() {
+		return nil
+	}
+	var analyses []Analysis
+	if build.FailureCategory == "" && t.start(analysisKey(analysisClassify, build.PRNumber, build.BuildNumber), now) {
+		analyses = append(analyses, classify(build, t.rules))
+	}
+	if !build.TestsChecked && t.start(analysisKey(analysisFlakyScan, build.PRNumber, build.BuildNumber), now) {
+		analyses = append(analyses, scanFlakyTests(build, t.flakyConfig, t.reports))
+	}
+	return analyses
+}
+
+// analysisKey returns the key of an analysis of a build
+func analysisKey(kind, prNumber string, buildNumber int) attemptKey {
+	return attemptKey{kind: kind, prNumber: prNumber, buildNumber: buildNumber}
+}
+
+// start marks an analysis as running, unless it already runs or waits to be retried
+func (t *Tracker) start(key attemptKey, now time.Time) bool {
+	if previous, ok := t.attempts[key]; ok && (previous.running || now.Before(previous.retryAt)) {
+		return false
+	}
+	current := t.attempts[key]
+	current.running = true
+	t.attempts[key] = current
+	return true
+}
+
+// finished records the end of an analysis; failed ones are retried after a delay
+func (t *Tracker) finished(key attemptKey, err error, now time.Time) {
+	if err == nil {
+		delete(t.attempts, key)
+		return
+	}
+	current := t.attempts[key]
+	current.running = false
+	current.failures++
+	delay := analysisRetry << (current.failures - 1)
+	if delay <= 0 || delay > maxAnalysisRetry {
+		delay = maxAnalysisRetry
+	}
+	current.retryAt = now.Add(delay)
+	t.attempts[key] = current
+}
+
+// Finish applies the result of an analysis to the tiles it concerns
+// Returns a message worth showing ("" = nothing to report)
+func (t *Tracker) Finish(builds []models.Build, result Result, now time.Time) string {
+	if result.kind != "" {
+		t.finished(analysisKey(result.kind, result.PRNumber, result.BuildNumber), result.err, now)
+	}
+	if result.err != nil {
+		return "" // Best effort - retried on a later refresh
+	}
+
+	var message string
+	if result.scanned {
+		if added := t.flakyTests.Record(result.detections, now); added > 0 {
+			_ = t.flakyTests.Save()
+			message = fmt.Sprintf("Found %d new flaky test(s), %d known", added, t.flakyTests.Len())
+		}
+	}
+
+	for i := range builds {
+		build := &builds[i]
+		analyzed := build.PRNumber == result.PRNumber && build.BuildNumber == result.BuildNumber
+		if analyzed && result.classified {
+			build.FailureCategory = result.match.Category
+			build.FailureLine = result.match.Line
+		}
+		if analyzed && result.scanned {
+			build.TestsChecked = true
+			build.FailedTests = result.failed
+		}
+		if result.scanned {
+			// The list may have grown - re-check every failed build's tests
+			build.FlakyTests = t.flakyTests.Filter(build.FailedTests)
+		}
+		if analyzed {
+			_ = t.history.Append(history.NewRecord(*build, now))
+		}
+	}
+	return message
+}
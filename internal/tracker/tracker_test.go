@@ -0,0 +1,269 @@
+package tracker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+)
+
+// mockClient serves a console log and per-branch test histories
+type mockClient struct {
+	log         string
+	err         error
+	history     map[string][]models.Build // Branch -> builds returned by ListBuilds
+	testResults map[int]map[string]bool   // Build number -> test results
+	reports     int                       // GetTestResults calls
+}
+
+func (m *mockClient) GetConsoleLog(jobPath, branch string, buildNum int) (string, error) {
+	return m.log, m.err
+}
+
+func (m *mockClient) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	return m.history[branch], m.err
+}
+
+func (m *mockClient) GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error) {
+	m.reports++
+	return m.testResults[buildNum], m.err
+}
+
+// runAnalyses runs the analyses of an outcome and applies their results
+func runAnalyses(tracker *Tracker, client Client, builds []models.Build, outcome Outcome) {
+	for _, analysis := range outcome.Analyses {
+		tracker.Finish(builds, analysis(client), time.Now())
+	}
+}
+
+func TestApply_Error(t *testing.T) {
+	tracker := New()
+	tile := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusRunning, GitBranch: "feature/login"}
+
+	outcome := tracker.Apply(tile, nil, errors.New("HTTP 503: unavailable"), time.Now())
+	if outcome.Build.Status != models.StatusError || outcome.Build.ErrorMessage == "" {
+		t.Errorf("Expected an error tile, got %+v", outcome.Build)
+	}
+	if outcome.Build.BuildNumber != 4 || outcome.Build.GitBranch != "feature/login" {
+		t.Errorf("Error tile should keep the build, got %+v", outcome.Build)
+	}
+	if len(outcome.Announce) != 0 || len(outcome.Analyses) != 0 {
+		t.Error("A fetch error should not be announced or analyzed")
+	}
+}
+
+func TestApply_ClassifiesAndScansFailedBuild(t *testing.T) {
+	t.Setenv("FLAKY_MAIN_BRANCH", "off")
+	tracker := New()
+	client := &mockClient{
+		log: "java.lang.OutOfMemoryError: Java heap space\n",
+		history: map[string][]models.Build{
+			"PR-3859": {
+				{BuildNumber: 12, Status: models.StatusFailure, BuiltSHA: "aaa1111"},
+				{BuildNumber: 11, Status: models.StatusSuccess, BuiltSHA: "aaa1111"},
+			},
+		},
+		testResults: map[int]map[string]bool{
+			11: {"qal.LoginTest.testSession": true},
+			12: {"qal.LoginTest.testSession": false},
+		},
+	}
+	tile := models.Build{PRNumber: "3859", BuildNumber: 12, Status: models.StatusRunning}
+
+	outcome := tracker.Apply(tile, &models.Build{PRNumber: "3859", BuildNumber: 12, Status: models.StatusFailure}, nil, time.Now())
+	if len(outcome.Analyses) != 2 {
+		t.Fatalf("Expected a classification and a flaky test scan, got %d analyses", len(outcome.Analyses))
+	}
+
+	builds := []models.Build{outcome.Build}
+	runAnalyses(tracker, client, builds, outcome)
+	build := builds[0]
+	if build.FailureCategory != "Out of memory" || !build.TestsChecked {
+		t.Errorf("Expected a classified and scanned build, got %+v", build)
+	}
+	if len(build.FlakyTests) != 1 || build.FlakyTests[0] != "qal.LoginTest.testSession" {
+		t.Errorf("Expected qal.LoginTest.testSession to be flaky, got %v", build.FlakyTests)
+	}
+
+	// The next poll of the same build carries the analysis over
+	again := tracker.Apply(build, &models.Build{PRNumber: "3859", BuildNumber: 12, Status: models.StatusFailure}, nil, time.Now())
+	if len(again.Analyses) != 0 || again.Build.FailureCategory != "Out of memory" {
+		t.Errorf("Analyzed build should not be analyzed again, got %d analyses", len(again.Analyses))
+	}
+}
+
+func TestApply_ScansFlakyTestsOnceAndCachesReports(t *testing.T) {
+	t.Setenv("FLAKY_MAIN_BRANCH", "off")
+	tracker := New()
+	history := []models.Build{
+		{BuildNumber: 12, Status: models.StatusFailure},
+		{BuildNumber: 11, Status: models.StatusSuccess},
+	}
+	client := &mockClient{
+		history:     map[string][]models.Build{"PR-3859": history},
+		testResults: map[int]map[string]bool{11: {"LoginTest": true}, 12: {"LoginTest": false}},
+	}
+	failed := models.Build{PRNumber: "3859", BuildNumber: 12, Status: models.StatusFailure, FailureCategory: "Test failure"}
+
+	scans := tracker.Apply(failed, &failed, nil, time.Now()).Analyses
+	if len(scans) != 1 || len(tracker.Apply(failed, &failed, nil, time.Now()).Analyses) != 0 {
+		t.Fatal("A flaky test scan should not start again while it runs")
+	}
+	scans[0](client)
+	if client.reports != 2 {
+		t.Fatalf("Expected 2 test reports fetched, got %d", client.reports)
+	}
+
+	// A later build's scan only fetches the new report
+	next := models.Build{PRNumber: "3859", BuildNumber: 13, Status: models.StatusFailure, FailureCategory: "Test failure"}
+	client.history["PR-3859"] = append([]models.Build{{BuildNumber: 13, Status: models.StatusFailure}}, history...)
+	client.testResults[13] = map[string]bool{"LoginTest": false}
+	for _, scan := range tracker.Apply(next, &next, nil, time.Now()).Analyses {
+		scan(client)
+	}
+	if client.reports != 3 {
+		t.Errorf("Expected cached reports to be reused, got %d fetches", client.reports)
+	}
+}
+
+func TestApply_ClassifiesOnceAndBacksOffAfterErrors(t *testing.T) {
+	tracker := New()
+	failed := models.Build{PRNumber: "3859", BuildNumber: 12, Status: models.StatusFailure, TestsChecked: true}
+	now := time.Now()
+	classifications := func(at time.Time) []Analysis {
+		return tracker.Apply(failed, &failed, nil, at).Analyses
+	}
+
+	running := classifications(now)
+	if len(running) != 1 || len(classifications(now.Add(time.Second))) != 0 {
+		t.Fatal("A classification should not start again while it runs")
+	}
+
+	builds := []models.Build{failed}
+	result := running[0](&mockClient{err: errors.New("HTTP 502")})
+	if message := tracker.Finish(builds, result, now); message != "" || builds[0].FailureCategory != "" {
+		t.Errorf("A failed classification should change nothing, got %q, category %q", message, builds[0].FailureCategory)
+	}
+	if len(classifications(now.Add(analysisRetry/2))) != 0 {
+		t.Error("A failed classification should not be retried before its delay")
+	}
+	retry := classifications(now.Add(analysisRetry))
+	if len(retry) != 1 {
+		t.Fatal("A failed classification should be retried after its delay")
+	}
+
+	tracker.Finish(builds, retry[0](&mockClient{log: "something unexpected"}), now)
+	if builds[0].FailureCategory != classifier.Unclassified {
+		t.Errorf("Expected category %q, got %q", classifier.Unclassified, builds[0].FailureCategory)
+	}
+}
+
+func TestApply_AnnouncesAgainstLastFetchedBuild(t *testing.T) {
+	targets, err := notify.CompileTargets([]notify.Target{{URL: "http://127.0.0.1:1/hook"}})
+	if err != nil {
+		t.Fatalf("CompileTargets() error = %v", err)
+	}
+	tracker := New()
+	tracker.SetWebhooks(notify.NewWebhooks(targets))
+	running := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusRunning}
+
+	steps := []struct {
+		name    string
+		tile    models.Build // Tile before the fetch
+		fetched models.Build
+		err     error
+		want    int // Announcements
+	}{
+		{"first fetch of an added PR", persistence.NewTile("3859"), running, nil, 0},
+		{"fetch error", running, models.Build{}, errors.New("HTTP 503: unavailable"), 0},
+		{"same build after the error", models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusError}, running, nil, 0},
+		{"tile cleared and refetched", persistence.NewTile("3859"), running, nil, 0},
+		{"build finished", running, models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusFailure}, nil, 1},
+	}
+	for _, step := range steps {
+		var fetched *models.Build
+		if step.err == nil {
+			fetched = &step.fetched
+		}
+		if got := len(tracker.Apply(step.tile, fetched, step.err, time.Now()).Announce); got != step.want {
+			t.Errorf("%s: %d announcement(s), want %d", step.name, got, step.want)
+		}
+	}
+}
+
+func TestApply_RunsHooksAgainstLastFetchedBuild(t *testing.T) {
+	tracker := New()
+	tracker.SetHooks(notify.NewHooks(map[string]string{notify.HookStart: "true", notify.HookFailure: "true"}, 0))
+	running := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusRunning}
+	errored := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusError}
+
+	tracker.Apply(models.Build{PRNumber: "3859", BuildNumber: 3, Status: models.StatusSuccess}, &running, nil, time.Now())
+	if got := len(tracker.Apply(errored, &running, nil, time.Now()).Announce); got != 0 {
+		t.Errorf("on_start ran again after a fetch error (%d hooks)", got)
+	}
+	if got := len(tracker.Apply(persistence.NewTile("3859"), &running, nil, time.Now()).Announce); got != 0 {
+		t.Errorf("on_start ran again after the tile was cleared (%d hooks)", got)
+	}
+	failed := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusFailure}
+	if got := len(tracker.Apply(errored, &failed, nil, time.Now()).Announce); got != 1 {
+		t.Errorf("on_failure should run for a build seen running before a fetch error, got %d hooks", got)
+	}
+}
+
+func TestApply_AnnouncesAgainstTileOfEarlierRun(t *testing.T) {
+	targets, err := notify.CompileTargets([]notify.Target{{URL: "http://127.0.0.1:1/hook"}})
+	if err != nil {
+		t.Fatalf("CompileTargets() error = %v", err)
+	}
+	tracker := New()
+	tracker.SetWebhooks(notify.NewWebhooks(targets))
+
+	saved := models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusSuccess}
+	outcome := tracker.Apply(saved, &models.Build{PRNumber: "3859", BuildNumber: 5, Status: models.StatusRunning}, nil, time.Now())
+	if len(outcome.Announce) != 1 {
+		t.Errorf("A new build after a saved one should be announced, got %d announcement(s)", len(outcome.Announce))
+	}
+}
+
+func TestApply_LeavesHistoryWritesToCaller(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	store := history.NewStore(path, history.Config{})
+	tracker := New()
+	tracker.SetBuildHistory(store)
+
+	outcome := tracker.Apply(models.Build{PRNumber: "3859"}, &models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusSuccess}, nil, time.Now())
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Apply should not write the build history itself")
+	}
+	for _, persist := range outcome.Persist {
+		if err := persist(); err != nil {
+			t.Fatalf("persist error = %v", err)
+		}
+	}
+	if records, _ := store.Records(history.Filter{}); len(records) != 1 {
+		t.Errorf("Expected the build recorded once the writes ran, got %d records", len(records))
+	}
+
+	// A failed write is returned, not dropped
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tracker.SetBuildHistory(history.NewStore(filepath.Join(blocker, "history.jsonl"), history.Config{}))
+	outcome = tracker.Apply(outcome.Build, &models.Build{PRNumber: "3859", BuildNumber: 5, Status: models.StatusRunning}, nil, time.Now())
+	var failed error
+	for _, persist := range outcome.Persist {
+		failed = errors.Join(failed, persist())
+	}
+	if failed == nil {
+		t.Error("Expected the failed history write to be reported")
+	}
+}
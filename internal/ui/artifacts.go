@@ -0,0 +1,166 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+// artifactsListedMsg is sent when the artifact list of a build has been fetched
+type artifactsListedMsg struct {
+	prNumber  string
+	artifacts []models.Artifact
+	err       error
+}
+
+// artifactProgressMsg reports download progress; ch delivers the next message
+type artifactProgressMsg struct {
+	fileName string
+	written  int64
+	total    int64
+	ch       <-chan tea.Msg
+}
+
+// artifactDownloadedMsg is sent when a download finishes (success or error)
+type artifactDownloadedMsg struct {
+	path string
+	err  error
+}
+
+// getArtifactDir returns where artifacts are downloaded
+// Reads from JENKINS_ARTIFACT_DIR or defaults to ~/Downloads/jenkins-dash
+func getArtifactDir() string {
+	if dir := os.Getenv("JENKINS_ARTIFACT_DIR"); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "jenkins-dash-artifacts"
+	}
+	return filepath.Join(homeDir, "Downloads", "jenkins-dash")
+}
+
+// listArtifactsCmd fetches the artifacts of a build
+func listArtifactsCmd(client Client, build models.Build) tea.Cmd {
+	return func() tea.Msg {
+		jobPath := jenkins.JobPathOf(build)
+		artifacts, err := client.ListArtifacts(jobPath, "PR-"+build.PRNumber, build.BuildNumber)
+		return artifactsListedMsg{prNumber: build.PRNumber, artifacts: artifacts, err: err}
+	}
+}
+
+// downloadArtifactCmd starts a download in the background and streams progress messages
+func downloadArtifactCmd(client Client, build models.Build, artifact models.Artifact, destDir string) tea.Cmd {
+	ch := make(chan tea.Msg, 1)
+
+	go func() {
+		jobPath := jenkins.JobPathOf(build)
+		progress := func(written, total int64) {
+			// Drop updates while the UI is busy; only the latest matters
+			select {
+			case ch <- artifactProgressMsg{fileName: artifact.FileName, written: written, total: total, ch: ch}:
+			default:
+			}
+		}
+		path, err := client.DownloadArtifact(jobPath, "PR-"+build.PRNumber, build.BuildNumber, artifact.RelativePath, destDir, progress)
+		ch <- artifactDownloadedMsg{path: path, err: err}
+		close(ch)
+	}()
+
+	return waitForArtifactCmd(ch)
+}
+
+// waitForArtifactCmd waits for the next message of a running download
+func waitForArtifactCmd(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// handleArtifactMode processes keyboard input while the artifact picker is open
+func (m Model) handleArtifactMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.artifactMode = false
+		m.artifacts = nil
+		m.statusMessage = "Press 'a' to add a PR build, arrow keys to navigate"
+		return m, nil
+
+	case tea.KeyUp:
+		if m.artifactIndex > 0 {
+			m.artifactIndex--
+		}
+		return m, nil
+
+	case tea.KeyDown:
+		if m.artifactIndex < len(m.artifacts)-1 {
+			m.artifactIndex++
+		}
+		return m, nil
+
+	case tea.KeyEnter:
+		build := m.state.GetSelectedBuild()
+		if build == nil || m.artifactIndex >= len(m.artifacts) || m.jenkinsClient == nil {
+			return m, nil
+		}
+		artifact := m.artifacts[m.artifactIndex]
+		m.artifactMode = false
+		m.artifacts = nil
+		m.statusMessage = fmt.Sprintf("Downloading %s...", artifact.FileName)
+		return m, downloadArtifactCmd(m.jenkinsClient, *build, artifact, getArtifactDir())
+	}
+
+	return m, nil
+}
+
+// renderArtifactPicker renders the artifact list of the selected build
+func (m Model) renderArtifactPicker() string {
+	lines := []string{"Artifacts (↑↓ select, enter download, esc cancel):"}
+	for i, artifact := range m.artifacts {
+		cursor := "  "
+		if i == m.artifactIndex {
+			cursor = "▶ "
+		}
+		lines = append(lines, cursor+artifact.RelativePath)
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#00FFFF")).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// formatProgress formats download progress for the status bar
+// Example: "report.zip 45% (4.5 MB / 10.0 MB)", or "report.zip 4.5 MB" if the size is unknown
+func formatProgress(fileName string, written, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("⬇ %s %s", fileName, formatBytes(written))
+	}
+	percent := written * 100 / total
+	return fmt.Sprintf("⬇ %s %d%% (%s / %s)", fileName, percent, formatBytes(written), formatBytes(total))
+}
+
+// formatBytes returns a human-readable size
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
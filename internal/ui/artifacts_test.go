@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestModel_ArtifactPickerAndDownload(t *testing.T) {
+	t.Setenv("JENKINS_ARTIFACT_DIR", "/tmp/artifacts")
+
+	mockClient := &mockJenkinsClient{
+		artifacts: []models.Artifact{
+			{FileName: "report.html", RelativePath: "target/report.html"},
+			{FileName: "bundle.zip", RelativePath: "bundle.zip"},
+		},
+	}
+	m := NewModelWithClient(mockClient, "")
+	m.state.AddBuild(models.Build{PRNumber: "3934", Status: models.StatusSuccess, BuildNumber: 263})
+
+	// 'f' lists artifacts of the selected build
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if cmd == nil {
+		t.Fatal("Pressing 'f' should return a command")
+	}
+	newModel, _ := m.Update(cmd())
+	m = newModel.(Model)
+
+	if !m.artifactMode {
+		t.Fatal("Artifact picker should open after listing")
+	}
+	if !strings.Contains(m.View(), "target/report.html") {
+		t.Error("Picker should list artifact paths")
+	}
+
+	// Select the second artifact and download it
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = newModel.(Model)
+	newModel, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(Model)
+
+	if m.artifactMode {
+		t.Error("Picker should close when a download starts")
+	}
+
+	// Drain progress messages until the download completes
+	for i := 0; cmd != nil && i < 10; i++ {
+		newModel, cmd = m.Update(cmd())
+		m = newModel.(Model)
+	}
+
+	if !strings.Contains(m.statusMessage, "/tmp/artifacts/bundle.zip") {
+		t.Errorf("Status should show saved path, got %q", m.statusMessage)
+	}
+}
+
+func TestFormatProgress(t *testing.T) {
+	if got := formatProgress("a.zip", 512*1024, 1024*1024); got != "⬇ a.zip 50% (512.0 KB / 1.0 MB)" {
+		t.Errorf("formatProgress() = %q", got)
+	}
+	if got := formatProgress("a.zip", 2048, -1); got != "⬇ a.zip 2.0 KB" {
+		t.Errorf("formatProgress() unknown size = %q", got)
+	}
+}
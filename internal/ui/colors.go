@@ -26,6 +26,17 @@ var (
 	// Error - same as failure but could be different
 	colorErrorBg = lipgloss.Color("#E06C75")  // Soft red
 	colorErrorFg = lipgloss.Color("#1A1A1A")  // Dark text
+
+	// Auth/permission errors - soft purple (needs user action, not a build failure)
+	colorAuthBg = lipgloss.Color("#C678DD")  // Soft purple
+	colorAuthFg = lipgloss.Color("#1A1A1A")  // Dark text
+
+	// Connectivity errors - soft grey (transient, Jenkins or network is down)
+	colorOfflineBg = lipgloss.Color("#ABB2BF")  // Soft grey
+	colorOfflineFg = lipgloss.Color("#1A1A1A")  // Dark text
+
+	// Detail view highlight for stages much slower than their baseline
+	colorSlowStage = lipgloss.Color("#D19A66")  // Soft orange
 )
 
 // GetTileColors returns the background and foreground colors for a build status
@@ -41,8 +52,28 @@ func GetTileColors(status models.BuildStatus) (bg lipgloss.Color, fg lipgloss.Co
 		return colorPendingBg, colorPendingFg
 	case models.StatusError:
 		return colorErrorBg, colorErrorFg
+	case models.StatusAborted:
+		return colorOfflineBg, colorOfflineFg // Stopped, not broken
 	default:
 		return colorPendingBg, colorPendingFg
 	}
 }
 
+
+// GetBuildColors returns the tile colors for a build, refining errors by kind
+func GetBuildColors(build models.Build) (bg lipgloss.Color, fg lipgloss.Color) {
+	if build.Status != models.StatusError {
+		return GetTileColors(build.Status)
+	}
+
+	switch build.ErrorKind {
+	case models.ErrorAuth, models.ErrorForbidden:
+		return colorAuthBg, colorAuthFg
+	case models.ErrorNotFound:
+		return colorPendingBg, colorPendingFg // Not built yet is "waiting", not broken
+	case models.ErrorServer, models.ErrorTimeout, models.ErrorOffline:
+		return colorOfflineBg, colorOfflineFg
+	default:
+		return GetTileColors(build.Status)
+	}
+}
@@ -1,119 +1,47 @@
 package ui
 
 import (
-	"fmt"
-	"os"
-
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mpetters/jenkins-dash/internal/browser"
-	"github.com/mpetters/jenkins-dash/internal/github"
-	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/fetch"
 	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/tracker"
 )
 
 // buildFetchedMsg is sent when a build fetch completes (success or error)
 type buildFetchedMsg struct {
-	index int
-	build *models.Build
-	err   error
+	index    int
+	prNumber string // Finds the tile again if tiles were deleted or reloaded meanwhile
+	build    *models.Build
+	err      error
 }
 
 // fetchBuildAndBranchCmd fetches both Jenkins build data and GitHub branch name
 func fetchBuildAndBranchCmd(client Client, prNumber string, index int) tea.Cmd {
 	return func() tea.Msg {
-		jobPath := jenkins.InferJobPath(prNumber)
-		branch := "PR-" + prNumber
-		build, err := client.GetBuildStatus(jobPath, branch, 0)
-
-		if err != nil {
-			return buildFetchedMsg{index: index, build: nil, err: err}
-		}
-
-		if build == nil {
-			return buildFetchedMsg{index: index, build: nil, err: fmt.Errorf("no build data returned")}
-		}
-
-		// Fetch Git branch and PR check status from GitHub
-		if build.GitBranch == "" || build.PRCheckStatus == "" {
-			token := os.Getenv("GITHUB_TOKEN")
-			if token != "" {
-			// Get GitHub repo from env or use default
-			githubRepo := os.Getenv("GITHUB_REPO")
-			if githubRepo == "" {
-				githubRepo = "identity-manage/account"
-			}
-
-			// Fetch PR information (branch, author, repository)
-			if build.GitBranch == "" || build.PRAuthor == "" || build.Repository == "" {
-				prInfo, err := github.FetchPRBranch(token, githubRepo, prNumber)
-				if err == nil {
-					if build.GitBranch == "" && prInfo.BranchName != "" {
-						build.GitBranch = prInfo.BranchName
-					}
-					if build.PRAuthor == "" && prInfo.Author != "" {
-						build.PRAuthor = prInfo.Author
-					}
-					if build.Repository == "" && prInfo.Repository != "" {
-						build.Repository = prInfo.Repository
-					}
-				}
-			}
-
-			// Fetch PR check status
-			checkStatus := github.FetchPRCheckStatus(token, githubRepo, prNumber)
-			build.PRCheckStatus = checkStatus.Summary
-			}
-		}
-
-		return buildFetchedMsg{
-			index: index,
-			build: build,
-			err:   nil,
-		}
+		build, err := fetch.Build(client, fetch.GitHubFromEnv(), prNumber, models.Build{})
+		return buildFetchedMsg{index: index, prNumber: prNumber, build: build, err: err}
 	}
 }
 
 // fetchBuildCmd is used for refresh - preserves Git branch, PR author, and repository but refreshes PR check status
-func fetchBuildCmd(client Client, prNumber string, index int, existingGitBranch, existingPRCheckStatus, existingPRAuthor, existingRepository string) tea.Cmd {
+// existing is the tile, so only builds it hasn't seen are backfilled
+func fetchBuildCmd(client Client, existing models.Build, index int) tea.Cmd {
 	return func() tea.Msg {
-		jobPath := jenkins.InferJobPath(prNumber)
-		branch := "PR-" + prNumber
-		build, err := client.GetBuildStatus(jobPath, branch, 0)
-
-		if build != nil {
-			// Preserve the Git branch (doesn't change often)
-			if existingGitBranch != "" {
-				build.GitBranch = existingGitBranch
-			}
-
-			// Preserve the PR author
-			if existingPRAuthor != "" {
-				build.PRAuthor = existingPRAuthor
-			}
-
-			// Preserve the repository
-			if existingRepository != "" {
-				build.Repository = existingRepository
-			}
-
-			// Re-fetch PR check status for real-time updates
-			token := os.Getenv("GITHUB_TOKEN")
-			if token != "" {
-				githubRepo := os.Getenv("GITHUB_REPO")
-				if githubRepo == "" {
-					githubRepo = "identity-manage/account"
-				}
+		build, err := fetch.Build(client, fetch.GitHubFromEnv(), existing.PRNumber, existing)
+		return buildFetchedMsg{index: index, prNumber: existing.PRNumber, build: build, err: err}
+	}
+}
 
-				checkStatus := github.FetchPRCheckStatus(token, githubRepo, prNumber)
-				build.PRCheckStatus = checkStatus.Summary
-			}
-		}
+// analyzedMsg is sent when a failure analysis of a build finished
+type analyzedMsg struct {
+	result tracker.Result
+}
 
-		return buildFetchedMsg{
-			index: index,
-			build: build,
-			err:   err,
-		}
+// analyzeCmd runs a failure analysis in the background
+func analyzeCmd(client Client, analysis tracker.Analysis) tea.Cmd {
+	return func() tea.Msg {
+		return analyzedMsg{result: analysis(client)}
 	}
 }
 
@@ -133,3 +61,20 @@ func openURLCmd(url string) tea.Cmd {
 		}
 	}
 }
+
+// backgroundFailedMsg is sent when a notification, webhook, hook script or
+// history write failed
+type backgroundFailedMsg struct {
+	err error
+}
+
+// backgroundCmd runs a notification, webhook post, hook script or history
+// write in the background
+func backgroundCmd(task func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := task(); err != nil {
+			return backgroundFailedMsg{err: err}
+		}
+		return nil
+	}
+}
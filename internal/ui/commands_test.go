@@ -11,6 +11,11 @@ import (
 type mockJenkinsClient struct {
 	buildToReturn *models.Build
 	errorToReturn error
+	queued        bool
+	artifacts     []models.Artifact
+	consoleLog    string
+	history       map[string][]models.Build // Branch -> builds returned by ListBuilds
+	testResults   map[int]map[string]bool   // Build number -> test results
 }
 
 func (m *mockJenkinsClient) GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error) {
@@ -20,6 +25,36 @@ func (m *mockJenkinsClient) GetBuildStatus(jobPath, branch string, buildNum int)
 	return m.buildToReturn, nil
 }
 
+func (m *mockJenkinsClient) GetConsoleLog(jobPath, branch string, buildNum int) (string, error) {
+	return m.consoleLog, m.errorToReturn
+}
+
+func (m *mockJenkinsClient) ListBuilds(jobPath, branch string, limit int) ([]models.Build, error) {
+	return m.history[branch], m.errorToReturn
+}
+
+func (m *mockJenkinsClient) GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error) {
+	return m.testResults[buildNum], m.errorToReturn
+}
+
+func (m *mockJenkinsClient) IsQueued(jobPath, branch string) (bool, error) {
+	return m.queued, nil
+}
+
+func (m *mockJenkinsClient) ListArtifacts(jobPath, branch string, buildNum int) ([]models.Artifact, error) {
+	return m.artifacts, m.errorToReturn
+}
+
+func (m *mockJenkinsClient) DownloadArtifact(jobPath, branch string, buildNum int, relativePath, destDir string, progress func(written, total int64)) (string, error) {
+	if m.errorToReturn != nil {
+		return "", m.errorToReturn
+	}
+	if progress != nil {
+		progress(50, 100)
+	}
+	return destDir + "/" + relativePath, nil
+}
+
 // TestFetchBuildAndBranchCmd_SetsPRCheckStatus tests that PR check status is fetched and set
 func TestFetchBuildAndBranchCmd_SetsPRCheckStatus(t *testing.T) {
 	// Setup: Create a mock build without PR check status
@@ -127,7 +162,7 @@ func TestFetchBuildCmd_RefreshesPRCheckStatus(t *testing.T) {
 		defer os.Unsetenv("GITHUB_TOKEN")
 		defer os.Unsetenv("GITHUB_REPO")
 
-		cmd := fetchBuildCmd(mockClient, "12345", 0, existingGitBranch, existingCheckStatus, existingPRAuthor, existingRepository)
+		cmd := fetchBuildCmd(mockClient, models.Build{PRNumber: "12345", GitBranch: existingGitBranch, PRCheckStatus: existingCheckStatus, PRAuthor: existingPRAuthor, Repository: existingRepository}, 0)
 		msg := cmd()
 
 		buildMsg, ok := msg.(buildFetchedMsg)
@@ -219,7 +254,7 @@ func TestPRCheckStatusRefreshThroughAutoRefresh(t *testing.T) {
 		defer os.Unsetenv("GITHUB_REPO")
 
 		// Auto-refresh fetches Jenkins data again AND re-fetches PR check status
-		cmd := fetchBuildCmd(mockClient, "12345", 0, existingGitBranch, existingPRCheckStatus, existingPRAuthor, existingRepository)
+		cmd := fetchBuildCmd(mockClient, models.Build{PRNumber: "12345", GitBranch: existingGitBranch, PRCheckStatus: existingPRCheckStatus, PRAuthor: existingPRAuthor, Repository: existingRepository}, 0)
 		msg := cmd()
 
 		buildMsg, ok := msg.(buildFetchedMsg)
@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+const (
+	detailLabelWidth   = 10
+	maxDetailCommits   = 8  // Most recent commits shown per section
+	commitMessageWidth = 50 // Truncate commit messages to this many cells
+	stageNameWidth     = 32 // Truncate stage names to this many cells
+)
+
+// renderDetail renders the detail panel for the selected build
+func (m Model) renderDetail(build models.Build) string {
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("PR-%s  #%d  %s", build.PRNumber, build.BuildNumber, build.Status.String())),
+	}
+
+	lines = append(lines, detailLine("Branch", build.GitBranch))
+	lines = append(lines, detailLine("Author", build.PRAuthor))
+	lines = append(lines, detailLine("Repo", build.Repository))
+	lines = append(lines, detailLine("Stage", build.Stage))
+	lines = append(lines, detailLine("Job", build.JobName))
+	lines = append(lines, detailLine("Duration", build.FormatDuration()))
+	if completed := build.FormatCompletedTime(); completed != "" {
+		lines = append(lines, detailLine("Completed", completed))
+	}
+	lines = append(lines, detailLine("Checks", build.PRCheckStatus))
+	lines = append(lines, detailLine("Trigger", causeDescriptions(build.Causes)))
+	lines = append(lines, detailLine("Commit", models.ShortSHA(build.BuiltSHA)))
+	if build.IsStale() {
+		lines = append(lines, detailLine("Warning", staleBuildText(build)))
+	}
+
+	// Current refresh cadence from the adaptive scheduler
+	lines = append(lines, detailLine("Refresh", m.scheduler.Cadence(build.PRNumber).String(time.Now())))
+
+	if build.FailureCategory != "" {
+		lines = append(lines, detailLine("Cause", build.FailureCategory))
+		if build.FailureLine != "" {
+			lines = append(lines, detailLine("Log", truncateWidth(build.FailureLine, 80)))
+		}
+	}
+	if build.ErrorMessage != "" {
+		lines = append(lines, detailLine("Error", fmt.Sprintf("%s [%s]", build.ErrorMessage, build.ErrorKind.String())))
+	}
+	lines = append(lines, detailLine("Build URL", build.BuildURL))
+	lines = append(lines, detailLine("PR URL", build.PRURL))
+	lines = append(lines, m.renderStageDurations(build)...)
+	lines = append(lines, renderFailedTests(build)...)
+	lines = append(lines, renderCommitSections(build)...)
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#888888")).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// detailLine formats a "Label: value" row, using "-" for empty values
+func detailLine(label, value string) string {
+	if value == "" {
+		value = "-"
+	}
+	return fmt.Sprintf("%-*s %s", detailLabelWidth, label+":", value)
+}
+
+// causeDescriptions joins all build causes as Jenkins describes them
+func causeDescriptions(causes []models.BuildCause) string {
+	descriptions := make([]string, 0, len(causes))
+	for _, cause := range causes {
+		if cause.Description != "" {
+			descriptions = append(descriptions, cause.Description)
+		} else {
+			descriptions = append(descriptions, cause.Type)
+		}
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// renderStageDurations lists each stage's duration next to its historical
+// median and p90, highlighting the stages flagged as much slower than usual
+// when the build was fetched
+func (m Model) renderStageDurations(build models.Build) []string {
+	jobPath := jenkins.JobPathOf(build)
+	var lines []string
+	for _, stage := range build.Stages {
+		if !analytics.Measurable(stage) {
+			continue
+		}
+
+		name := strings.Repeat("  ", stage.Depth) + stage.Name // Nested stages of the stage graph
+		line := "  " + fitWidth(truncateWidth(name, stageNameWidth), stageNameWidth) + " " +
+			fitWidth(models.FormatSeconds(int(stage.DurationMillis/1000)), 10)
+		if baseline, ok := m.tracker.Baseline(jobPath, stage.Name); ok {
+			line += fmt.Sprintf(" p50 %-10s p90 %-10s", models.FormatSeconds(int(baseline.Median.Seconds())), models.FormatSeconds(int(baseline.P90.Seconds())))
+		} else {
+			line += " no baseline yet"
+		}
+		if ratio, ok := build.SlowRatios[stage.Name]; ok {
+			line = lipgloss.NewStyle().Foreground(colorSlowStage).Render(fmt.Sprintf("%s ⚠ %.1f×", line, ratio))
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	lines = append([]string{"", "Stages:"}, lines...)
+
+	if timeline := renderTimeline(build.Stages); len(timeline) > 0 {
+		lines = append(lines, "", "Timeline:")
+		lines = append(lines, timeline...)
+	}
+	return lines
+}
+
+// renderFailedTests lists the failed tests of the build, marking the known flaky ones
+func renderFailedTests(build models.Build) []string {
+	if len(build.FailedTests) == 0 {
+		return nil
+	}
+
+	flaky := make(map[string]bool, len(build.FlakyTests))
+	for _, test := range build.FlakyTests {
+		flaky[test] = true
+	}
+
+	lines := []string{"", fmt.Sprintf("Failed tests (%d flaky):", len(build.FlakyTests))}
+	for i, test := range build.FailedTests {
+		if i == maxDetailCommits {
+			lines = append(lines, fmt.Sprintf("  … %d more", len(build.FailedTests)-i))
+			break
+		}
+		tag := ""
+		if flaky[test] {
+			tag = "flaky"
+		}
+		lines = append(lines, "  "+fitWidth(tag, 6)+truncateWidth(test, 80))
+	}
+	return lines
+}
+
+// renderCommitSections lists the commits this build covered, the commits since the
+// last green build (for red builds), and which build covered each PR commit
+func renderCommitSections(build models.Build) []string {
+	var lines []string
+
+	if len(build.Changes) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Changes in #%d:", build.BuildNumber))
+		lines = append(lines, commitLines(build.Changes, nil)...)
+	}
+
+	if build.IsFailure() && build.LastGreenBuild > 0 && len(build.SinceGreen) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Since last green #%d:", build.LastGreenBuild))
+		lines = append(lines, commitLines(build.SinceGreen, func(c models.Commit) string {
+			return fmt.Sprintf("#%d", c.BuildNumber)
+		})...)
+	}
+
+	if len(build.PRCommits) > 0 {
+		coverage := prCommitCoverage(build)
+		lines = append(lines, "", "PR commits:")
+		lines = append(lines, commitLines(build.PRCommits, func(c models.Commit) string {
+			return coverage[c.SHA]
+		})...)
+	}
+
+	return lines
+}
+
+// commitLines formats the most recent commits, one per line
+// If tag is non-nil, its result is shown in a column after the SHA
+func commitLines(commits []models.Commit, tag func(models.Commit) string) []string {
+	var lines []string
+	start := 0
+	if len(commits) > maxDetailCommits {
+		start = len(commits) - maxDetailCommits
+		lines = append(lines, fmt.Sprintf("  … %d earlier", start))
+	}
+
+	for _, commit := range commits[start:] {
+		line := "  " + models.ShortSHA(commit.SHA)
+		if tag != nil {
+			line += " " + fitWidth(tag(commit), 9)
+		}
+		line += " " + fitWidth(commit.Author, 12) + " " + truncateWidth(commit.Message, commitMessageWidth)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// prCommitCoverage maps each PR commit SHA to the build that covered it:
+// "#N" if a known build's changeset included it, "built" if it is at or before
+// the built commit, and "not built" if it came after
+func prCommitCoverage(build models.Build) map[string]string {
+	coverage := make(map[string]string, len(build.PRCommits))
+	for _, commit := range build.SinceGreen {
+		coverage[commit.SHA] = fmt.Sprintf("#%d", commit.BuildNumber)
+	}
+	for _, commit := range build.Changes {
+		coverage[commit.SHA] = fmt.Sprintf("#%d", commit.BuildNumber)
+	}
+
+	builtIndex := -1
+	for i, commit := range build.PRCommits {
+		if build.BuiltSHA != "" && strings.EqualFold(commit.SHA, build.BuiltSHA) {
+			builtIndex = i
+		}
+	}
+
+	result := make(map[string]string, len(build.PRCommits))
+	for i, commit := range build.PRCommits {
+		switch {
+		case coverage[commit.SHA] != "":
+			result[commit.SHA] = coverage[commit.SHA]
+		case builtIndex >= 0 && i <= builtIndex:
+			result[commit.SHA] = "built"
+		case builtIndex >= 0:
+			result[commit.SHA] = "not built"
+		}
+	}
+	return result
+}
+
+// truncateWidth shortens text to at most width cells, adding "…" when cut
+func truncateWidth(text string, width int) string {
+	if lipgloss.Width(text) <= width {
+		return text
+	}
+	return strings.TrimRight(fitWidth(text, width-1), " ") + "…"
+}
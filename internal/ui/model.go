@@ -6,25 +6,46 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/history"
+	"github.com/mpetters/jenkins-dash/internal/jenkins"
 	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
+	"github.com/mpetters/jenkins-dash/internal/scheduler"
+	"github.com/mpetters/jenkins-dash/internal/tracker"
 )
 
 // Model represents the Bubbletea application state
 type Model struct {
 	state         *models.DashboardState
 	jenkinsClient Client
-	configPath    string
+	store         *persistence.Store // Saved builds file, shared with other instances
 	inputMode     bool
 	inputValue    string
 	statusMessage string
 	termWidth     int
 	termHeight    int
 	blinkState    bool
+	showDetail    bool
+	scheduler     *scheduler.Scheduler
+	artifactMode  bool
+	artifacts     []models.Artifact
+	artifactIndex int
+	tracker       *tracker.Tracker // Merges, analyzes and announces fetched builds
 }
 
 // Client is an interface to avoid import cycle with jenkins package
 type Client interface {
 	GetBuildStatus(jobPath, branch string, buildNum int) (*models.Build, error)
+	IsQueued(jobPath, branch string) (bool, error)
+	ListArtifacts(jobPath, branch string, buildNum int) ([]models.Artifact, error)
+	DownloadArtifact(jobPath, branch string, buildNum int, relativePath, destDir string, progress func(written, total int64)) (string, error)
+	GetConsoleLog(jobPath, branch string, buildNum int) (string, error)
+	ListBuilds(jobPath, branch string, limit int) ([]models.Build, error)
+	GetTestResults(jobPath, branch string, buildNum int) (map[string]bool, error)
 }
 
 // NewModel creates a new Model with default values
@@ -41,16 +62,54 @@ func NewModelWithClient(client Client, configPath string) Model {
 			GridColumns:   3,
 		},
 		jenkinsClient: client,
-		configPath:    configPath,
+		store:         persistence.NewStore(configPath),
 		inputMode:     false,
 		inputValue:    "",
 		statusMessage: "Press 'a' to add a PR build, arrow keys to navigate",
 		termWidth:     120,
 		termHeight:    40,
 		blinkState:    false,
+		showDetail:    false,
+		scheduler:     scheduler.New(scheduler.LoadConfig()),
+		tracker:       tracker.New(),
 	}
 }
 
+// SetFailureRules replaces the rules used to classify failed builds
+func (m *Model) SetFailureRules(rules []classifier.Rule) {
+	m.tracker.SetFailureRules(rules)
+}
+
+// SetFlakyStore replaces the persistent list of known flaky tests
+func (m *Model) SetFlakyStore(store *flaky.Store) {
+	m.tracker.SetFlakyStore(store)
+}
+
+// SetStageHistory replaces the stage duration history used for baselines
+func (m *Model) SetStageHistory(history *analytics.StageHistory) {
+	m.tracker.SetStageHistory(history)
+}
+
+// SetBuildHistory replaces the store every observed build result is appended to
+func (m *Model) SetBuildHistory(store *history.Store) {
+	m.tracker.SetBuildHistory(store)
+}
+
+// SetNotifier replaces the notifier used when builds finish
+func (m *Model) SetNotifier(notifier *notify.Notifier) {
+	m.tracker.SetNotifier(notifier)
+}
+
+// SetWebhooks replaces the webhooks posted to when builds change status
+func (m *Model) SetWebhooks(webhooks *notify.Webhooks) {
+	m.tracker.SetWebhooks(webhooks)
+}
+
+// SetHooks replaces the user-defined hook scripts run on build events
+func (m *Model) SetHooks(hooks *notify.Hooks) {
+	m.tracker.SetHooks(hooks)
+}
+
 // AddTestBuild adds a build to the model (for testing/demo purposes)
 func (m *Model) AddTestBuild(build models.Build) {
 	m.state.AddBuild(build)
@@ -59,7 +118,7 @@ func (m *Model) AddTestBuild(build models.Build) {
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
-		tickCmd(),     // 10 second build refresh
+		tickCmd(),     // 1 second poll check (per-tile cadence comes from the scheduler)
 		blinkCmd(),    // 800ms blink for running builds
 		timeTickCmd(), // 1 second time tick for live clock
 	)
@@ -78,41 +137,89 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case buildFetchedMsg:
+		var cmds []tea.Cmd
+		if msg.prNumber != "" {
+			msg.index = m.tileIndex(msg.index, msg.prNumber)
+		}
 		// Update build with fetched data
 		if msg.index >= 0 && msg.index < len(m.state.Builds) {
+			// Schedule the next poll for this tile based on the outcome
+			var fetched models.Build
+			if msg.build != nil {
+				fetched = *msg.build
+			}
+			m.scheduler.Finished(m.state.Builds[msg.index].PRNumber, fetched, msg.err, time.Now())
+
+			outcome := m.tracker.Apply(m.state.Builds[msg.index], msg.build, msg.err, time.Now())
+			m.state.Builds[msg.index] = outcome.Build
+			build := outcome.Build
 			if msg.err != nil {
-				m.state.Builds[msg.index].Status = models.StatusError
-				m.state.Builds[msg.index].ErrorMessage = msg.err.Error()
-				m.statusMessage = fmt.Sprintf("✗ Error fetching PR-%s: %v", m.state.Builds[msg.index].PRNumber, msg.err)
+				m.statusMessage = fmt.Sprintf("✗ PR-%s: %s", build.PRNumber, jenkins.DescribeErrorKind(build.ErrorKind))
 			} else if msg.build != nil {
-				// Preserve Git branch if already set (from GitHub or user input)
-				existingGitBranch := m.state.Builds[msg.index].GitBranch
-				m.state.Builds[msg.index] = *msg.build
-				if msg.build.GitBranch == "" && existingGitBranch != "" {
-					m.state.Builds[msg.index].GitBranch = existingGitBranch
-				}
-				completedTime := msg.build.FormatCompletedTime()
-				if completedTime != "" {
+				completedTime := build.FormatCompletedTime()
+				if build.IsStale() {
+					m.statusMessage = fmt.Sprintf("⚠ PR-%s: %s", build.PRNumber, staleBuildText(build))
+				} else if completedTime != "" {
 					m.statusMessage = fmt.Sprintf("✓ PR-%s: %s (Stage: %s, Job: %s, Branch: %s, Completed: %s)",
-						msg.build.PRNumber, msg.build.Status.String(), msg.build.Stage, msg.build.JobName, msg.build.GitBranch, completedTime)
+						build.PRNumber, build.Status.String(), build.Stage, build.JobName, build.GitBranch, completedTime)
 				} else {
 					m.statusMessage = fmt.Sprintf("✓ PR-%s: %s (Stage: %s, Job: %s, Branch: %s)",
-						msg.build.PRNumber, msg.build.Status.String(), msg.build.Stage, msg.build.JobName, msg.build.GitBranch)
+						build.PRNumber, build.Status.String(), build.Stage, build.JobName, build.GitBranch)
 				}
 			}
 			// Save state after update (Git branch persists)
 			_ = m.saveState()
+
+			// Announce finished builds on the desktop, status changes on chat, and run hook scripts
+			// Terminal notifications are written here, on the loop that renders, so they land between frames
+			for _, deliver := range outcome.Notify {
+				if err := deliver(); err != nil {
+					m.statusMessage = fmt.Sprintf("⚠ %v", err)
+				}
+			}
+			for _, announce := range outcome.Announce {
+				cmds = append(cmds, backgroundCmd(announce))
+			}
+			// Record the build in the stage and build histories without blocking the UI on disk
+			for _, persist := range outcome.Persist {
+				cmds = append(cmds, backgroundCmd(persist))
+			}
+			// Analyze newly failed builds: root cause from the console log, flaky tests from the test history
+			if m.jenkinsClient != nil {
+				for _, analysis := range outcome.Analyses {
+					cmds = append(cmds, analyzeCmd(m.jenkinsClient, analysis))
+				}
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case backgroundFailedMsg:
+		m.statusMessage = fmt.Sprintf("⚠ %v", msg.err)
+		return m, nil
+
+	case analyzedMsg:
+		if message := m.tracker.Finish(m.state.Builds, msg.result, time.Now()); message != "" {
+			m.statusMessage = "⚠ " + message
 		}
+		_ = m.saveState()
 		return m, nil
 
 	case tickMsg:
-		// Refresh all builds every 10 seconds
+		// Pick up tiles another instance added or removed (e.g., `jenkins-dash add`)
+		if changed, err := m.reloadPersistedBuilds(); err != nil {
+			m.statusMessage = fmt.Sprintf("⚠ Could not reload saved builds: %v", err)
+		} else if changed != "" {
+			m.statusMessage = changed
+		}
+		// Refresh only the builds whose polling interval has elapsed
 		if m.jenkinsClient != nil {
 			var cmds []tea.Cmd
+			now := time.Time(msg)
 			for i, build := range m.state.Builds {
-				if build.Status != models.StatusPending {
+				if m.scheduler.Due(build.PRNumber, now) {
+					m.scheduler.Started(build.PRNumber)
 					// Pass existing Git branch, PR check status, PR author, and repository to preserve them on refresh
-					cmds = append(cmds, fetchBuildCmd(m.jenkinsClient, build.PRNumber, i, build.GitBranch, build.PRCheckStatus, build.PRAuthor, build.Repository))
+					cmds = append(cmds, fetchBuildCmd(m.jenkinsClient, build, i))
 				}
 			}
 			cmds = append(cmds, tickCmd())
@@ -129,6 +236,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Just trigger re-render for live time updates
 		return m, timeTickCmd()
 
+	case artifactsListedMsg:
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("✗ PR-%s: could not list artifacts: %v", msg.prNumber, msg.err)
+			return m, nil
+		}
+		if len(msg.artifacts) == 0 {
+			m.statusMessage = fmt.Sprintf("PR-%s has no artifacts", msg.prNumber)
+			return m, nil
+		}
+		m.artifactMode = true
+		m.artifacts = msg.artifacts
+		m.artifactIndex = 0
+		m.statusMessage = fmt.Sprintf("PR-%s: %d artifact(s)", msg.prNumber, len(msg.artifacts))
+		return m, nil
+
+	case artifactProgressMsg:
+		m.statusMessage = formatProgress(msg.fileName, msg.written, msg.total)
+		return m, waitForArtifactCmd(msg.ch)
+
+	case artifactDownloadedMsg:
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("✗ Download failed: %v", msg.err)
+		} else {
+			m.statusMessage = "✓ Saved " + msg.path
+		}
+		return m, nil
+
 	case urlOpenedMsg:
 		// Browser opened (or failed)
 		if msg.err != nil {
@@ -148,15 +282,20 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.inputMode {
 		return m.handleInputMode(msg)
 	}
+	if m.artifactMode {
+		return m.handleArtifactMode(msg)
+	}
 
 	// Handle normal mode keys
 	switch msg.Type {
 	case tea.KeyCtrlC, tea.KeyEsc:
+		_ = m.saveState() // Keep the selection for the next start
 		return m, tea.Quit
 
 	case tea.KeyRunes:
 		switch string(msg.Runes) {
 		case "q":
+			_ = m.saveState()
 			return m, tea.Quit
 		case "a":
 			m.inputMode = true
@@ -165,13 +304,26 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		case "d":
 			// Delete selected build
-			if m.state.GetSelectedBuild() != nil {
+			if build := m.state.GetSelectedBuild(); build != nil {
+				m.scheduler.Forget(build.PRNumber)
+				m.tracker.Forget(build.PRNumber)
 				m.state.RemoveBuild(m.state.SelectedIndex)
 				m.statusMessage = "Build deleted"
 				// Save state after deletion
 				_ = m.saveState()
 			}
 			return m, nil
+		case "i":
+			// Toggle detail view for the selected build
+			m.showDetail = !m.showDetail
+			return m, nil
+		case "f":
+			// List artifacts of the selected build
+			if build := m.state.GetSelectedBuild(); build != nil && m.jenkinsClient != nil && build.BuildNumber > 0 {
+				m.statusMessage = fmt.Sprintf("Fetching artifacts for PR-%s #%d...", build.PRNumber, build.BuildNumber)
+				return m, listArtifactsCmd(m.jenkinsClient, *build)
+			}
+			return m, nil
 		case "p":
 			// Open PR in browser
 			if build := m.state.GetSelectedBuild(); build != nil {
@@ -179,13 +331,14 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "r":
-			// Manual refresh all builds
+			// Manual refresh all builds, except those with a fetch already in flight
 			if m.jenkinsClient != nil {
 				var cmds []tea.Cmd
 				for i, build := range m.state.Builds {
-					if build.Status != models.StatusPending {
+					if build.Status != models.StatusPending && !m.scheduler.Cadence(build.PRNumber).InFlight {
+						m.scheduler.Started(build.PRNumber)
 						// Pass existing Git branch, PR check status, PR author, and repository to preserve them on refresh
-						cmds = append(cmds, fetchBuildCmd(m.jenkinsClient, build.PRNumber, i, build.GitBranch, build.PRCheckStatus, build.PRAuthor, build.Repository))
+						cmds = append(cmds, fetchBuildCmd(m.jenkinsClient, build, i))
 					}
 				}
 				m.statusMessage = "Refreshing all builds..."
@@ -208,13 +361,8 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				var cmds []tea.Cmd
 				for i, prNum := range prNumbers {
 					// Create fresh loading build
-					build := models.Build{
-						PRNumber: prNum,
-						Status:   models.StatusPending,
-						Stage:    "Loading...",
-						JobName:  "Fetching data...",
-					}
-					m.state.AddBuild(build)
+					m.state.AddBuild(persistence.NewTile(prNum))
+					m.scheduler.Started(prNum)
 					// Fetch with GitHub branch update
 					cmds = append(cmds, fetchBuildAndBranchCmd(m.jenkinsClient, prNum, i))
 				}
@@ -260,12 +408,7 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Submit the PR number
 		if m.inputValue != "" {
 			// Create a loading build and add it to state
-			build := models.Build{
-				PRNumber: m.inputValue,
-				Status:   models.StatusPending,
-				Stage:    "Loading...",
-				JobName:  "Fetching data...",
-			}
+			build := persistence.NewTile(m.inputValue)
 			m.state.AddBuild(build)
 			newIndex := len(m.state.Builds) - 1
 			m.statusMessage = "✓ Added PR-" + m.inputValue + " - Fetching build & branch data..."
@@ -279,6 +422,7 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 			// Fetch Jenkins build data AND GitHub branch name
 			if m.jenkinsClient != nil {
+				m.scheduler.Started(build.PRNumber)
 				return m, fetchBuildAndBranchCmd(m.jenkinsClient, build.PRNumber, newIndex)
 			}
 			return m, nil
@@ -306,6 +450,9 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// pollTickInterval is how often the scheduler is asked which builds are due
+const pollTickInterval = 1 * time.Second
+
 // Message types for async operations
 type tickMsg time.Time
 type blinkMsg time.Time
@@ -313,7 +460,7 @@ type timeTickMsg time.Time
 
 // Commands
 func tickCmd() tea.Cmd {
-	return tea.Tick(10*time.Second, func(t time.Time) tea.Msg {
+	return tea.Tick(pollTickInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
@@ -353,6 +500,26 @@ func (m Model) View() string {
 	sections = append(sections, gridWithMargin)
 	sections = append(sections, "") // Space before input/status
 
+	// Detail view for the selected build (if toggled on)
+	if m.showDetail {
+		if build := m.state.GetSelectedBuild(); build != nil {
+			detailWithMargin := lipgloss.NewStyle().
+				MarginLeft(2).
+				Render(m.renderDetail(*build))
+			sections = append(sections, detailWithMargin)
+			sections = append(sections, "")
+		}
+	}
+
+	// Artifact picker (if open)
+	if m.artifactMode {
+		pickerWithMargin := lipgloss.NewStyle().
+			MarginLeft(2).
+			Render(m.renderArtifactPicker())
+		sections = append(sections, pickerWithMargin)
+		sections = append(sections, "")
+	}
+
 	// Input field (if in input mode)
 	if m.inputMode {
 		inputStyle := lipgloss.NewStyle().
@@ -379,7 +546,7 @@ func (m Model) View() string {
 		Foreground(lipgloss.Color("#666666")).
 		Padding(0, 2).
 		MarginLeft(2)
-	footer := footerStyle.Render("a: Add PR | c: Clear Cache | d: Delete | r: Refresh | ↑↓←→: Navigate | enter: Open Build | p: Open PR | q: Quit")
+	footer := footerStyle.Render("a: Add PR | c: Clear Cache | d: Delete | r: Refresh | i: Details | f: Artifacts | ↑↓←→: Navigate | enter: Open Build | p: Open PR | q: Quit")
 	sections = append(sections, footer)
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
@@ -1,11 +1,23 @@
 package ui
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mpetters/jenkins-dash/internal/flaky"
+	"github.com/mpetters/jenkins-dash/internal/history"
 	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/notify"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
 )
 
 // Test 11: RED - Bubbletea model initialization
@@ -180,3 +192,406 @@ func TestModel_View_InputMode(t *testing.T) {
 		t.Error("View should show input value")
 	}
 }
+
+func TestModel_Update_DetailToggle(t *testing.T) {
+	m := NewModel()
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusRunning, BuildNumber: 42})
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	m = newModel.(Model)
+
+	if !m.showDetail {
+		t.Fatal("Pressing 'i' should show the detail view")
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "Refresh:") {
+		t.Error("Detail view should show the refresh cadence")
+	}
+
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	m = newModel.(Model)
+	if m.showDetail {
+		t.Error("Pressing 'i' again should hide the detail view")
+	}
+}
+
+func TestModel_View_DetailShowsCommitsSinceGreen(t *testing.T) {
+	m := NewModel()
+	m.state.AddBuild(models.Build{
+		PRNumber:       "3859",
+		Status:         models.StatusFailure,
+		BuildNumber:    12,
+		BuiltSHA:       "bbb2222",
+		LastGreenBuild: 10,
+		Changes:        []models.Commit{{SHA: "bbb2222", Author: "jdoe", Message: "Break the build", BuildNumber: 12}},
+		SinceGreen: []models.Commit{
+			{SHA: "aaa1111", Author: "jdoe", Message: "Refactor login", BuildNumber: 11},
+			{SHA: "bbb2222", Author: "jdoe", Message: "Break the build", BuildNumber: 12},
+		},
+		PRCommits: []models.Commit{
+			{SHA: "aaa1111", Author: "jdoe", Message: "Refactor login"},
+			{SHA: "bbb2222", Author: "jdoe", Message: "Break the build"},
+			{SHA: "ccc3333", Author: "jdoe", Message: "Fix the build"},
+		},
+	})
+	m.showDetail = true
+
+	view := m.View()
+
+	for _, want := range []string{"Changes in #12:", "Since last green #10:", "Refactor login", "PR commits:", "not built"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Detail view should contain %q", want)
+		}
+	}
+}
+
+func TestModel_Update_ClassifiesFailedBuild(t *testing.T) {
+	m := NewModel()
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusRunning, BuildNumber: 42})
+	m.jenkinsClient = &mockJenkinsClient{consoleLog: "[INFO] Tests run: 4\njava.lang.OutOfMemoryError: Java heap space\n"}
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+
+	failed := &models.Build{PRNumber: "3859", Status: models.StatusFailure, BuildNumber: 42}
+	newModel, cmd := m.Update(buildFetchedMsg{index: 0, build: failed})
+	m = newModel.(Model)
+	if cmd == nil {
+		t.Fatal("Failed build should be classified from its console log")
+	}
+
+	for _, msg := range runCmds(cmd) {
+		newModel, _ = m.Update(msg)
+		m = newModel.(Model)
+	}
+	build := m.state.Builds[0]
+	if build.FailureCategory != "Out of memory" {
+		t.Errorf("Expected category 'Out of memory', got %q", build.FailureCategory)
+	}
+	if build.FailureLine != "java.lang.OutOfMemoryError: Java heap space" {
+		t.Errorf("Unexpected matched line %q", build.FailureLine)
+	}
+
+	// Refreshing the same build keeps the classification without refetching the log
+	again := &models.Build{PRNumber: "3859", Status: models.StatusFailure, BuildNumber: 42}
+	newModel, cmd = m.Update(buildFetchedMsg{index: 0, build: again})
+	m = newModel.(Model)
+	for _, msg := range runCmds(cmd) {
+		if _, ok := msg.(analyzedMsg); ok {
+			t.Error("Already analyzed build should not be analyzed again")
+		}
+	}
+	if m.state.Builds[0].FailureCategory != "Out of memory" {
+		t.Error("Classification should carry over across refreshes of the same build")
+	}
+}
+
+func TestModel_Refresh_SkipsFetchesInFlight(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	m := NewModel()
+	m.jenkinsClient = &mockJenkinsClient{buildToReturn: &models.Build{Status: models.StatusSuccess, BuildNumber: 7}}
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusRunning})
+	m.state.AddBuild(models.Build{PRNumber: "3934", Status: models.StatusRunning})
+	m.scheduler.Started("3859") // Polled by the last tick, not back yet
+
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	m = newModel.(Model)
+
+	var fetched []string
+	for _, msg := range runCmds(cmd) {
+		if msg, ok := msg.(buildFetchedMsg); ok {
+			fetched = append(fetched, msg.prNumber)
+		}
+	}
+	if len(fetched) != 1 || fetched[0] != "3934" {
+		t.Errorf("Expected only PR 3934 refreshed, got %v", fetched)
+	}
+	if !m.scheduler.Cadence("3934").InFlight {
+		t.Error("Refreshed PR should be marked in flight")
+	}
+}
+
+// runCmds runs a command, expanding batches, and returns the resulting messages
+func runCmds(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, runCmds(c)...)
+		}
+		return msgs
+	}
+	if msg == nil {
+		return nil
+	}
+	return []tea.Msg{msg}
+}
+
+func TestModel_Update_DetectsFlakyTests(t *testing.T) {
+	t.Setenv("FLAKY_MAIN_BRANCH", "off")
+	flakyTests := flaky.NewStore("")
+	m := NewModel()
+	m.SetFlakyStore(flakyTests)
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusRunning, BuildNumber: 12})
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	m.jenkinsClient = &mockJenkinsClient{
+		history: map[string][]models.Build{
+			"PR-3859": {
+				{BuildNumber: 12, Status: models.StatusFailure, BuiltSHA: "aaa1111"},
+				{BuildNumber: 11, Status: models.StatusSuccess, BuiltSHA: "aaa1111"},
+			},
+		},
+		testResults: map[int]map[string]bool{
+			11: {"qal.LoginTest.testSession": true, "unit.MathTest.testAdd": true},
+			12: {"qal.LoginTest.testSession": false, "unit.MathTest.testAdd": true},
+		},
+	}
+
+	failed := &models.Build{PRNumber: "3859", Status: models.StatusFailure, BuildNumber: 12}
+	_, cmd := m.Update(buildFetchedMsg{index: 0, build: failed})
+	for _, msg := range runCmds(cmd) {
+		newModel, _ := m.Update(msg)
+		m = newModel.(Model)
+	}
+
+	build := m.state.Builds[0]
+	if !build.TestsChecked {
+		t.Error("Build should be marked as checked for flaky tests")
+	}
+	if len(build.FlakyTests) != 1 || build.FlakyTests[0] != "qal.LoginTest.testSession" {
+		t.Errorf("Expected qal.LoginTest.testSession to be flaky, got %v", build.FlakyTests)
+	}
+	if !flakyTests.IsFlaky("qal.LoginTest.testSession") {
+		t.Error("Flaky test should be added to the persistent list")
+	}
+	if !strings.Contains(RenderTile(build, false), "flaky") {
+		t.Error("Failed tile should show a flaky badge")
+	}
+}
+
+func TestModel_Update_FlagsSlowStages(t *testing.T) {
+	m := NewModel()
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusPending})
+
+	stageBuild := func(number int, minutes int64, status models.BuildStatus) *models.Build {
+		return &models.Build{
+			PRNumber:    "3859",
+			BuildNumber: number,
+			Status:      status,
+			Stages:      []models.Stage{{Name: "QAL Tests", Status: "SUCCESS", DurationMillis: minutes * 60000}},
+		}
+	}
+
+	for i := 1; i <= 5; i++ {
+		newModel, _ := m.Update(buildFetchedMsg{index: 0, build: stageBuild(i, 5, models.StatusSuccess)})
+		m = newModel.(Model)
+	}
+	if len(m.state.Builds[0].SlowStages) != 0 {
+		t.Fatal("Builds at the baseline should not be flagged")
+	}
+
+	// The slow build joins the history once finished; the detail view still
+	// shows the slowdown it was flagged with
+	newModel, _ := m.Update(buildFetchedMsg{index: 0, build: stageBuild(6, 15, models.StatusSuccess)})
+	m = newModel.(Model)
+	if got := m.state.Builds[0].SlowStages; len(got) != 1 || got[0] != "QAL Tests" {
+		t.Errorf("Expected QAL Tests to be flagged as slow, got %v", got)
+	}
+
+	m.showDetail = true
+	if view := m.View(); !strings.Contains(view, "p50 5m 0s") || !strings.Contains(view, "3.0×") {
+		t.Error("Detail view should show the stage baseline and the slowdown the build was flagged with")
+	}
+}
+
+func TestModel_Update_RecordsBuildHistory(t *testing.T) {
+	m := NewModel()
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	buildHistory := history.NewStore(t.TempDir()+"/history.jsonl", history.Config{})
+	m.SetBuildHistory(buildHistory)
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusPending})
+
+	for _, status := range []models.BuildStatus{models.StatusRunning, models.StatusRunning, models.StatusSuccess} {
+		newModel, cmd := m.Update(buildFetchedMsg{index: 0, build: &models.Build{PRNumber: "3859", BuildNumber: 4, Status: status}})
+		m = newModel.(Model)
+		// History is written in the background, by the commands Update returns
+		for _, msg := range runCmds(cmd) {
+			if failed, ok := msg.(backgroundFailedMsg); ok {
+				t.Fatalf("History write failed: %v", failed.err)
+			}
+		}
+	}
+
+	records, err := buildHistory.Records(history.Filter{PRNumber: "3859"})
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("Expected running and success to be recorded once each, got %d records", len(records))
+	}
+}
+
+func TestModel_Update_NotifiesOnFinish(t *testing.T) {
+	var out strings.Builder
+	m := NewModel()
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	m.SetNotifier(notify.New(notify.Config{Methods: []string{notify.MethodOSC9}}, &out))
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusPending})
+
+	// A fetch error while the build runs doesn't lose the running state
+	fetchError := errors.New("HTTP 503: unavailable")
+	for _, err := range []error{nil, fetchError, nil} {
+		var build *models.Build
+		if err == nil {
+			build = &models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusRunning}
+		}
+		newModel, _ := m.Update(buildFetchedMsg{index: 0, build: build, err: err})
+		m = newModel.(Model)
+	}
+	newModel, _ := m.Update(buildFetchedMsg{index: 0, err: fetchError})
+	m = newModel.(Model)
+	for range 2 {
+		// Written by Update itself, not by a command running next to the renderer
+		newModel, _ := m.Update(buildFetchedMsg{index: 0, build: &models.Build{PRNumber: "3859", BuildNumber: 4, Status: models.StatusFailure}})
+		m = newModel.(Model)
+	}
+
+	if got := strings.Count(out.String(), "\x1b]9;"); got != 1 {
+		t.Fatalf("Expected one notification for running→failure, got %d: %q", got, out.String())
+	}
+	if !strings.Contains(out.String(), "PR-3859 failed") {
+		t.Errorf("Notification should name the PR and result, got %q", out.String())
+	}
+}
+
+func TestModel_Update_PostsWebhookOnStatusChange(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	targets, err := notify.CompileTargets([]notify.Target{{URL: server.URL, Filter: notify.Filter{Statuses: []string{"failure"}}}})
+	if err != nil {
+		t.Fatalf("CompileTargets() error = %v", err)
+	}
+	m := NewModel()
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	m.SetWebhooks(notify.NewWebhooks(targets))
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusPending})
+
+	for _, status := range []models.BuildStatus{models.StatusRunning, models.StatusFailure, models.StatusFailure} {
+		newModel, cmd := m.Update(buildFetchedMsg{index: 0, build: &models.Build{PRNumber: "3859", BuildNumber: 4, Status: status}})
+		m = newModel.(Model)
+		runCmds(cmd)
+	}
+
+	if len(bodies) != 1 || !strings.Contains(bodies[0], `"status":"failure"`) {
+		t.Errorf("Expected one failure webhook, got %v", bodies)
+	}
+}
+
+func TestModel_Update_RunsHookScripts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "events")
+	m := NewModel()
+	m.store = persistence.NewStore(t.TempDir() + "/builds.json")
+	m.SetHooks(notify.NewHooks(map[string]string{
+		notify.HookStart:   `echo "$HOOK_EVENT $HOOK_PR" >> ` + out,
+		notify.HookSuccess: `echo "$HOOK_EVENT $HOOK_PR" >> ` + out,
+	}, 0))
+	m.state.AddBuild(models.Build{PRNumber: "3859", BuildNumber: 3, Status: models.StatusSuccess})
+
+	for _, status := range []models.BuildStatus{models.StatusRunning, models.StatusRunning, models.StatusSuccess} {
+		newModel, cmd := m.Update(buildFetchedMsg{index: 0, build: &models.Build{PRNumber: "3859", BuildNumber: 4, Status: status}})
+		m = newModel.(Model)
+		runCmds(cmd)
+	}
+
+	data, _ := os.ReadFile(out)
+	if got := string(data); got != "on_start 3859\non_success 3859\n" {
+		t.Errorf("Hooks ran for %q", got)
+	}
+}
+
+func TestModel_Tick_ReloadsSavedBuilds(t *testing.T) {
+	m := NewModel()
+	path := filepath.Join(t.TempDir(), "builds.json")
+	m.store = persistence.NewStore(path)
+	m.state.AddBuild(models.Build{PRNumber: "3859", Status: models.StatusSuccess, BuildNumber: 42})
+	m.state.AddBuild(models.Build{PRNumber: "3860", Status: models.StatusRunning, BuildNumber: 7})
+	m.state.SelectedIndex = 1
+	if err := m.saveState(); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+
+	// Nothing changed on disk
+	newModel, _ := m.Update(tickMsg(time.Now()))
+	m = newModel.(Model)
+	if len(m.state.Builds) != 2 {
+		t.Fatalf("Expected 2 builds, got %d", len(m.state.Builds))
+	}
+
+	// Another process removes 3859 and adds 3934
+	if _, err := persistence.RemovePRs(path, []string{"3859"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := persistence.AddPRs(path, []string{"3934"}); err != nil {
+		t.Fatal(err)
+	}
+	newModel, _ = m.Update(tickMsg(time.Now()))
+	m = newModel.(Model)
+
+	if len(m.state.Builds) != 2 || m.state.Builds[0].PRNumber != "3860" || m.state.Builds[1].PRNumber != "3934" {
+		t.Fatalf("Unexpected tiles after reload: %+v", m.state.Builds)
+	}
+	if m.state.Builds[0].BuildNumber != 7 {
+		t.Error("Tiles in both should keep their in-memory build")
+	}
+	if m.state.SelectedIndex != 0 {
+		t.Errorf("Selection should follow PR-3860, got index %d", m.state.SelectedIndex)
+	}
+	if !strings.Contains(m.statusMessage, "1 added, 1 removed") {
+		t.Errorf("Unexpected status message %q", m.statusMessage)
+	}
+
+	// A fetch that started before the reload updates its PR's tile, not the tile at its old index
+	newModel, _ = m.Update(buildFetchedMsg{index: 1, prNumber: "3860", build: &models.Build{PRNumber: "3860", Status: models.StatusSuccess, BuildNumber: 7}})
+	m = newModel.(Model)
+	if m.state.Builds[0].Status != models.StatusSuccess || m.state.Builds[1].PRNumber != "3934" {
+		t.Errorf("Fetch result went to the wrong tile: %+v", m.state.Builds)
+	}
+	newModel, _ = m.Update(buildFetchedMsg{index: 0, prNumber: "3859", build: &models.Build{PRNumber: "3859", Status: models.StatusFailure}})
+	m = newModel.(Model)
+	if len(m.state.Builds) != 2 || m.state.Builds[0].PRNumber != "3860" {
+		t.Errorf("Result for a removed PR should be dropped: %+v", m.state.Builds)
+	}
+}
+
+func TestModel_RestoresSavedUIState(t *testing.T) {
+	m := NewModel()
+	path := filepath.Join(t.TempDir(), "builds.json")
+	m.store = persistence.NewStore(path)
+	m.state.AddBuild(models.Build{PRNumber: "3859"})
+	m.state.AddBuild(models.Build{PRNumber: "3860"})
+	m.state.SelectedIndex = 1
+	m.showDetail = true
+
+	// Quitting saves the selection
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+
+	restarted := NewModel()
+	restarted.store = persistence.NewStore(path)
+	if err := restarted.LoadPersistedBuilds(); err != nil {
+		t.Fatalf("LoadPersistedBuilds() error = %v", err)
+	}
+	if restarted.state.SelectedIndex != 1 || !restarted.showDetail {
+		t.Errorf("Expected PR-3860 selected in detail view, got index %d, detail %v", restarted.state.SelectedIndex, restarted.showDetail)
+	}
+}
@@ -1,50 +1,94 @@
 package ui
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+	"github.com/mpetters/jenkins-dash/internal/persistence"
 )
 
-// saveState persists the current builds to disk
+// saveState persists the current builds and UI state to disk
+// Tiles another instance added or removed meanwhile are kept, and taken into
+// this model on the next tick
 func (m Model) saveState() error {
-	if m.configPath == "" {
-		return nil // No config path set, skip saving
+	ui := persistence.UIState{Detail: m.showDetail}
+	if build := m.state.GetSelectedBuild(); build != nil {
+		ui.SelectedPR = build.PRNumber
 	}
+	m.store.SetUI(ui)
+	return m.store.Save(m.state.Builds)
+}
 
-	data, err := json.MarshalIndent(m.state.Builds, "", "  ")
+// LoadPersistedBuilds loads builds from disk (public method for startup)
+func (m *Model) LoadPersistedBuilds() error {
+	builds, err := m.store.Load()
 	if err != nil {
 		return err
 	}
+	m.state.Builds = builds
+	ui := m.store.UI()
+	m.selectPR(ui.SelectedPR)
+	m.showDetail = ui.Detail && len(builds) > 0
 
-	return os.WriteFile(m.configPath, data, 0644)
+	// Update status message if builds were loaded
+	if len(m.state.Builds) > 0 {
+		m.statusMessage = fmt.Sprintf("Loaded %d saved build(s). Press 'a' to add more.", len(m.state.Builds))
+	}
+
+	return nil
 }
 
-// LoadPersistedBuilds loads builds from disk (public method for startup)
-func (m *Model) LoadPersistedBuilds() error {
-	if m.configPath == "" {
-		return nil // No config path set, skip loading
+// reloadPersistedBuilds takes the changes another instance saved to the
+// builds file (e.g., `jenkins-dash add`, a second dashboard)
+// Returns a status message if tiles were added or removed
+func (m *Model) reloadPersistedBuilds() (string, error) {
+	merged, changed, err := m.store.Reload(m.state.Builds)
+	if err != nil || !changed {
+		return "", err
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
-		return nil // File doesn't exist yet, not an error
+	added, removed := m.replaceTiles(merged)
+	if added == 0 && removed == 0 {
+		return "", nil
 	}
+	return fmt.Sprintf("↻ Saved builds changed: %d added, %d removed", added, removed), nil
+}
 
-	data, err := os.ReadFile(m.configPath)
-	if err != nil {
-		return err
+// replaceTiles replaces the tiles, keeping the selection on the same PR
+// New PRs are fetched on the next tick, as PRs never scheduled are always due
+// Returns how many PRs were added and removed
+func (m *Model) replaceTiles(builds []models.Build) (added, removed int) {
+	var selectedPR string
+	if build := m.state.GetSelectedBuild(); build != nil {
+		selectedPR = build.PRNumber
 	}
 
-	if err := json.Unmarshal(data, &m.state.Builds); err != nil {
-		return err
+	addedPRs, removedPRs := persistence.Changes(m.state.Builds, builds)
+	for _, prNumber := range removedPRs {
+		m.scheduler.Forget(prNumber)
+		m.tracker.Forget(prNumber)
 	}
 
-	// Update status message if builds were loaded
-	if len(m.state.Builds) > 0 {
-		m.statusMessage = fmt.Sprintf("Loaded %d saved build(s). Press 'a' to add more.", len(m.state.Builds))
-	}
+	m.state.Builds = builds
+	m.selectPR(selectedPR)
+	return len(addedPRs), len(removedPRs)
+}
 
-	return nil
+// selectPR selects the tile of a PR, or the first tile if it's gone
+func (m *Model) selectPR(prNumber string) {
+	m.state.SelectedIndex = max(m.tileIndex(-1, prNumber), 0)
 }
 
+// tileIndex returns the index of a PR's tile, trying the index it had when a
+// fetch started first; -1 if the tile is gone
+func (m Model) tileIndex(index int, prNumber string) int {
+	if index >= 0 && index < len(m.state.Builds) && m.state.Builds[index].PRNumber == prNumber {
+		return index
+	}
+	for i, build := range m.state.Builds {
+		if build.PRNumber == prNumber {
+			return i
+		}
+	}
+	return -1
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mpetters/jenkins-dash/internal/classifier"
 	"github.com/mpetters/jenkins-dash/internal/models"
 )
 
@@ -15,7 +16,7 @@ const (
 // RenderTile renders a build tile with proper styling
 func RenderTile(build models.Build, isSelected bool) string {
 	// Get aesthetically pleasing pastel colors
-	bgColor, fgColor := GetTileColors(build.Status)
+	bgColor, fgColor := GetBuildColors(build)
 
 	// Build the tile content
 	lines := make([]string, 0, 8)
@@ -73,14 +74,17 @@ func RenderTile(build models.Build, isSelected bool) string {
 			stageText = "Unknown"
 		}
 	}
-	if len(stageText) > 18 {
-		stageText = stageText[:18]
+	jobText := build.JobName
+	if build.Status == models.StatusError && build.ErrorKind != models.ErrorNone {
+		stageText, jobText = errorTileText(build.ErrorKind)
+	}
+	if build.IsFailure() && build.FailureCategory != "" && build.FailureCategory != classifier.Unclassified {
+		stageText = build.FailureCategory // Root cause from the console log instead of "Failed"
 	}
-	stageLine := fmt.Sprintf("│ Stage: %-18s │", stageText)
+	stageLine := fmt.Sprintf("│ Stage: %s │", fitWidth(stageText, 18))
 	lines = append(lines, stageLine)
 
 	// Job
-	jobText := build.JobName
 	if jobText == "" {
 		if build.Status == models.StatusPending {
 			jobText = "Fetching data..."
@@ -91,9 +95,42 @@ func RenderTile(build models.Build, isSelected bool) string {
 	if len(jobText) > 18 {
 		jobText = jobText[:18]
 	}
-	jobLine := fmt.Sprintf("│ Job: %-20s │", jobText)
+	jobLine := fmt.Sprintf("│ Job: %s │", fitWidth(jobText, 20))
 	lines = append(lines, jobLine)
 
+	// Trigger (why Jenkins started the build) - re-indexes are marked so they stand out from real pushes
+	if trigger := build.Trigger(); trigger != "" {
+		if build.IsReindex() {
+			trigger = "↻ " + trigger
+		}
+		triggerLine := fmt.Sprintf("│ Via: %s │", fitWidth(trigger, 21))
+		lines = append(lines, triggerLine)
+	}
+
+	// Stale build warning (Jenkins built an older commit than the PR head)
+	if build.IsStale() {
+		staleText := "⚠ Stale: PR has new commits"
+		if build.NewerQueued {
+			staleText = "⚠ Stale (newer build queued)"
+		}
+		lines = append(lines, fmt.Sprintf("│ %s │", fitWidth(staleText, tileWidth-4)))
+	}
+
+	// Flaky badge (failed tests that are known to be flaky)
+	if build.IsFailure() && len(build.FlakyTests) > 0 {
+		flakyText := fmt.Sprintf("🎲 flaky: %d/%d failed tests", len(build.FlakyTests), len(build.FailedTests))
+		lines = append(lines, fmt.Sprintf("│ %s │", fitWidth(flakyText, tileWidth-4)))
+	}
+
+	// Slow stage warning (stage much slower than its historical median)
+	if len(build.SlowStages) > 0 {
+		slowText := "🐢 Slow: " + build.SlowStages[0]
+		if len(build.SlowStages) > 1 {
+			slowText = fmt.Sprintf("🐢 Slow: +%d %s", len(build.SlowStages)-1, build.SlowStages[0])
+		}
+		lines = append(lines, fmt.Sprintf("│ %s │", fitWidth(slowText, tileWidth-4)))
+	}
+
 	// Duration
 	durationText := build.FormatDuration()
 	timeLine := fmt.Sprintf("│ Time: %-20s │", durationText)
@@ -165,3 +202,43 @@ func RenderTile(build models.Build, isSelected bool) string {
 
 	return style.Render(content)
 }
+
+// errorTileText returns the stage and job lines shown for each error class
+func errorTileText(kind models.ErrorKind) (stage, job string) {
+	switch kind {
+	case models.ErrorAuth:
+		return "🔒 Auth failed", "Check credentials"
+	case models.ErrorForbidden:
+		return "🔒 No access", "Check job perms"
+	case models.ErrorNotFound:
+		return "Not built yet", "No build for PR"
+	case models.ErrorServer:
+		return "Jenkins error", "Retrying..."
+	case models.ErrorTimeout:
+		return "Timed out", "Retrying..."
+	case models.ErrorOffline:
+		return "Offline", "Host unreachable"
+	default:
+		return "Error", "See details (i)"
+	}
+}
+
+// fitWidth truncates or pads text to exactly width terminal cells
+// Unlike %-Ns, this accounts for wide characters such as emoji
+func fitWidth(text string, width int) string {
+	for lipgloss.Width(text) > width {
+		runes := []rune(text)
+		text = string(runes[:len(runes)-1])
+	}
+	return text + strings.Repeat(" ", width-lipgloss.Width(text))
+}
+
+// staleBuildText describes a stale build for the status bar and detail view
+func staleBuildText(build models.Build) string {
+	text := fmt.Sprintf("stale build: PR has newer commits (built %s, head %s)",
+		models.ShortSHA(build.BuiltSHA), models.ShortSHA(build.PRHeadSHA))
+	if build.NewerQueued {
+		return text + ", newer build queued"
+	}
+	return text + ", no newer build queued"
+}
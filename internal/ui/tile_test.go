@@ -266,3 +266,100 @@ func stripANSI(str string) string {
 	}
 	return result
 }
+
+func TestRenderTile_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		kind models.ErrorKind
+		want string
+	}{
+		{models.ErrorAuth, "🔒 Auth failed"},
+		{models.ErrorForbidden, "🔒 No access"},
+		{models.ErrorNotFound, "Not built yet"},
+		{models.ErrorOffline, "Offline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			build := models.Build{
+				PRNumber:     "3859",
+				Status:       models.StatusError,
+				ErrorKind:    tt.kind,
+				ErrorMessage: "https://build.example.com/job/PR-3859/lastBuild/api/json",
+			}
+
+			result := RenderTile(build, false)
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("Tile should contain %q", tt.want)
+			}
+			if strings.Contains(result, "https://") {
+				t.Error("Tile should not show raw URLs")
+			}
+		})
+	}
+}
+
+func TestRenderTile_ShowsTrigger(t *testing.T) {
+	build := models.Build{
+		PRNumber: "3934",
+		Status:   models.StatusRunning,
+		Causes:   []models.BuildCause{{Type: "BranchIndexingCause", Description: "Branch indexing"}},
+	}
+
+	result := RenderTile(build, false)
+	if !strings.Contains(result, "Via: ↻ branch indexing") {
+		t.Error("Tile should mark builds started by branch indexing")
+	}
+
+	build.Causes = []models.BuildCause{{Type: "UserIdCause", UserID: "jdoe"}}
+	result = RenderTile(build, false)
+	if !strings.Contains(result, "Via: by jdoe") {
+		t.Error("Tile should show the triggering user")
+	}
+}
+
+func TestRenderTile_StaleBuildWarning(t *testing.T) {
+	build := models.Build{
+		PRNumber:  "3934",
+		Status:    models.StatusSuccess,
+		BuiltSHA:  "aaaaaaa111",
+		PRHeadSHA: "bbbbbbb222",
+	}
+
+	if result := RenderTile(build, false); !strings.Contains(result, "Stale: PR has new commits") {
+		t.Error("Tile should warn when the PR has newer commits than the build")
+	}
+
+	build.NewerQueued = true
+	if result := RenderTile(build, false); !strings.Contains(result, "newer build queued") {
+		t.Error("Tile should say when a newer build is queued")
+	}
+
+	build.PRHeadSHA = build.BuiltSHA
+	if result := RenderTile(build, false); strings.Contains(result, "Stale") {
+		t.Error("Up-to-date build should not show a stale warning")
+	}
+}
+
+func TestRenderTile_ShowsFailureCategory(t *testing.T) {
+	build := models.Build{
+		PRNumber:        "3934",
+		Status:          models.StatusFailure,
+		FailureCategory: "Out of memory",
+	}
+
+	if result := RenderTile(build, false); !strings.Contains(result, "Stage: Out of memory") {
+		t.Error("Failed tile should show the root-cause category as its stage")
+	}
+}
+
+func TestRenderTile_SlowStageWarning(t *testing.T) {
+	build := models.Build{
+		PRNumber:   "3934",
+		Status:     models.StatusRunning,
+		SlowStages: []string{"QAL Tests"},
+	}
+
+	if result := RenderTile(build, false); !strings.Contains(result, "Slow: QAL Tests") {
+		t.Error("Tile should warn about stages slower than their baseline")
+	}
+}
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mpetters/jenkins-dash/internal/analytics"
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+const (
+	timelineWidth     = 48 // Cells for the whole pipeline
+	timelineNameWidth = 26 // Stage name column, including the lane marker
+)
+
+// renderTimeline draws the stages as a Gantt chart: one row per stage, with the
+// bar offset by the stage's start and sized by its duration. Parallel branches
+// are stacked into a bracketed group of lanes
+func renderTimeline(stages []models.Stage) []string {
+	var timed []models.Stage
+	for _, stage := range stages {
+		if analytics.Measurable(stage) && stage.StartMillis > 0 {
+			timed = append(timed, stage)
+		}
+	}
+	if len(timed) == 0 {
+		return nil
+	}
+
+	start, end := timed[0].StartMillis, timed[0].StartMillis
+	for _, stage := range timed {
+		start = min(start, stage.StartMillis)
+		end = max(end, stage.StartMillis+stage.DurationMillis)
+	}
+	span := max(end-start, 1)
+
+	var lines []string
+	for _, lane := range timelineLanes(timed) {
+		stage := lane.stage
+		offset := int((stage.StartMillis - start) * timelineWidth / span)
+		length := max(int(stage.DurationMillis*timelineWidth/span), 1)
+		length = min(length, timelineWidth-offset)
+
+		bar := lipgloss.NewStyle().Foreground(stageColor(stage.Status)).Render(strings.Repeat("█", length))
+		track := strings.Repeat(" ", offset) + bar + strings.Repeat(" ", timelineWidth-offset-length)
+
+		name := lane.prefix + truncateWidth(stage.Name, max(timelineNameWidth-lipgloss.Width(lane.prefix), 1))
+		lines = append(lines, fmt.Sprintf("  %s │%s│ %s",
+			fitWidth(name, timelineNameWidth), track, models.FormatSeconds(int(stage.DurationMillis/1000))))
+	}
+	return lines
+}
+
+// timelineLane is one row of the timeline: a stage and the marker before its name
+type timelineLane struct {
+	stage  models.Stage
+	prefix string
+}
+
+// timelineLanes orders the stages into rows
+// Stages from the stage graph keep their pipeline order, indented by depth,
+// with the branches of each parallel block bracketed. Flat wfapi stages have no
+// structure, so stages that overlap in time are bracketed instead
+func timelineLanes(stages []models.Stage) []timelineLane {
+	var lanes []timelineLane
+	if stages[0].ID == "" {
+		sorted := append([]models.Stage(nil), stages...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartMillis < sorted[j].StartMillis })
+		for _, group := range parallelGroups(sorted) {
+			for i, stage := range group {
+				lanes = append(lanes, timelineLane{stage: stage, prefix: laneMarker(i, len(group))})
+			}
+		}
+		return lanes
+	}
+
+	// Number the branches of each parallel block
+	branches := make(map[string][]string) // Parent ID -> branch IDs
+	for _, stage := range stages {
+		if stage.Parallel {
+			branches[stage.ParentID] = append(branches[stage.ParentID], stage.ID)
+		}
+	}
+	for _, stage := range stages {
+		marker := "  "
+		if stage.Parallel {
+			siblings := branches[stage.ParentID]
+			marker = laneMarker(slices.Index(siblings, stage.ID), len(siblings))
+		}
+		indent := strings.Repeat(" ", max(stage.Depth-1, 0))
+		lanes = append(lanes, timelineLane{stage: stage, prefix: indent + marker})
+	}
+	return lanes
+}
+
+// parallelGroups splits stages (sorted by start) into runs of stages that overlap
+// in time; a group with more than one stage ran in parallel
+func parallelGroups(stages []models.Stage) [][]models.Stage {
+	var groups [][]models.Stage
+	var groupEnd int64
+	for _, stage := range stages {
+		if len(groups) > 0 && stage.StartMillis < groupEnd {
+			last := len(groups) - 1
+			groups[last] = append(groups[last], stage)
+		} else {
+			groups = append(groups, []models.Stage{stage})
+		}
+		groupEnd = max(groupEnd, stage.StartMillis+stage.DurationMillis)
+	}
+	return groups
+}
+
+// laneMarker brackets the lanes of a parallel group
+func laneMarker(index, size int) string {
+	switch {
+	case size == 1:
+		return "  "
+	case index == 0:
+		return "┌ "
+	case index == size-1:
+		return "└ "
+	default:
+		return "├ "
+	}
+}
+
+// stageColor returns the bar color for a wfapi stage status
+func stageColor(status string) lipgloss.Color {
+	switch status {
+	case "SUCCESS":
+		return colorSuccessBg
+	case "FAILED", "ABORTED":
+		return colorFailureBg
+	case "IN_PROGRESS":
+		return colorRunningBg
+	case "UNSTABLE", "PAUSED_PENDING_INPUT":
+		return colorPendingBg
+	default:
+		return colorOfflineBg
+	}
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mpetters/jenkins-dash/internal/models"
+)
+
+func TestRenderTimeline_ScalesBars(t *testing.T) {
+	stages := []models.Stage{
+		{Name: "BUILD:", Status: "SUCCESS", StartMillis: 1000, DurationMillis: 1},
+		{Name: "Compile", Status: "SUCCESS", StartMillis: 1000, DurationMillis: 60000},
+		{Name: "Package", Status: "SUCCESS", StartMillis: 61000, DurationMillis: 180000},
+	}
+
+	lines := renderTimeline(stages)
+	if len(lines) != 2 {
+		t.Fatalf("Expected a row per timed stage (no phase labels), got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	// Compile is the first quarter of the pipeline, Package the rest
+	compile, pkg := barCells(lines[0]), barCells(lines[1])
+	if compile.offset != 0 || compile.length != timelineWidth/4 {
+		t.Errorf("Compile bar = %+v, want offset 0 length %d", compile, timelineWidth/4)
+	}
+	if pkg.offset != timelineWidth/4 || pkg.length != timelineWidth*3/4 {
+		t.Errorf("Package bar = %+v, want offset %d length %d", pkg, timelineWidth/4, timelineWidth*3/4)
+	}
+	if !strings.HasSuffix(lines[1], "3m 0s") {
+		t.Errorf("Row should end with the stage duration: %q", lines[1])
+	}
+}
+
+func TestRenderTimeline_StacksParallelStages(t *testing.T) {
+	stages := []models.Stage{
+		{Name: "Compile", Status: "SUCCESS", StartMillis: 1000, DurationMillis: 10000},
+		{Name: "QAL East", Status: "SUCCESS", StartMillis: 11000, DurationMillis: 30000},
+		{Name: "QAL West", Status: "FAILED", StartMillis: 11500, DurationMillis: 20000},
+		{Name: "QAL Central", Status: "IN_PROGRESS", StartMillis: 12000, DurationMillis: 25000},
+		{Name: "Deploy", Status: "SUCCESS", StartMillis: 41000, DurationMillis: 5000},
+	}
+
+	lines := renderTimeline(stages)
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 rows, got %d", len(lines))
+	}
+	wantMarkers := []string{"  Compile", "┌ QAL East", "├ QAL West", "└ QAL Central", "  Deploy"}
+	for i, want := range wantMarkers {
+		if !strings.HasPrefix(strings.TrimPrefix(lines[i], "  "), want) {
+			t.Errorf("Row %d = %q, want prefix %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestRenderTimeline_GroupsGraphBranches(t *testing.T) {
+	// Branches from the stage graph are grouped by their parallel block even when
+	// they don't overlap in time, and nested stages are indented
+	stages := []models.Stage{
+		{Name: "Tests", Status: "SUCCESS", StartMillis: 1000, DurationMillis: 40000, ID: "6"},
+		{Name: "unit", Status: "SUCCESS", StartMillis: 1000, DurationMillis: 5000, ID: "9", ParentID: "6", Depth: 1, Parallel: true},
+		{Name: "integration", Status: "SUCCESS", StartMillis: 10000, DurationMillis: 30000, ID: "10", ParentID: "6", Depth: 1, Parallel: true},
+		{Name: "setup", Status: "SUCCESS", StartMillis: 10000, DurationMillis: 10000, ID: "14", ParentID: "10", Depth: 2},
+		{Name: "Deploy", Status: "SUCCESS", StartMillis: 41000, DurationMillis: 5000, ID: "30"},
+	}
+
+	lines := renderTimeline(stages)
+	wantPrefixes := []string{"  Tests", "┌ unit", "└ integration", "   setup", "  Deploy"}
+	if len(lines) != len(wantPrefixes) {
+		t.Fatalf("Expected %d rows, got %d", len(wantPrefixes), len(lines))
+	}
+	for i, want := range wantPrefixes {
+		if !strings.HasPrefix(strings.TrimPrefix(lines[i], "  "), want) {
+			t.Errorf("Row %d = %q, want prefix %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestRenderTimeline_NoStages(t *testing.T) {
+	if lines := renderTimeline(nil); lines != nil {
+		t.Errorf("Expected no timeline without stages, got %v", lines)
+	}
+}
+
+type bar struct{ offset, length int }
+
+// barCells finds the bar inside a timeline row's │...│ track, ignoring color codes
+func barCells(line string) bar {
+	var b bar
+	cell, inTrack, inEscape := 0, false, false
+	for _, r := range line {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			inEscape = r != 'm'
+		case r == '│':
+			if inTrack {
+				return b
+			}
+			inTrack = true
+		case inTrack:
+			if r == '█' {
+				if b.length == 0 {
+					b.offset = cell
+				}
+				b.length++
+			}
+			cell++
+		}
+	}
+	return b
+}